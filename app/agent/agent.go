@@ -3,6 +3,8 @@ package agent
 import (
 	"context"
 	"fmt"
+	"iter"
+	"log"
 	"strings"
 
 	adkagent "google.golang.org/adk/agent"
@@ -14,6 +16,7 @@ import (
 	"google.golang.org/genai"
 
 	"github.com/tihn/amo-ai-tgbot-go/app/agent/prompts"
+	svcagent "github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
 )
 
 const AppName = "amocrm-bot"
@@ -23,22 +26,36 @@ type Agent struct {
 	runner         *runner.Runner
 	sessionService session.Service
 	adkAgent       adkagent.Agent
+	model          model.LLM
+	history        HistoryPolicy
+	systemPrompt   string
+	sessionLocks   sessionLocks
 }
 
 // NewAgent creates a new AI agent backed by ADK Runner with CRM tools.
-func NewAgent(ctx context.Context, llmModel model.LLM, toolsets ...tool.Toolset) (*Agent, error) {
+// sessionService stores conversation history; nil falls back to in-memory sessions.
+// history limits the context sent to the model (see HistoryPolicy).
+func NewAgent(ctx context.Context, llmModel model.LLM, sessionService session.Service, history HistoryPolicy, toolsets ...tool.Toolset) (*Agent, error) {
+	a := &Agent{
+		model:        llmModel,
+		history:      history,
+		systemPrompt: prompts.BuildSystemPrompt(),
+	}
+
 	adkAgent, err := llmagent.New(llmagent.Config{
-		Name:        "crm-assistant",
-		Model:       llmModel,
-		Description: "amoCRM AI assistant",
-		Instruction: prompts.BuildSystemPrompt(),
-		Toolsets:    toolsets,
+		Name:                "crm-assistant",
+		Model:               llmModel,
+		Description:         "amoCRM AI assistant",
+		InstructionProvider: a.instruction,
+		Toolsets:            toolsets,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("NewAgent: create llm agent: %w", err)
 	}
 
-	sessionService := session.InMemoryService()
+	if sessionService == nil {
+		sessionService = session.InMemoryService()
+	}
 
 	runnr, err := runner.New(runner.Config{
 		AppName:           AppName,
@@ -50,15 +67,16 @@ func NewAgent(ctx context.Context, llmModel model.LLM, toolsets ...tool.Toolset)
 		return nil, fmt.Errorf("NewAgent: create runner: %w", err)
 	}
 
-	return &Agent{
-		runner:         runnr,
-		sessionService: sessionService,
-		adkAgent:       adkAgent,
-	}, nil
+	a.runner = runnr
+	a.sessionService = sessionService
+	a.adkAgent = adkAgent
+	return a, nil
 }
 
 // Process processes a user message through the ADK Runner.
 func (a *Agent) Process(ctx context.Context, userID, sessionID, message string) (string, error) {
+	defer a.sessionLocks.lock(userID, sessionID)()
+	a.prepareSession(ctx, userID, sessionID)
 	userMsg := genai.NewContentFromText(message, genai.RoleUser)
 
 	var result strings.Builder
@@ -78,6 +96,91 @@ func (a *Agent) Process(ctx context.Context, userID, sessionID, message string)
 	return result.String(), nil
 }
 
+// ProcessStream processes a user message through the ADK Runner in SSE mode,
+// yielding partial text, tool calls and their results as they arrive.
+// The last event is always StreamEventDone with the complete answer.
+func (a *Agent) ProcessStream(ctx context.Context, userID, sessionID, message string) iter.Seq2[svcagent.StreamEvent, error] {
+	return func(yield func(svcagent.StreamEvent, error) bool) {
+		defer a.sessionLocks.lock(userID, sessionID)()
+		a.prepareSession(ctx, userID, sessionID)
+		userMsg := genai.NewContentFromText(message, genai.RoleUser)
+		runCfg := adkagent.RunConfig{StreamingMode: adkagent.StreamingModeSSE}
+
+		// committed holds text of completed model responses,
+		// draft — partial chunks of the response being generated.
+		var committed, draft strings.Builder
+		for event, err := range a.runner.Run(ctx, userID, sessionID, userMsg, runCfg) {
+			if err != nil {
+				yield(svcagent.StreamEvent{}, fmt.Errorf("agent run: %w", err))
+				return
+			}
+			if event.Content == nil {
+				continue
+			}
+
+			if event.Partial {
+				for _, part := range event.Content.Parts {
+					draft.WriteString(part.Text)
+				}
+				if draft.Len() == 0 {
+					continue
+				}
+				if !yield(svcagent.StreamEvent{Kind: svcagent.StreamEventText, Text: committed.String() + draft.String()}, nil) {
+					return
+				}
+				continue
+			}
+
+			// Final (non-partial) event repeats the whole response — drop the draft.
+			draft.Reset()
+			var hasText bool
+			for _, part := range event.Content.Parts {
+				if part.FunctionCall != nil {
+					ev := svcagent.StreamEvent{
+						Kind:       svcagent.StreamEventToolCall,
+						ToolCallID: part.FunctionCall.ID,
+						ToolName:   part.FunctionCall.Name,
+						ToolArgs:   part.FunctionCall.Args,
+					}
+					if !yield(ev, nil) {
+						return
+					}
+				}
+				if part.FunctionResponse != nil {
+					ev := svcagent.StreamEvent{
+						Kind:       svcagent.StreamEventToolResult,
+						ToolCallID: part.FunctionResponse.ID,
+						ToolName:   part.FunctionResponse.Name,
+						ToolResult: part.FunctionResponse.Response,
+					}
+					if !yield(ev, nil) {
+						return
+					}
+				}
+				if part.Text != "" {
+					committed.WriteString(part.Text)
+					hasText = true
+				}
+			}
+			if hasText {
+				if !yield(svcagent.StreamEvent{Kind: svcagent.StreamEventText, Text: committed.String()}, nil) {
+					return
+				}
+			}
+		}
+
+		yield(svcagent.StreamEvent{Kind: svcagent.StreamEventDone, Text: committed.String()}, nil)
+	}
+}
+
+// prepareSession applies the history policy before a run; the caller holds the session lock.
+// Compaction failures are not fatal: the run proceeds with the full history.
+func (a *Agent) prepareSession(ctx context.Context, userID, sessionID string) {
+	if err := a.compactHistory(ctx, userID, sessionID); err != nil {
+		log.Printf("[agent] history compaction for session %s failed: %v", sessionID, err)
+	}
+}
+
 // ADKAgent returns the underlying ADK agent (for web launcher).
 func (a *Agent) ADKAgent() adkagent.Agent {
 	return a.adkAgent
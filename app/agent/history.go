@@ -0,0 +1,388 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	adkagent "google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+
+	"github.com/tihn/amo-ai-tgbot-go/app/agent/prompts"
+	svcagent "github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
+)
+
+// stateHistorySummary is the session state key holding the summary of compacted dialogue.
+const stateHistorySummary = "history_summary"
+
+// HistoryPolicy limits the conversation history sent to the model.
+// Before each run the session is measured in estimated tokens; when it is over budget,
+// old tool responses are collapsed to digests and, if that is not enough, old turns
+// are summarized into session state and removed from the session.
+type HistoryPolicy struct {
+	MaxTokens   int // token budget for session events; 0 disables the policy
+	KeepTurns   int // last user turns that are always kept verbatim
+	DigestChars int // max size of a collapsed tool response
+}
+
+// instruction builds the system prompt, appending the current user and
+// the summary of compacted history if any.
+func (a *Agent) instruction(ctx adkagent.ReadonlyContext) (string, error) {
+	prompt := a.systemPrompt
+	if id, ok := svcagent.IdentityFromContext(ctx); ok {
+		prompt += prompts.BuildCurrentUserSection(id.AmoUserName, id.AmoUserID, id.Email)
+	}
+	if v, err := ctx.ReadonlyState().Get(stateHistorySummary); err == nil {
+		if summary, ok := v.(string); ok && summary != "" {
+			prompt += prompts.BuildHistorySummarySection(summary)
+		}
+	}
+	return prompt, nil
+}
+
+// compactHistory applies the history policy to a session before the next run.
+func (a *Agent) compactHistory(ctx context.Context, userID, sessionID string) error {
+	if a.history.MaxTokens <= 0 {
+		return nil
+	}
+
+	resp, err := a.sessionService.Get(ctx, &session.GetRequest{
+		AppName:   AppName,
+		UserID:    userID,
+		SessionID: sessionID,
+	})
+	if err != nil {
+		// Session does not exist yet — the runner will create it
+		return nil
+	}
+	sess := resp.Session
+
+	events := make([]*session.Event, 0, sess.Events().Len())
+	for ev := range sess.Events().All() {
+		events = append(events, ev)
+	}
+	if estimateEventsTokens(events) <= a.history.MaxTokens {
+		return nil
+	}
+
+	keepFrom := turnStart(events, a.history.KeepTurns)
+	if keepFrom == 0 {
+		// Everything belongs to the turns kept verbatim
+		return nil
+	}
+	old, recent := events[:keepFrom], events[keepFrom:]
+
+	state := sessionState(sess)
+
+	// Step 1: collapse old tool responses
+	digested := make([]*session.Event, len(old))
+	for i, ev := range old {
+		digested[i] = digestEvent(ev, a.history.DigestChars)
+	}
+	if estimateEventsTokens(digested)+estimateEventsTokens(recent) <= a.history.MaxTokens {
+		return a.rewriteSession(ctx, sess, state, append(digested, recent...))
+	}
+
+	// Step 2: fold old turns into the rolling summary.
+	// The first user message goes away with them, keep it as the session title.
+	if _, ok := state[stateSessionTitle]; !ok {
+		if title := firstUserMessage(slices.Values(old)); title != "" {
+			state[stateSessionTitle] = title
+		}
+	}
+	previous, _ := state[stateHistorySummary].(string)
+	summary, err := a.summarize(ctx, previous, digested)
+	if err != nil {
+		return fmt.Errorf("summarize history: %w", err)
+	}
+	state[stateHistorySummary] = summary
+
+	return a.rewriteSession(ctx, sess, state, recent)
+}
+
+// sessionReplacer is implemented by session stores that can swap a session's
+// content atomically (sessionstore.FileService).
+type sessionReplacer interface {
+	Replace(ctx context.Context, appName, userID, sessionID string, state map[string]any, events []*session.Event) error
+}
+
+// rewriteSession replaces the session with the given state and events.
+// Events are stored without state deltas: their effect is already in state.
+// The caller holds the session lock, so no run appends to the session meanwhile.
+func (a *Agent) rewriteSession(ctx context.Context, sess session.Session, state map[string]any, events []*session.Event) error {
+	cleaned := make([]*session.Event, len(events))
+	for i, ev := range events {
+		cp := *ev
+		cp.Actions.StateDelta = nil
+		cleaned[i] = &cp
+	}
+
+	if r, ok := a.sessionService.(sessionReplacer); ok {
+		if err := r.Replace(ctx, sess.AppName(), sess.UserID(), sess.ID(), state, cleaned); err != nil {
+			return fmt.Errorf("replace session: %w", err)
+		}
+		return nil
+	}
+
+	// Stores without atomic replace keep sessions in memory, where these calls do not fail
+	if err := a.sessionService.Delete(ctx, &session.DeleteRequest{
+		AppName:   sess.AppName(),
+		UserID:    sess.UserID(),
+		SessionID: sess.ID(),
+	}); err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+
+	created, err := a.sessionService.Create(ctx, &session.CreateRequest{
+		AppName:   sess.AppName(),
+		UserID:    sess.UserID(),
+		SessionID: sess.ID(),
+		State:     state,
+	})
+	if err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+
+	for _, ev := range cleaned {
+		if err := a.sessionService.AppendEvent(ctx, created.Session, ev); err != nil {
+			return fmt.Errorf("append event: %w", err)
+		}
+	}
+	return nil
+}
+
+// summarize asks the model to merge old events into the previous summary.
+func (a *Agent) summarize(ctx context.Context, previous string, events []*session.Event) (string, error) {
+	transcript := renderTranscript(events)
+	// The summarization request itself must fit the budget: keep the tail of the transcript
+	if maxRunes := a.history.MaxTokens * runesPerToken; utf8.RuneCountInString(transcript) > maxRunes {
+		runes := []rune(transcript)
+		transcript = "…\n" + string(runes[len(runes)-maxRunes:])
+	}
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{
+			genai.NewContentFromText(prompts.BuildHistorySummaryPrompt(previous, transcript), genai.RoleUser),
+		},
+		Config: &genai.GenerateContentConfig{},
+	}
+
+	var summary strings.Builder
+	for resp, err := range a.model.GenerateContent(ctx, req, false) {
+		if err != nil {
+			return "", err
+		}
+		if resp.Partial || resp.Content == nil {
+			continue
+		}
+		for _, part := range resp.Content.Parts {
+			summary.WriteString(part.Text)
+		}
+	}
+
+	result := strings.TrimSpace(summary.String())
+	if result == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return result, nil
+}
+
+// sessionState returns session-scoped state; app:, user: and temp: keys are owned by the service.
+func sessionState(sess session.Session) map[string]any {
+	state := make(map[string]any)
+	for k, v := range sess.State().All() {
+		if strings.HasPrefix(k, session.KeyPrefixApp) ||
+			strings.HasPrefix(k, session.KeyPrefixUser) ||
+			strings.HasPrefix(k, session.KeyPrefixTemp) {
+			continue
+		}
+		state[k] = v
+	}
+	return state
+}
+
+// turnStart returns the index of the first event of the last n user turns,
+// or 0 if the session has no more than n turns.
+func turnStart(events []*session.Event, n int) int {
+	n = max(n, 1)
+	seen := 0
+	for i := len(events) - 1; i >= 0; i-- {
+		if !isUserMessage(events[i]) {
+			continue
+		}
+		seen++
+		if seen == n {
+			return i
+		}
+	}
+	return 0
+}
+
+// isUserMessage reports whether the event is a message typed by the user (not a tool response).
+func isUserMessage(ev *session.Event) bool {
+	if ev.Author != "user" || ev.Content == nil {
+		return false
+	}
+	for _, part := range ev.Content.Parts {
+		if part.Text != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// runesPerToken is a rough ratio for mixed Russian/English text and JSON.
+const runesPerToken = 3
+
+// estimateEventsTokens estimates the prompt size of events.
+func estimateEventsTokens(events []*session.Event) int {
+	total := 0
+	for _, ev := range events {
+		total += estimateEventTokens(ev)
+	}
+	return total
+}
+
+// estimateEventTokens estimates tokens of a single event: text, tool call arguments
+// and tool responses as they are serialized for the model.
+func estimateEventTokens(ev *session.Event) int {
+	if ev.Content == nil {
+		return 0
+	}
+
+	const partOverhead = 4
+	runes := 0
+	tokens := 0
+	for _, part := range ev.Content.Parts {
+		tokens += partOverhead
+		runes += utf8.RuneCountInString(part.Text)
+		if fc := part.FunctionCall; fc != nil {
+			runes += len(fc.Name) + jsonLen(fc.Args)
+		}
+		if fr := part.FunctionResponse; fr != nil {
+			runes += len(fr.Name) + jsonLen(fr.Response)
+		}
+	}
+	return tokens + (runes+runesPerToken-1)/runesPerToken
+}
+
+func jsonLen(v any) int {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return utf8.RuneCount(data)
+}
+
+// digestEvent returns a copy of the event with tool responses collapsed to short digests.
+func digestEvent(ev *session.Event, maxChars int) *session.Event {
+	if ev.Content == nil {
+		return ev
+	}
+
+	changed := false
+	parts := make([]*genai.Part, len(ev.Content.Parts))
+	for i, part := range ev.Content.Parts {
+		parts[i] = part
+		fr := part.FunctionResponse
+		if fr == nil || jsonLen(fr.Response) <= maxChars {
+			continue
+		}
+		frCopy := *fr
+		frCopy.Response = digestResponse(fr.Response, maxChars)
+		partCopy := *part
+		partCopy.FunctionResponse = &frCopy
+		parts[i] = &partCopy
+		changed = true
+	}
+	if !changed {
+		return ev
+	}
+
+	cp := *ev
+	cp.Content = &genai.Content{Role: ev.Content.Role, Parts: parts}
+	return &cp
+}
+
+// digestResponse keeps scalar fields of a tool response and replaces
+// lists and objects with their sizes, e.g. available_values → "[42 items]".
+func digestResponse(resp map[string]any, maxChars int) map[string]any {
+	digest := map[string]any{"_digest": "ответ сокращён, при необходимости запроси данные заново"}
+	for k, v := range resp {
+		digest[k] = digestValue(v, maxChars/4)
+	}
+
+	if jsonLen(digest) > maxChars {
+		data, _ := json.Marshal(digest)
+		return map[string]any{
+			"_digest": "ответ сокращён, при необходимости запроси данные заново",
+			"preview": truncateRunes(string(data), maxChars),
+		}
+	}
+	return digest
+}
+
+func digestValue(v any, maxString int) any {
+	switch val := v.(type) {
+	case string:
+		return truncateRunes(val, maxString)
+	case []any:
+		return fmt.Sprintf("[%d items]", len(val))
+	case map[string]any:
+		return fmt.Sprintf("{%d fields}", len(val))
+	case nil, bool, float64, int, int64, json.Number:
+		return val
+	}
+
+	// Typed values (structs, typed slices) — describe by JSON shape
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%T", v)
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return truncateRunes(string(data), maxString)
+	}
+	switch generic.(type) {
+	case []any, map[string]any:
+		return digestValue(generic, maxString)
+	}
+	return generic
+}
+
+// renderTranscript formats events as plain text for summarization.
+func renderTranscript(events []*session.Event) string {
+	var sb strings.Builder
+	for _, ev := range events {
+		if ev.Content == nil {
+			continue
+		}
+		for _, part := range ev.Content.Parts {
+			switch {
+			case part.Text != "" && ev.Author == "user":
+				fmt.Fprintf(&sb, "Пользователь: %s\n", part.Text)
+			case part.Text != "" && !part.Thought:
+				fmt.Fprintf(&sb, "Ассистент: %s\n", part.Text)
+			case part.FunctionCall != nil:
+				args, _ := json.Marshal(part.FunctionCall.Args)
+				fmt.Fprintf(&sb, "Вызов %s: %s\n", part.FunctionCall.Name, truncateRunes(string(args), 300))
+			case part.FunctionResponse != nil:
+				data, _ := json.Marshal(part.FunctionResponse.Response)
+				fmt.Fprintf(&sb, "Результат %s: %s\n", part.FunctionResponse.Name, truncateRunes(string(data), 500))
+			}
+		}
+	}
+	return sb.String()
+}
+
+func truncateRunes(s string, n int) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func textEvent(author, text string) *session.Event {
+	ev := session.NewEvent("inv")
+	ev.Author = author
+	ev.Content = genai.NewContentFromText(text, genai.RoleUser)
+	return ev
+}
+
+func toolResponseEvent(name string, response map[string]any) *session.Event {
+	ev := session.NewEvent("inv")
+	ev.Author = "crm-assistant"
+	ev.Content = &genai.Content{Role: genai.RoleUser, Parts: []*genai.Part{
+		{FunctionResponse: &genai.FunctionResponse{Name: name, Response: response}},
+	}}
+	return ev
+}
+
+func TestEstimateEventTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		ev   *session.Event
+		want int
+	}{
+		{name: "no content", ev: session.NewEvent("inv"), want: 0},
+		{name: "text", ev: textEvent("user", "привет"), want: 4 + 2},
+		{name: "rounds up", ev: textEvent("user", "abcd"), want: 4 + 2},
+		{name: "tool response as json", ev: toolResponseEvent("ab", map[string]any{"a": 1}), want: 4 + 3}, // ab + {"a":1}
+	}
+	for _, tt := range tests {
+		if got := estimateEventTokens(tt.ev); got != tt.want {
+			t.Errorf("%s: estimateEventTokens = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	events := []*session.Event{textEvent("user", "abc"), textEvent("user", "abcdef")}
+	if got := estimateEventsTokens(events); got != (4+1)+(4+2) {
+		t.Errorf("estimateEventsTokens = %d, want 11", got)
+	}
+}
+
+func TestDigestEvent(t *testing.T) {
+	small := toolResponseEvent("entities", map[string]any{"id": 1.0})
+	if digestEvent(small, 100) != small {
+		t.Error("small response must be kept as is")
+	}
+
+	items := make([]any, 50)
+	for i := range items {
+		items[i] = map[string]any{"id": float64(i), "name": strings.Repeat("сделка ", 5)}
+	}
+	large := toolResponseEvent("entities", map[string]any{
+		"items":   items,
+		"total":   50.0,
+		"message": strings.Repeat("длинный текст ", 20),
+		"meta":    map[string]any{"page": 1.0, "has_more": true},
+	})
+
+	digested := digestEvent(large, 200)
+	if digested == large {
+		t.Fatal("large response was not digested")
+	}
+	if _, ok := large.Content.Parts[0].FunctionResponse.Response["items"].([]any); !ok {
+		t.Fatal("original event was modified")
+	}
+
+	resp := digested.Content.Parts[0].FunctionResponse.Response
+	if resp["items"] != "[50 items]" || resp["meta"] != "{2 fields}" || resp["total"] != 50.0 {
+		t.Errorf("digest = %v", resp)
+	}
+	if msg, _ := resp["message"].(string); len([]rune(msg)) > 200/4+1 {
+		t.Errorf("message not truncated: %d runes", len([]rune(msg)))
+	}
+	if _, ok := resp["_digest"]; !ok {
+		t.Error("digest marker missing")
+	}
+	if estimateEventTokens(digested) >= estimateEventTokens(large) {
+		t.Error("digest is not smaller than the original")
+	}
+}
+
+func TestDigestResponseTooLarge(t *testing.T) {
+	resp := map[string]any{}
+	for i := range 100 {
+		resp[strings.Repeat("k", i+1)] = 1.0
+	}
+	digest := digestResponse(resp, 100)
+	if len(digest) != 2 || digest["preview"] == nil {
+		t.Fatalf("digest = %v, want _digest and preview", digest)
+	}
+	if preview, _ := digest["preview"].(string); len([]rune(preview)) > 101 {
+		t.Errorf("preview has %d runes", len([]rune(preview)))
+	}
+}
+
+func TestTurnStart(t *testing.T) {
+	events := []*session.Event{
+		textEvent("user", "1"),
+		toolResponseEvent("x", nil),
+		textEvent("crm-assistant", "a"),
+		textEvent("user", "2"),
+		textEvent("crm-assistant", "b"),
+		textEvent("user", "3"),
+	}
+	for n, want := range map[int]int{1: 5, 2: 3, 3: 0, 4: 0} {
+		if got := turnStart(events, n); got != want {
+			t.Errorf("turnStart(%d) = %d, want %d", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package prompts
+
+import (
+	"strings"
+)
+
+// BuildHistorySummaryPrompt creates a prompt for rolling summarization of old dialogue.
+// previous is the summary from earlier compactions (may be empty), transcript — the turns to fold in.
+func BuildHistorySummaryPrompt(previous, transcript string) string {
+	var sb strings.Builder
+
+	sb.WriteString(`Ты сжимаешь историю диалога пользователя с AI-агентом amoCRM.
+Составь краткое содержание, которое заменит старые сообщения в контексте агента.
+
+Сохрани:
+- о каких сделках, контактах, компаниях, задачах шла речь — с названиями и ID
+- что было создано, изменено или удалено
+- договорённости, нерешённые вопросы и предпочтения пользователя
+
+Не пересказывай списки справочных значений и не добавляй ничего от себя.
+Пиши по-русски, обычным текстом без HTML и Markdown, не длиннее 15 пунктов.
+`)
+
+	if previous != "" {
+		sb.WriteString("\n## Предыдущее краткое содержание\n\n")
+		sb.WriteString(previous)
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n## Новые сообщения\n\n")
+	sb.WriteString(transcript)
+
+	return sb.String()
+}
+
+// BuildHistorySummarySection renders the summary of old dialogue for the system prompt.
+func BuildHistorySummarySection(summary string) string {
+	return "\n\n## Краткое содержание предыдущего диалога\n\n" +
+		"Старые сообщения удалены из контекста, ниже их краткое содержание. " +
+		"ID из него можно использовать, но перед изменением данных уточняй актуальное состояние через инструменты.\n\n" +
+		summary + "\n"
+}
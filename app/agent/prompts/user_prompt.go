@@ -0,0 +1,29 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BuildCurrentUserSection describes the user the agent works for, so that
+// "мои сделки", "мои задачи" resolve to the bound amoCRM user.
+// amoUserName is empty when the Telegram user is not bound to amoCRM.
+func BuildCurrentUserSection(amoUserName string, amoUserID int, email string) string {
+	var sb strings.Builder
+
+	sb.WriteString("\n\n## Текущий пользователь\n\n")
+	if amoUserName == "" {
+		sb.WriteString("Пользователь не привязан к сотруднику amoCRM. " +
+			"Если он просит «мои» сделки, задачи и т.п., попроси выполнить /bind или уточнить имя ответственного.\n")
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "Ты работаешь от имени сотрудника amoCRM «%s» (ID %d", amoUserName, amoUserID)
+	if email != "" {
+		fmt.Fprintf(&sb, ", %s", email)
+	}
+	sb.WriteString(").\n")
+	fmt.Fprintf(&sb, "- «мои», «у меня», «я» — это записи с ответственным %q: используй responsible_user_name / responsible_user_names с этим именем\n", amoUserName)
+	sb.WriteString("- Создаваемые сделки, контакты и задачи назначай на этого сотрудника, если пользователь не указал другого ответственного\n")
+	return sb.String()
+}
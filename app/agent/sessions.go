@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+
+	svcagent "github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
+)
+
+// stateSessionTitle keeps the first user message once it is compacted out of the session.
+const stateSessionTitle = "session_title"
+
+// ListSessions returns sessions of the user whose IDs start with prefix, most recent first.
+func (a *Agent) ListSessions(ctx context.Context, userID, prefix string) ([]svcagent.SessionInfo, error) {
+	resp, err := a.sessionService.List(ctx, &session.ListRequest{AppName: AppName, UserID: userID})
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+
+	var sessions []session.Session
+	for _, sess := range resp.Sessions {
+		if strings.HasPrefix(sess.ID(), prefix) {
+			sessions = append(sessions, sess)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUpdateTime().After(sessions[j].LastUpdateTime())
+	})
+
+	infos := make([]svcagent.SessionInfo, 0, len(sessions))
+	for _, sess := range sessions {
+		// List does not return events, load the session to find its title
+		full, err := a.sessionService.Get(ctx, &session.GetRequest{AppName: AppName, UserID: userID, SessionID: sess.ID()})
+		if err != nil {
+			return nil, fmt.Errorf("get session %s: %w", sess.ID(), err)
+		}
+		infos = append(infos, svcagent.SessionInfo{
+			ID:        sess.ID(),
+			Title:     sessionTitle(full.Session),
+			UpdatedAt: sess.LastUpdateTime(),
+		})
+	}
+	return infos, nil
+}
+
+// CreateSession creates an empty session, so it becomes the most recent one of the user.
+func (a *Agent) CreateSession(ctx context.Context, userID, sessionID string) error {
+	_, err := a.sessionService.Create(ctx, &session.CreateRequest{AppName: AppName, UserID: userID, SessionID: sessionID})
+	if err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+	return nil
+}
+
+// DeleteSession deletes a session with all its history.
+func (a *Agent) DeleteSession(ctx context.Context, userID, sessionID string) error {
+	if err := a.sessionService.Delete(ctx, &session.DeleteRequest{AppName: AppName, UserID: userID, SessionID: sessionID}); err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	return nil
+}
+
+// sessionTitle returns the saved title or the first user message of the session.
+func sessionTitle(sess session.Session) string {
+	if v, err := sess.State().Get(stateSessionTitle); err == nil {
+		if title, ok := v.(string); ok && title != "" {
+			return title
+		}
+	}
+	return firstUserMessage(sess.Events().All())
+}
+
+func firstUserMessage(events iter.Seq[*session.Event]) string {
+	for ev := range events {
+		if !isUserMessage(ev) {
+			continue
+		}
+		for _, part := range ev.Content.Parts {
+			if part.Text != "" {
+				return part.Text
+			}
+		}
+	}
+	return ""
+}
+
+// cancelledRunText is added to the session after a run interrupted by the user.
+const cancelledRunText = "[Ответ прерван пользователем. Запрос не был выполнен до конца — не продолжай его без новой просьбы.]"
+
+// RecordCancellation notes in the session that the user interrupted the last run.
+// Tool calls left without a response get a "cancelled" response, so that the history
+// stays valid for providers that require a response to every call.
+func (a *Agent) RecordCancellation(ctx context.Context, userID, sessionID string) error {
+	defer a.sessionLocks.lock(userID, sessionID)()
+
+	resp, err := a.sessionService.Get(ctx, &session.GetRequest{AppName: AppName, UserID: userID, SessionID: sessionID})
+	if err != nil {
+		return fmt.Errorf("get session: %w", err)
+	}
+	sess := resp.Session
+
+	var pending []*genai.FunctionCall
+	for ev := range sess.Events().All() {
+		if ev.Content == nil {
+			continue
+		}
+		for _, part := range ev.Content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				pending = append(pending, part.FunctionCall)
+			case part.FunctionResponse != nil:
+				pending = slices.DeleteFunc(pending, func(fc *genai.FunctionCall) bool {
+					return fc.ID == part.FunctionResponse.ID
+				})
+			}
+		}
+	}
+
+	if len(pending) > 0 {
+		ev := session.NewEvent("")
+		ev.Author = a.adkAgent.Name()
+		ev.Content = &genai.Content{Role: genai.RoleUser}
+		for _, fc := range pending {
+			ev.Content.Parts = append(ev.Content.Parts, &genai.Part{FunctionResponse: &genai.FunctionResponse{
+				ID:       fc.ID,
+				Name:     fc.Name,
+				Response: map[string]any{"status": "cancelled", "message": "Вызов прерван пользователем"},
+			}})
+		}
+		if err := a.sessionService.AppendEvent(ctx, sess, ev); err != nil {
+			return fmt.Errorf("append cancelled tool responses: %w", err)
+		}
+	}
+
+	ev := session.NewEvent("")
+	ev.Author = a.adkAgent.Name()
+	ev.Content = genai.NewContentFromText(cancelledRunText, genai.RoleModel)
+	if err := a.sessionService.AppendEvent(ctx, sess, ev); err != nil {
+		return fmt.Errorf("append cancellation note: %w", err)
+	}
+	return nil
+}
+
+// sessionLocks serializes everything that writes to one session: runs, history
+// compaction before a run and cancellation notes after it.
+type sessionLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sessionLock
+}
+
+type sessionLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lock locks the session and returns the unlock function.
+func (l *sessionLocks) lock(userID, sessionID string) (unlock func()) {
+	key := userID + "/" + sessionID
+
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*sessionLock)
+	}
+	sl, ok := l.locks[key]
+	if !ok {
+		sl = &sessionLock{}
+		l.locks[key] = sl
+	}
+	sl.refs++
+	l.mu.Unlock()
+
+	sl.mu.Lock()
+	return func() {
+		sl.mu.Unlock()
+		l.mu.Lock()
+		if sl.refs--; sl.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -1259,3 +1260,48 @@ func (t *ActivitiesTool) handleTalks(ctx tool.Context, input models.ActivitiesIn
 		return nil, fmt.Errorf("unknown action: %s (talks supports 'get' and 'close')", input.Action)
 	}
 }
+
+// writeTargets реализует writeTargeter: родительская запись изменяющих действий, цели links
+// и сами задачи для tasks.update/complete. Теги и беседы записям не принадлежат.
+func (t *ActivitiesTool) writeTargets(_ context.Context, raw map[string]any) ([]record, error) {
+	input, err := decodeArgs[models.ActivitiesInput](raw)
+	if err != nil {
+		return nil, fmt.Errorf("activities: unmarshal input: %w", err)
+	}
+
+	var targets []record
+	addParent := func() {
+		if input.Parent != nil && ownedType(input.Parent.Type) && input.Parent.ID != 0 {
+			targets = append(targets, record{entityType: input.Parent.Type, id: input.Parent.ID})
+		}
+	}
+	switch input.Layer {
+	case "tasks":
+		switch input.Action {
+		case "create":
+			addParent()
+		case "update", "complete":
+			if input.ID != 0 {
+				targets = append(targets, record{entityType: "tasks", id: input.ID})
+			}
+		}
+	case "notes", "calls", "files", "subscriptions":
+		if input.Action != "list" && input.Action != "get" {
+			addParent()
+		}
+	case "links":
+		if input.Action == "link" || input.Action == "unlink" {
+			addParent()
+			linksTo := input.LinksTo
+			if input.LinkTo != nil {
+				linksTo = append(linksTo, *input.LinkTo)
+			}
+			for _, to := range linksTo {
+				if ownedType(to.Type) && to.ID != 0 {
+					targets = append(targets, record{entityType: to.Type, id: to.ID})
+				}
+			}
+		}
+	}
+	return targets, nil
+}
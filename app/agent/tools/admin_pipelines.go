@@ -271,7 +271,7 @@ func adminPipelinesSchema(action string) (any, error) {
 	case "get":
 		base.Description = "Получить воронку по ID или имени."
 		base.RequiredFields = map[string]fieldDesc{
-			"pipeline_id OR pipeline_name": {Type: "int | string", Description: "Одно из двух: числовой ID или точное имя воронки", Required: true},
+			"pipeline_id OR pipeline_name": {Type: "int | string", Description: "Одно из двух: числовой ID или имя воронки (регистр не важен, при опечатке вернутся похожие варианты)", Required: true},
 		}
 		base.OptionalFields = map[string]fieldDesc{
 			"pipeline_id":   {Type: "integer", Description: "Числовой ID воронки"},
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -569,3 +570,17 @@ func joinNames(names []string) string {
 	return result
 }
 
+// writeTargets реализует writeTargeter: сущность, к которой привязывается или от которой отвязывается элемент.
+func (t *CatalogsTool) writeTargets(_ context.Context, raw map[string]any) ([]record, error) {
+	input, err := mapToCatalogsInput(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch input.Action {
+	case "link_element", "unlink_element":
+		if input.LinkData != nil && ownedType(input.LinkData.EntityType) && input.LinkData.EntityID != 0 {
+			return []record{{entityType: input.LinkData.EntityType, id: input.LinkData.EntityID}}, nil
+		}
+	}
+	return nil, nil
+}
@@ -66,7 +66,7 @@ func (t *ComplexCreateTool) Declaration() *genai.FunctionDeclaration {
 						"pipeline_name":         {Type: genai.TypeString, Description: "Название воронки"},
 						"status_name":           {Type: genai.TypeString, Description: "Название статуса в воронке"},
 						"responsible_user_name": {Type: genai.TypeString, Description: "Имя ответственного пользователя"},
-						"custom_fields_values":  {Type: genai.TypeObject, Description: "Кастомные поля сделки. Формат: {field_id: [{value: значение}]}"},
+						"custom_fields_values":  {Type: genai.TypeObject, Description: customFieldsValuesDescription},
 						"tags": {
 							Type:        genai.TypeArray,
 							Description: "Теги сделки (названия)",
@@ -87,7 +87,7 @@ func (t *ComplexCreateTool) Declaration() *genai.FunctionDeclaration {
 							"email":                 {Type: genai.TypeString, Description: "Email (добавляется как кастомное поле EMAIL)"},
 							"is_main":               {Type: genai.TypeBoolean, Description: "Основной контакт"},
 							"responsible_user_name": {Type: genai.TypeString, Description: "Имя ответственного пользователя"},
-							"custom_fields_values":  {Type: genai.TypeObject, Description: "Прочие кастомные поля контакта"},
+							"custom_fields_values":  {Type: genai.TypeObject, Description: customFieldsValuesDescription},
 						},
 					},
 				},
@@ -97,7 +97,7 @@ func (t *ComplexCreateTool) Declaration() *genai.FunctionDeclaration {
 					Properties: map[string]*genai.Schema{
 						"name":                  {Type: genai.TypeString, Description: "Название компании"},
 						"responsible_user_name": {Type: genai.TypeString, Description: "Имя ответственного пользователя"},
-						"custom_fields_values":  {Type: genai.TypeObject, Description: "Кастомные поля компании"},
+						"custom_fields_values":  {Type: genai.TypeObject, Description: customFieldsValuesDescription},
 					},
 				},
 				"items": {
@@ -167,7 +167,7 @@ func (t *ComplexCreateTool) complexCreateSchema() map[string]any {
 							"pipeline_name":         map[string]any{"type": "string", "description": "Название воронки"},
 							"status_name":           map[string]any{"type": "string", "description": "Название статуса в воронке"},
 							"responsible_user_name": map[string]any{"type": "string", "description": "Имя ответственного пользователя"},
-							"custom_fields_values":  map[string]any{"type": "object", "description": "Кастомные поля сделки. Формат: {field_id: [{value: значение}]}"},
+							"custom_fields_values":  map[string]any{"type": "object", "description": customFieldsValuesDescription},
 							"tags":                  map[string]any{"type": "array", "items": "string", "description": "Теги сделки (названия)"},
 						},
 					},
@@ -184,7 +184,7 @@ func (t *ComplexCreateTool) complexCreateSchema() map[string]any {
 							"email":                 map[string]any{"type": "string", "description": "Email (добавляется как кастомное поле EMAIL)"},
 							"is_main":               map[string]any{"type": "boolean", "description": "Основной контакт"},
 							"responsible_user_name": map[string]any{"type": "string", "description": "Имя ответственного пользователя"},
-							"custom_fields_values":  map[string]any{"type": "object", "description": "Прочие кастомные поля контакта"},
+							"custom_fields_values":  map[string]any{"type": "object", "description": customFieldsValuesDescription},
 						},
 					},
 					"company": map[string]any{
@@ -193,7 +193,7 @@ func (t *ComplexCreateTool) complexCreateSchema() map[string]any {
 						"fields": map[string]any{
 							"name":                  map[string]any{"type": "string", "description": "Название компании"},
 							"responsible_user_name": map[string]any{"type": "string", "description": "Имя ответственного пользователя"},
-							"custom_fields_values":  map[string]any{"type": "object", "description": "Кастомные поля компании"},
+							"custom_fields_values":  map[string]any{"type": "object", "description": customFieldsValuesDescription},
 						},
 					},
 				},
@@ -240,6 +240,11 @@ func (t *ComplexCreateTool) complexCreateSchema() map[string]any {
 			"pipelines": t.service.PipelineNames(),
 			"statuses":  t.service.StatusesByPipeline(),
 			"users":     t.service.UserNames(),
+			"custom_fields": map[string]any{
+				"leads":     describeCustomFields(t.service.CustomFields("leads")),
+				"contacts":  describeCustomFields(t.service.CustomFields("contacts")),
+				"companies": describeCustomFields(t.service.CustomFields("companies")),
+			},
 		},
 	}
 }
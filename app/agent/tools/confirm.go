@@ -0,0 +1,267 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
+)
+
+// runnableTool — инструмент, который ADK может вызвать: декларация + Run.
+type runnableTool interface {
+	declaringTool
+	Run(ctx tool.Context, args any) (map[string]any, error)
+}
+
+// confirmRule решает, требует ли вызов подтверждения пользователя.
+// Возвращает описание действия и true, если вызов будет выполнен (не schema mode)
+// и относится к удалению или массовому изменению.
+type confirmRule func(raw map[string]any) (summary string, ok bool)
+
+// confirmRules — правила подтверждения по имени инструмента.
+var confirmRules = map[string]confirmRule{
+	"products":        productsConfirmRule,
+	"customers":       customersConfirmRule,
+	"files":           filesConfirmRule,
+	"admin_schema":    adminSchemaConfirmRule,
+	"admin_pipelines": adminPipelinesConfirmRule,
+	"entities":        entitiesConfirmRule,
+}
+
+// withConfirmation оборачивает инструмент проверкой подтверждения, если для него есть правило.
+func withConfirmation(t runnableTool) runnableTool {
+	rule, ok := confirmRules[t.Name()]
+	if !ok {
+		return t
+	}
+	return &confirmingTool{runnableTool: t, rule: rule}
+}
+
+// confirmingTool перед опасными действиями приостанавливает ход агента и ждёт
+// подтверждения пользователя через agent.Confirmer из контекста.
+// Если Confirmer не задан (канал не умеет спрашивать), действие не выполняется.
+type confirmingTool struct {
+	runnableTool
+	rule confirmRule
+}
+
+// ProcessRequest регистрирует в LLM request обёртку, а не исходный инструмент:
+// ADK вызывает Run у того, что лежит в req.Tools.
+func (t *confirmingTool) ProcessRequest(_ tool.Context, req *model.LLMRequest) error {
+	return packToolDeclaration(req, t)
+}
+
+// Run implements the ADK runnableTool interface.
+func (t *confirmingTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	raw, ok := args.(map[string]any)
+	if !ok {
+		return t.runnableTool.Run(ctx, args)
+	}
+	summary, needed := t.rule(raw)
+	if !needed {
+		return t.runnableTool.Run(ctx, args)
+	}
+
+	action, _ := raw["action"].(string)
+	confirmer, ok := agent.ConfirmerFromContext(ctx)
+	if !ok {
+		return map[string]any{
+			"status":  "not_confirmed",
+			"message": "Действие требует подтверждения пользователя, но в этом канале подтверждение недоступно. Не выполняй его.",
+		}, nil
+	}
+
+	approved, err := confirmer.Confirm(ctx, agent.Confirmation{
+		Tool:    t.Name(),
+		Action:  action,
+		Summary: summary,
+	})
+	switch {
+	case errors.Is(err, agent.ErrConfirmationTimeout):
+		return map[string]any{
+			"status":  "timeout",
+			"message": "Пользователь не подтвердил действие вовремя, оно не выполнено. Не повторяй вызов без новой просьбы пользователя.",
+		}, nil
+	case err != nil:
+		return nil, fmt.Errorf("%s: confirmation: %w", t.Name(), err)
+	case !approved:
+		return map[string]any{
+			"status":  "cancelled",
+			"message": "Пользователь отменил действие, оно не выполнено. Не повторяй вызов без новой просьбы пользователя.",
+		}, nil
+	}
+	return t.runnableTool.Run(ctx, args)
+}
+
+// ============ Правила ============
+
+func productsConfirmRule(raw map[string]any) (string, bool) {
+	action, _ := raw["action"].(string)
+	if isProductsSchemaMode(raw, action) {
+		return "", false
+	}
+	switch action {
+	case "delete":
+		return "Удалить товары: " + describeIDs(raw["ids"]), true
+	case "update":
+		if n := listLen(raw["items"]); n > 1 {
+			return fmt.Sprintf("Массово обновить товары: %d шт.", n), true
+		}
+	}
+	return "", false
+}
+
+// customersObjects — что удаляется в каждом layer customers (родительный падеж).
+var customersObjects = map[string]string{
+	"customers":    "покупателя",
+	"statuses":     "статуса покупателей",
+	"transactions": "транзакции",
+	"segments":     "сегмента",
+}
+
+func customersConfirmRule(raw map[string]any) (string, bool) {
+	layer, _ := raw["layer"].(string)
+	action, _ := raw["action"].(string)
+	if customersIsSchemaMode(layer, action, raw) {
+		return "", false
+	}
+	switch {
+	case action == "delete":
+		object, ok := customersObjects[layer]
+		if !ok {
+			object = layer
+		}
+		return fmt.Sprintf("Удаление %s с ID %s", object, describeValue(raw["id"])), true
+	case action == "update" && layer == "customers":
+		if n := listLen(raw["batch"]); n > 1 {
+			return fmt.Sprintf("Массово обновить покупателей: %d шт.", n), true
+		}
+	}
+	return "", false
+}
+
+func filesConfirmRule(raw map[string]any) (string, bool) {
+	action, _ := raw["action"].(string)
+	if action != "delete" || isFilesSchemaMode(action, raw) {
+		return "", false
+	}
+	if uuid, _ := raw["uuid"].(string); uuid != "" {
+		return "Удалить файл " + uuid, true
+	}
+	return "Удалить файлы: " + describeIDs(raw["uuids"]), true
+}
+
+// adminSchemaObjects — что удаляется в каждом layer admin_schema (родительный падеж).
+var adminSchemaObjects = map[string]string{
+	"custom_fields": "поля",
+	"field_groups":  "группы полей",
+	"loss_reasons":  "причины отказа",
+	"sources":       "источника",
+}
+
+func adminSchemaConfirmRule(raw map[string]any) (string, bool) {
+	layer, _ := raw["layer"].(string)
+	action, _ := raw["action"].(string)
+	if action != "delete" || adminSchemaIsSchemaMode(raw, layer, action) {
+		return "", false
+	}
+	object, ok := adminSchemaObjects[layer]
+	if !ok {
+		object = layer
+	}
+	id := raw["id"]
+	if layer == "field_groups" {
+		id = raw["group_id"]
+	}
+	summary := fmt.Sprintf("Удаление %s с ID %s", object, describeValue(id))
+	if entityType, _ := raw["entity_type"].(string); entityType != "" {
+		summary += " (" + entityType + ")"
+	}
+	return summary, true
+}
+
+func adminPipelinesConfirmRule(raw map[string]any) (string, bool) {
+	action, _ := raw["action"].(string)
+	if adminPipelinesIsSchemaMode(action, raw) {
+		return "", false
+	}
+	pipeline := describeNamed(raw["pipeline_name"], raw["pipeline_id"])
+	switch action {
+	case "delete":
+		return "Удалить воронку " + pipeline + " вместе с её статусами", true
+	case "delete_status":
+		return fmt.Sprintf("Удалить статус %s в воронке %s", describeNamed(raw["status_name"], raw["status_id"]), pipeline), true
+	}
+	return "", false
+}
+
+// entitiesObjects — названия сущностей entities во множественном числе.
+var entitiesObjects = map[string]string{
+	"leads":     "сделки",
+	"contacts":  "контакты",
+	"companies": "компании",
+}
+
+func entitiesConfirmRule(raw map[string]any) (string, bool) {
+	action, _ := raw["action"].(string)
+	if action != "update" {
+		return "", false
+	}
+	n := listLen(raw["data_list"])
+	if n <= 1 {
+		return "", false
+	}
+	entityType, _ := raw["entity_type"].(string)
+	object, ok := entitiesObjects[entityType]
+	if !ok {
+		object = "записи"
+	}
+	return fmt.Sprintf("Массово обновить %s: %d шт.", object, n), true
+}
+
+// ============ Форматирование ============
+
+// maxListedIDs — сколько ID показывать в описании, остальные сворачиваются в «и ещё N».
+const maxListedIDs = 10
+
+func listLen(v any) int {
+	arr, _ := v.([]any)
+	return len(arr)
+}
+
+// describeIDs форматирует массив ID: "12, 34, 56 (3 шт.)".
+func describeIDs(v any) string {
+	arr, _ := v.([]any)
+	parts := make([]string, 0, min(len(arr), maxListedIDs))
+	for i, item := range arr {
+		if i == maxListedIDs {
+			break
+		}
+		parts = append(parts, describeValue(item))
+	}
+	s := strings.Join(parts, ", ")
+	if len(arr) > maxListedIDs {
+		s += fmt.Sprintf(" и ещё %d", len(arr)-maxListedIDs)
+	}
+	return fmt.Sprintf("%s (%d шт.)", s, len(arr))
+}
+
+// describeNamed возвращает «имя», если оно задано, иначе ID.
+func describeNamed(name, id any) string {
+	if s, _ := name.(string); s != "" {
+		return "«" + s + "»"
+	}
+	return "ID " + describeValue(id)
+}
+
+// describeValue форматирует скаляр из JSON аргументов (числа приходят как float64).
+func describeValue(v any) string {
+	if f, ok := v.(float64); ok && f == float64(int64(f)) {
+		return fmt.Sprintf("%d", int64(f))
+	}
+	return fmt.Sprint(v)
+}
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -700,3 +701,16 @@ func (t *CustomersTool) executeCustomers(ctx tool.Context, input *models.Custome
 		return nil, fmt.Errorf("unknown layer: %s", input.Layer)
 	}
 }
+
+// writeTargets реализует writeTargeter: контакт или компания, к которым привязывается покупатель.
+func (t *CustomersTool) writeTargets(_ context.Context, raw map[string]any) ([]record, error) {
+	input, err := decodeArgs[models.CustomersInput](raw)
+	if err != nil {
+		return nil, fmt.Errorf("customers: unmarshal input: %w", err)
+	}
+	if input.Layer == "customers" && input.Action == "link" && input.LinkData != nil &&
+		ownedType(input.LinkData.EntityType) && input.LinkData.EntityID != 0 {
+		return []record{{entityType: input.LinkData.EntityType, id: input.LinkData.EntityID}}, nil
+	}
+	return nil, nil
+}
@@ -10,8 +10,9 @@ import (
 	"google.golang.org/adk/tool"
 	"google.golang.org/genai"
 
-	"github.com/tihn/amo-ai-tgbot-go/internal/services/crm/entities"
 	toolmodels "github.com/tihn/amo-ai-tgbot-go/internal/models/tools"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/account_context"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/crm/entities"
 )
 
 // EntitiesTool — нативный ADK tool для работы с основными сущностями amoCRM (Shadow Tool паттерн).
@@ -157,15 +158,11 @@ func (t *EntitiesTool) entitiesBuildSchemaResponse(entityType, action string) ma
 
 	// Справочные данные
 	availableValues := map[string]any{
-		"pipelines":    svc.PipelineNames(),
-		"statuses":     svc.StatusesByPipeline(),
-		"users":        svc.UserNames(),
-		"loss_reasons": svc.LossReasonNames(),
-		"custom_field_codes": map[string]any{
-			"leads":     svc.CustomFieldCodes("leads"),
-			"contacts":  svc.CustomFieldCodes("contacts"),
-			"companies": svc.CustomFieldCodes("companies"),
-		},
+		"pipelines":     svc.PipelineNames(),
+		"statuses":      svc.StatusesByPipeline(),
+		"users":         svc.UserNames(),
+		"loss_reasons":  svc.LossReasonNames(),
+		"custom_fields": describeCustomFields(svc.CustomFields(entityType)),
 	}
 
 	required, optional, description, example := entitiesSchemaForAction(entityType, action)
@@ -183,13 +180,38 @@ func (t *EntitiesTool) entitiesBuildSchemaResponse(entityType, action string) ma
 	}
 }
 
+// customFieldsValuesDescription — формат custom_fields_values для create и update.
+const customFieldsValuesDescription = "Кастомные поля (из available_values.custom_fields): {\"Название или код поля\": значение}. " +
+	"Списки — название варианта (multiselect — массив названий), даты — \"15.01.2024\" или \"2024-01-15\", флажки — да/нет, числа — \"150 000\". " +
+	"Телефон и email — строка или [{\"value\": \"+7...\", \"enum_code\": \"MOB\"}] (WORK, MOB, HOME, PRIV, OTHER)"
+
+// describeCustomFields описывает кастомные поля для schema response: название, код, тип и варианты списков.
+func describeCustomFields(fields []account_context.CustomField) []map[string]any {
+	result := make([]map[string]any, 0, len(fields))
+	for _, f := range fields {
+		d := map[string]any{"name": f.Name, "type": f.Type}
+		if f.Code != "" {
+			d["code"] = f.Code
+		}
+		if len(f.Enums) > 0 {
+			options := make([]string, len(f.Enums))
+			for i, e := range f.Enums {
+				options[i] = e.Name
+			}
+			d["options"] = options
+		}
+		result = append(result, d)
+	}
+	return result
+}
+
 // entitiesSchemaForAction возвращает описание полей, description и пример для action.
 func entitiesSchemaForAction(entityType, action string) (required, optional map[string]any, description string, example map[string]any) {
 	// Общие опциональные поля для data объекта
 	dataFields := map[string]any{
 		"name":                  map[string]any{"type": "string", "description": "Название"},
 		"responsible_user_name": map[string]any{"type": "string", "description": "Имя ответственного (из available_values.users)"},
-		"custom_fields_values":  map[string]any{"type": "object", "description": "Кастомные поля: {\"FIELD_CODE\": \"значение\"} или {\"PHONE\": [{\"value\": \"+7...\", \"enum_code\": \"WORK\"}]}"},
+		"custom_fields_values":  map[string]any{"type": "object", "description": customFieldsValuesDescription},
 		"tags":                  map[string]any{"type": "array", "description": "Теги: [{\"name\": \"тег\"}]"},
 	}
 	if entityType == "leads" {
@@ -217,7 +239,7 @@ func entitiesSchemaForAction(entityType, action string) (required, optional map[
 		"created_at_to":          map[string]any{"type": "string", "description": "Дата создания до (ISO-8601)"},
 		"updated_at_from":        map[string]any{"type": "string", "description": "Дата обновления от (ISO-8601)"},
 		"updated_at_to":          map[string]any{"type": "string", "description": "Дата обновления до (ISO-8601)"},
-		"custom_fields_values":   map[string]any{"type": "array", "description": "Фильтр по кастомным полям: [{\"field_code\": \"PHONE\", \"values\": [\"+7...\"]}]"},
+		"custom_fields_values":   map[string]any{"type": "array", "description": "Фильтр по кастомным полям (из available_values.custom_fields): [{\"field_code\": \"Телефон\", \"values\": [\"+7...\"]}], field_code — название, код или ID поля"},
 	}
 	if entityType == "leads" {
 		filterFields["pipeline_names"] = map[string]any{"type": "array", "description": "Воронки (из available_values.pipelines)"}
@@ -566,3 +588,45 @@ func (t *EntitiesTool) handleCompanies(ctx context.Context, input toolmodels.Ent
 	}
 }
 
+// writeTargets реализует writeTargeter: изменяемые записи (id, data.id, data_list[].id),
+// обе стороны link/unlink и контакты link_chats. create новых записей не затрагивает.
+func (t *EntitiesTool) writeTargets(_ context.Context, raw map[string]any) ([]record, error) {
+	input, err := decodeArgs[toolmodels.EntitiesInput](raw)
+	if err != nil {
+		return nil, fmt.Errorf("entities: unmarshal input: %w", err)
+	}
+	if !ownedType(input.EntityType) {
+		return nil, fmt.Errorf("unknown entity_type: %s", input.EntityType)
+	}
+
+	var targets []record
+	add := func(entityType string, id int) {
+		if id != 0 {
+			targets = append(targets, record{entityType: entityType, id: id})
+		}
+	}
+	switch input.Action {
+	case "update", "sync":
+		add(input.EntityType, input.ID)
+		if input.Data != nil {
+			add(input.EntityType, input.Data.ID)
+		}
+		for _, data := range input.DataList {
+			add(input.EntityType, data.ID)
+		}
+	case "link", "unlink":
+		add(input.EntityType, input.ID)
+		if input.LinkTo != nil && ownedType(input.LinkTo.Type) {
+			add(input.LinkTo.Type, input.LinkTo.ID)
+		}
+	case "link_chats":
+		links, _ := raw["chat_links"].([]any)
+		for _, item := range links {
+			link, _ := item.(map[string]any)
+			if id, ok := link["contact_id"].(float64); ok {
+				add("contacts", int(id))
+			}
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/crm/activities"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/crm/entities"
+)
+
+// crmOwners реализует ownerLookup: загружает запись из amoCRM и возвращает её ответственного.
+type crmOwners struct {
+	entities   entities.Service
+	activities activities.Service
+}
+
+func (o crmOwners) responsibleUser(ctx context.Context, r record) (recordOwner, error) {
+	if r.entityType == "tasks" {
+		task, err := o.activities.GetTask(ctx, r.id, nil)
+		if err != nil {
+			return recordOwner{}, err
+		}
+		return recordOwner{id: task.ResponsibleUserID, name: task.ResponsibleUserName}, nil
+	}
+
+	var (
+		result *entities.EntityResult
+		err    error
+	)
+	switch r.entityType {
+	case "leads":
+		result, err = o.entities.GetLead(ctx, r.id, nil)
+	case "contacts":
+		result, err = o.entities.GetContact(ctx, r.id, nil)
+	case "companies":
+		result, err = o.entities.GetCompany(ctx, r.id, nil)
+	default:
+		return recordOwner{}, fmt.Errorf("unknown entity type: %s", r.entityType)
+	}
+	if err != nil {
+		return recordOwner{}, err
+	}
+	return recordOwner{id: result.ResponsibleUserID, name: result.ResponsibleUserName}, nil
+}
+
+// ownedType сообщает, есть ли у записей этого типа ответственный.
+// Покупатели и элементы каталогов проверкой своих записей не ограничиваются.
+func ownedType(entityType string) bool {
+	switch entityType {
+	case "leads", "contacts", "companies":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/access"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
+)
+
+// permission — решение политики для action инструмента.
+type permission int
+
+const (
+	denied permission = iota
+	allowed
+	// allowedOwn — только если все затронутые записи принадлежат пользователю (см. writeTargeter).
+	allowedOwn
+)
+
+// readActions — действия, которые только читают данные.
+var readActions = map[string]bool{
+	"search":        true,
+	"list":          true,
+	"get":           true,
+	"get_by_entity": true,
+	"get_statuses":  true,
+	"list_statuses": true,
+	"get_status":    true,
+	"get_chats":     true,
+	"summary":       true,
+	"list_elements": true,
+	"get_element":   true,
+}
+
+// rolePolicy — права роли: скрытые инструменты не передаются модели вовсе,
+// rule решает для каждого action остальных инструментов.
+type rolePolicy struct {
+	hidden map[string]bool
+	rule   func(toolName, action string) permission
+}
+
+// rolePolicies — профили прав по ролям access.Role.
+var rolePolicies = map[access.Role]rolePolicy{
+	access.RoleReadOnly: {
+		hidden: map[string]bool{"admin_integrations": true},
+		rule: func(_, action string) permission {
+			if readActions[action] {
+				return allowed
+			}
+			return denied
+		},
+	},
+	access.RoleSales: {
+		hidden: map[string]bool{"admin_integrations": true},
+		rule: func(toolName, action string) permission {
+			switch {
+			case readActions[action]:
+				return allowed
+			case strings.HasPrefix(toolName, "admin_"):
+				// Настройки аккаунта — только чтение
+				return denied
+			case strings.HasPrefix(action, "delete"):
+				return denied
+			}
+			// Любое изменение — только своих сделок, контактов, компаний и задач
+			return allowedOwn
+		},
+	},
+	access.RoleAdmin: {
+		rule: func(string, string) permission { return allowed },
+	},
+}
+
+// policyFor возвращает политику пользователя из контекста.
+// ok = false — ограничений нет (контроль доступа выключен или вызов не от пользователя бота).
+func policyFor(ctx context.Context) (policy rolePolicy, id agent.Identity, ok bool) {
+	id, found := agent.IdentityFromContext(ctx)
+	if !found || id.Role == "" {
+		return rolePolicy{}, id, false
+	}
+	policy, known := rolePolicies[access.Role(id.Role)]
+	if !known {
+		// Неизвестная роль — ничего не разрешаем
+		policy = rolePolicy{rule: func(string, string) permission { return denied }}
+	}
+	return policy, id, true
+}
+
+// filterTools убирает инструменты, скрытые для роли пользователя.
+func filterTools(ctx context.Context, tools []tool.Tool) []tool.Tool {
+	policy, _, ok := policyFor(ctx)
+	if !ok || len(policy.hidden) == 0 {
+		return tools
+	}
+	return slices.DeleteFunc(slices.Clone(tools), func(t tool.Tool) bool {
+		return policy.hidden[t.Name()]
+	})
+}
+
+// record — запись amoCRM, которую изменит вызов инструмента.
+type record struct {
+	entityType string // leads, contacts, companies или tasks
+	id         int
+}
+
+func (r record) String() string {
+	return fmt.Sprintf("%s %d", r.entityType, r.id)
+}
+
+// recordOwner — ответственный за запись.
+type recordOwner struct {
+	id   int
+	name string
+}
+
+// writeTargeter реализуют инструменты, которые изменяют сделки, контакты, компании или задачи.
+// Изменения allowedOwn у инструментов без него разрешены, только если action есть в createOnly.
+type writeTargeter interface {
+	// writeTargets возвращает записи, которые изменит вызов. Пустой список — вызов
+	// только создаёт новые записи.
+	writeTargets(ctx context.Context, raw map[string]any) ([]record, error)
+}
+
+// createOnly — действия инструментов без writeTargeter, которые только создают новое.
+// Остальные их изменения для allowedOwn запрещены: чьи записи они затронут, не проверить.
+var createOnly = map[string]map[string]bool{
+	"complex_create": {"create": true, "create_batch": true},
+	"files":          {"upload": true},
+}
+
+// ownerLookup находит ответственного за запись (см. crmOwners).
+type ownerLookup interface {
+	responsibleUser(ctx context.Context, r record) (recordOwner, error)
+}
+
+// withPermissions оборачивает инструмент проверкой прав роли.
+// targets — исходный инструмент, если он изменяет записи с ответственным (иначе nil).
+func withPermissions(t runnableTool, targets writeTargeter, owners ownerLookup) runnableTool {
+	return &guardedTool{runnableTool: t, targets: targets, owners: owners}
+}
+
+// guardedTool проверяет права роли до выполнения (и до запроса подтверждения)
+// и возвращает модели понятную ошибку доступа.
+type guardedTool struct {
+	runnableTool
+	targets writeTargeter
+	owners  ownerLookup
+}
+
+// ProcessRequest регистрирует в LLM request обёртку, а не исходный инструмент.
+func (t *guardedTool) ProcessRequest(_ tool.Context, req *model.LLMRequest) error {
+	return packToolDeclaration(req, t)
+}
+
+// Run implements the ADK runnableTool interface.
+func (t *guardedTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	if err := t.authorize(ctx, args); err != nil {
+		return nil, err
+	}
+	return t.runnableTool.Run(ctx, args)
+}
+
+func (t *guardedTool) authorize(ctx context.Context, args any) error {
+	policy, id, ok := policyFor(ctx)
+	if !ok {
+		return nil
+	}
+	if policy.hidden[t.Name()] {
+		return permissionError(id.Role, t.Name(), "")
+	}
+
+	raw, _ := args.(map[string]any)
+	action, _ := raw["action"].(string)
+	if action == "" {
+		// Без action инструмент только возвращает список действий
+		return nil
+	}
+
+	switch policy.rule(t.Name(), action) {
+	case allowed:
+		return nil
+	case allowedOwn:
+		return t.authorizeOwn(ctx, id, action, raw)
+	}
+	return permissionError(id.Role, t.Name(), action)
+}
+
+// authorizeOwn разрешает действие, только если все затронутые записи принадлежат пользователю:
+// их responsible_user_id совпадает с привязанным сотрудником amoCRM.
+func (t *guardedTool) authorizeOwn(ctx context.Context, id agent.Identity, action string, raw map[string]any) error {
+	if t.targets == nil {
+		if createOnly[t.Name()][action] {
+			return nil
+		}
+		return permissionError(id.Role, t.Name(), action)
+	}
+	targets, err := t.targets.writeTargets(ctx, raw)
+	if err != nil {
+		return fmt.Errorf("%s: check responsible user: %w", t.Name(), err)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	if t.owners == nil {
+		return permissionError(id.Role, t.Name(), action)
+	}
+	if id.AmoUserID == 0 {
+		return fmt.Errorf("permission denied: %s.%s разрешено только для своих записей, "+
+			"а пользователь не привязан к сотруднику amoCRM. Предложи ему выполнить /bind", t.Name(), action)
+	}
+
+	var foreign []string
+	for _, r := range targets {
+		owner, err := t.owners.responsibleUser(ctx, r)
+		if err != nil {
+			return fmt.Errorf("%s: check responsible user of %s: %w", t.Name(), r, err)
+		}
+		if owner.id != id.AmoUserID {
+			foreign = append(foreign, fmt.Sprintf("%s (ответственный: %s)", r, owner.name))
+		}
+	}
+	if len(foreign) > 0 {
+		slices.Sort(foreign)
+		foreign = slices.Compact(foreign)
+		return fmt.Errorf("permission denied: роль %q может выполнять %s.%s только для своих записей, "+
+			"а эти записи принадлежат другим сотрудникам: %s. Сообщи пользователю, что у него нет прав",
+			id.Role, t.Name(), action, strings.Join(foreign, ", "))
+	}
+	return nil
+}
+
+// decodeArgs переводит аргументы вызова в типизированный input инструмента (JSON roundtrip).
+func decodeArgs[T any](raw map[string]any) (T, error) {
+	var input T
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return input, err
+	}
+	err = json.Unmarshal(b, &input)
+	return input, err
+}
+
+func permissionError(role, toolName, action string) error {
+	target := toolName
+	if action != "" {
+		target += "." + action
+	}
+	return fmt.Errorf("permission denied: роль %q не может выполнять %s. "+
+		"Не пытайся обойти ограничение другими инструментами — сообщи пользователю, что у него нет прав на это действие", role, target)
+}
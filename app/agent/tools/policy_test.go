@@ -0,0 +1,169 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
+)
+
+// fakeOwners — ответственные по записям; для остальных записей ошибка.
+type fakeOwners map[record]int
+
+func (o fakeOwners) responsibleUser(_ context.Context, r record) (recordOwner, error) {
+	id, ok := o[r]
+	if !ok {
+		return recordOwner{}, errors.New("not found")
+	}
+	return recordOwner{id: id, name: fmt.Sprintf("user %d", id)}, nil
+}
+
+const (
+	me      = 7
+	someone = 8
+)
+
+var testOwners = fakeOwners{
+	{"leads", 1}:     me,
+	{"leads", 2}:     someone,
+	{"contacts", 10}: me,
+	{"contacts", 20}: someone,
+	{"companies", 5}: me,
+	{"companies", 6}: someone,
+	{"tasks", 100}:   me,
+	{"tasks", 200}:   someone,
+}
+
+func TestAuthorize(t *testing.T) {
+	tools := map[string]*guardedTool{}
+	for _, rt := range []runnableTool{
+		&EntitiesTool{}, &ActivitiesTool{}, &ProductsTool{}, &CatalogsTool{},
+		&UnsortedTool{}, &CustomersTool{}, &ComplexCreateTool{}, &FilesTool{}, &AdminSchemaTool{}, &AdminIntegrationsTool{},
+	} {
+		targets, _ := rt.(writeTargeter)
+		tools[rt.Name()] = withPermissions(rt, targets, testOwners).(*guardedTool)
+	}
+
+	type call struct {
+		tool string
+		args map[string]any
+	}
+	leads := func(action string, extra map[string]any) call {
+		args := map[string]any{"entity_type": "leads", "action": action}
+		for k, v := range extra {
+			args[k] = v
+		}
+		return call{tool: "entities", args: args}
+	}
+	activity := func(layer, action string, extra map[string]any) call {
+		args := map[string]any{"layer": layer, "action": action}
+		for k, v := range extra {
+			args[k] = v
+		}
+		return call{tool: "activities", args: args}
+	}
+	parent := func(entityType string, id float64) map[string]any {
+		return map[string]any{"parent": map[string]any{"type": entityType, "id": id}}
+	}
+
+	tests := []struct {
+		name string
+		call call
+		// ожидание по ролям: true — разрешено
+		readonly, sales, admin bool
+	}{
+		{name: "search", call: leads("search", nil), readonly: true, sales: true, admin: true},
+		{name: "get foreign", call: leads("get", map[string]any{"id": 2.0}), readonly: true, sales: true, admin: true},
+		{name: "create", call: leads("create", map[string]any{"data": map[string]any{"name": "x"}}), sales: true, admin: true},
+		{name: "update own", call: leads("update", map[string]any{"id": 1.0, "data": map[string]any{"name": "x"}}), sales: true, admin: true},
+		{name: "update foreign", call: leads("update", map[string]any{"id": 2.0, "data": map[string]any{"name": "x"}}), admin: true},
+		{name: "batch update with foreign", call: leads("update", map[string]any{"data_list": []any{
+			map[string]any{"id": 1.0, "name": "a"}, map[string]any{"id": 2.0, "name": "b"},
+		}}), admin: true},
+		{name: "sync without id", call: leads("sync", map[string]any{"data": map[string]any{"name": "x"}}), sales: true, admin: true},
+		{name: "sync own id", call: leads("sync", map[string]any{"id": 1.0, "data": map[string]any{"name": "x"}}), sales: true, admin: true},
+		{name: "sync foreign id", call: leads("sync", map[string]any{"id": 2.0, "data": map[string]any{"name": "x"}}), admin: true},
+		{name: "sync foreign data.id", call: leads("sync", map[string]any{"data": map[string]any{"id": 2.0, "name": "x"}}), admin: true},
+		{name: "link own", call: leads("link", map[string]any{"id": 1.0, "link_to": map[string]any{"type": "contacts", "id": 10.0}}), sales: true, admin: true},
+		{name: "link foreign lead", call: leads("link", map[string]any{"id": 2.0, "link_to": map[string]any{"type": "contacts", "id": 10.0}}), admin: true},
+		{name: "unlink foreign contact", call: leads("unlink", map[string]any{"id": 1.0, "link_to": map[string]any{"type": "contacts", "id": 20.0}}), admin: true},
+		{name: "delete own", call: leads("delete", map[string]any{"id": 1.0}), admin: true},
+		{name: "link_chats foreign", call: call{tool: "entities", args: map[string]any{
+			"entity_type": "contacts", "action": "link_chats",
+			"chat_links": []any{map[string]any{"chat_id": "c", "contact_id": 20.0}},
+		}}, admin: true},
+
+		{name: "note on own", call: activity("notes", "create", parent("leads", 1)), sales: true, admin: true},
+		{name: "note on foreign", call: activity("notes", "create", parent("leads", 2)), admin: true},
+		{name: "notes list foreign", call: activity("notes", "list", parent("leads", 2)), readonly: true, sales: true, admin: true},
+		{name: "task on foreign", call: activity("tasks", "create", parent("contacts", 20)), admin: true},
+		{name: "complete own task", call: activity("tasks", "complete", map[string]any{"id": 100.0}), sales: true, admin: true},
+		{name: "complete foreign task", call: activity("tasks", "complete", map[string]any{"id": 200.0}), admin: true},
+		{name: "link to foreign", call: activity("links", "link", map[string]any{
+			"parent":   map[string]any{"type": "leads", "id": 1.0},
+			"links_to": []any{map[string]any{"type": "contacts", "id": 20.0}},
+		}), admin: true},
+		{name: "subscribe to foreign", call: activity("subscriptions", "subscribe", parent("companies", 6)), admin: true},
+		{name: "tag create", call: activity("tags", "create", map[string]any{"parent": map[string]any{"type": "leads"}, "tag_name": "vip"}), sales: true, admin: true},
+
+		{name: "product to foreign lead", call: call{tool: "products", args: map[string]any{
+			"action": "link", "entity": map[string]any{"type": "leads", "id": 2.0}, "product": map[string]any{"id": 1.0},
+		}}, admin: true},
+		{name: "catalog element to own lead", call: call{tool: "catalogs", args: map[string]any{
+			"action": "link_element", "catalog_name": "c", "element_id": 1.0,
+			"link_data": map[string]any{"entity_type": "leads", "entity_id": 1.0},
+		}}, sales: true, admin: true},
+		{name: "unsorted to foreign lead", call: call{tool: "unsorted", args: map[string]any{
+			"action": "link", "uid": "u", "link_data": map[string]any{"lead_id": 2.0},
+		}}, admin: true},
+		{name: "customer to foreign contact", call: call{tool: "customers", args: map[string]any{
+			"layer": "customers", "action": "link", "customer_id": 1.0,
+			"link_data": map[string]any{"entity_type": "contacts", "entity_id": 20.0},
+		}}, admin: true},
+		{name: "complex create", call: call{tool: "complex_create", args: map[string]any{"action": "create"}}, sales: true, admin: true},
+		{name: "file upload", call: call{tool: "files", args: map[string]any{"action": "upload"}}, sales: true, admin: true},
+		{name: "file update", call: call{tool: "files", args: map[string]any{"action": "update", "uuid": "u"}}, admin: true},
+		{name: "unchecked tool write", call: call{tool: "complex_create", args: map[string]any{"action": "merge"}}, admin: true},
+		{name: "admin schema list", call: call{tool: "admin_schema", args: map[string]any{"layer": "custom_fields", "action": "list"}}, readonly: true, sales: true, admin: true},
+		{name: "admin schema create", call: call{tool: "admin_schema", args: map[string]any{"layer": "custom_fields", "action": "create"}}, admin: true},
+		{name: "hidden tool", call: call{tool: "admin_integrations", args: map[string]any{"layer": "webhooks", "action": "list"}}, admin: true},
+	}
+
+	for _, tt := range tests {
+		for role, want := range map[string]bool{"readonly": tt.readonly, "sales": tt.sales, "admin": tt.admin} {
+			t.Run(tt.name+"/"+role, func(t *testing.T) {
+				ctx := agent.ContextWithIdentity(context.Background(), agent.Identity{AmoUserID: me, Role: role})
+				err := tools[tt.call.tool].authorize(ctx, tt.call.args)
+				if want && err != nil {
+					t.Errorf("denied: %v", err)
+				}
+				if !want && (err == nil || !strings.HasPrefix(err.Error(), "permission denied")) {
+					t.Errorf("err = %v, want permission denied", err)
+				}
+			})
+		}
+	}
+}
+
+func TestAuthorizeWithoutBinding(t *testing.T) {
+	g := withPermissions(&EntitiesTool{}, &EntitiesTool{}, testOwners).(*guardedTool)
+	update := map[string]any{"entity_type": "leads", "action": "update", "id": 1.0, "data": map[string]any{"name": "x"}}
+
+	// Имя совпадает, но без ID сотрудника запись своей не считается
+	ctx := agent.ContextWithIdentity(context.Background(), agent.Identity{AmoUserName: "user 7", Role: "sales"})
+	if err := g.authorize(ctx, update); err == nil || !strings.Contains(err.Error(), "/bind") {
+		t.Errorf("err = %v, want a /bind hint", err)
+	}
+
+	// Без роли (контроль доступа выключен) ограничений нет
+	if err := g.authorize(context.Background(), update); err != nil {
+		t.Errorf("no identity: %v", err)
+	}
+	ctx = agent.ContextWithIdentity(context.Background(), agent.Identity{AmoUserID: me, Role: "guest"})
+	if err := g.authorize(ctx, map[string]any{"entity_type": "leads", "action": "search"}); err == nil {
+		t.Error("unknown role must be denied")
+	}
+}
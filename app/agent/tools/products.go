@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -484,3 +485,18 @@ func hasObjectField(raw map[string]any, key string) bool {
 	_, ok = v.(map[string]any)
 	return ok
 }
+
+// writeTargets реализует writeTargeter: сущность, к которой привязывается или от которой отвязывается товар.
+func (t *ProductsTool) writeTargets(_ context.Context, raw map[string]any) ([]record, error) {
+	input, err := decodeArgs[gkitmodels.ProductsInput](raw)
+	if err != nil {
+		return nil, fmt.Errorf("products: unmarshal input: %w", err)
+	}
+	switch input.Action {
+	case "link", "unlink", "update_quantity":
+		if input.Entity != nil && ownedType(input.Entity.Type) && input.Entity.ID != 0 {
+			return []record{{entityType: input.Entity.Type, id: input.Entity.ID}}, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"context"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+)
+
+// ReferenceRefresher перезагружает справочники аккаунта (account_context.AccountContext).
+type ReferenceRefresher interface {
+	Refresh(ctx context.Context)
+}
+
+// referenceTools — инструменты, которые меняют справочники: воронки и статусы,
+// кастомные поля, источники и причины отказа, пользователей.
+var referenceTools = map[string]bool{
+	"admin_pipelines": true,
+	"admin_schema":    true,
+	"admin_users":     true,
+}
+
+// withRefresh оборачивает инструмент, меняющий справочники, их перезагрузкой после изменения.
+func withRefresh(t runnableTool, refresher ReferenceRefresher) runnableTool {
+	if refresher == nil || !referenceTools[t.Name()] {
+		return t
+	}
+	return &refreshingTool{runnableTool: t, refresher: refresher}
+}
+
+// refreshingTool после успешного изменения сразу обновляет справочники,
+// чтобы следующие вызовы в том же ходе уже видели новый статус или поле.
+type refreshingTool struct {
+	runnableTool
+	refresher ReferenceRefresher
+}
+
+// ProcessRequest регистрирует в LLM request обёртку, а не исходный инструмент.
+func (t *refreshingTool) ProcessRequest(_ tool.Context, req *model.LLMRequest) error {
+	return packToolDeclaration(req, t)
+}
+
+// Run implements the ADK runnableTool interface.
+func (t *refreshingTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	result, err := t.runnableTool.Run(ctx, args)
+	if err != nil {
+		return result, err
+	}
+	raw, _ := args.(map[string]any)
+	action, _ := raw["action"].(string)
+	if schema, _ := result["schema"].(bool); schema || action == "" || readActions[action] {
+		return result, nil
+	}
+	t.refresher.Refresh(ctx)
+	return result, nil
+}
@@ -28,6 +28,7 @@ type CRMToolset struct {
 }
 
 // NewCRMToolset creates a toolset with all 12 CRM tools.
+// references may be nil, then account reference data is not reloaded after admin changes.
 func NewCRMToolset(
 	entitiesSvc entities.Service,
 	activitiesSvc activities.Service,
@@ -41,23 +42,34 @@ func NewCRMToolset(
 	adminPipelinesSvc admin_pipelines.Service,
 	adminUsersSvc admin_users.Service,
 	adminIntegrationsSvc admin_integrations.Service,
+	references ReferenceRefresher,
 ) *CRMToolset {
-	return &CRMToolset{
-		tools: []tool.Tool{
-			NewEntitiesTool(entitiesSvc),
-			NewActivitiesTool(activitiesSvc),
-			NewComplexCreateTool(complexCreateSvc),
-			NewProductsTool(productsSvc),
-			NewCatalogsTool(catalogsSvc),
-			NewFilesTool(filesSvc),
-			NewUnsortedTool(unsortedSvc),
-			NewCustomersTool(customersSvc),
-			NewAdminSchemaTool(adminSchemaSvc),
-			NewAdminPipelinesTool(adminPipelinesSvc),
-			NewAdminUsersTool(adminUsersSvc),
-			NewAdminIntegrationsTool(adminIntegrationsSvc),
-		},
+	runnable := []runnableTool{
+		NewEntitiesTool(entitiesSvc),
+		NewActivitiesTool(activitiesSvc),
+		NewComplexCreateTool(complexCreateSvc),
+		NewProductsTool(productsSvc),
+		NewCatalogsTool(catalogsSvc),
+		NewFilesTool(filesSvc),
+		NewUnsortedTool(unsortedSvc),
+		NewCustomersTool(customersSvc),
+		NewAdminSchemaTool(adminSchemaSvc),
+		NewAdminPipelinesTool(adminPipelinesSvc),
+		NewAdminUsersTool(adminUsersSvc),
+		NewAdminIntegrationsTool(adminIntegrationsSvc),
 	}
+
+	// Права роли проверяются первыми: запрещённое действие не доходит до подтверждения.
+	// Удаления и массовые изменения выполняются только после подтверждения пользователя.
+	// После изменения настроек аккаунта справочники перезагружаются.
+	// Для роли sales изменения проверяются по ответственному затронутых записей.
+	owners := crmOwners{entities: entitiesSvc, activities: activitiesSvc}
+	tools := make([]tool.Tool, 0, len(runnable))
+	for _, t := range runnable {
+		targets, _ := t.(writeTargeter)
+		tools = append(tools, withPermissions(withConfirmation(withRefresh(t, references)), targets, owners))
+	}
+	return &CRMToolset{tools: tools}
 }
 
 // Name implements tool.Toolset.
@@ -66,8 +78,9 @@ func (ts *CRMToolset) Name() string {
 }
 
 // Tools implements tool.Toolset.
-func (ts *CRMToolset) Tools(_ agent.ReadonlyContext) ([]tool.Tool, error) {
-	return ts.tools, nil
+// Инструменты, скрытые для роли пользователя (см. rolePolicies), модели не передаются.
+func (ts *CRMToolset) Tools(ctx agent.ReadonlyContext) ([]tool.Tool, error) {
+	return filterTools(ctx, ts.tools), nil
 }
 
 // declaringTool is a tool that provides a FunctionDeclaration (duck typing match for toolinternal.FunctionTool).
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -458,3 +459,15 @@ func toResultMap(v any) (map[string]any, error) {
 	}
 	return result, nil
 }
+
+// writeTargets реализует writeTargeter: существующая сделка, к которой привязывается заявка.
+func (t *UnsortedTool) writeTargets(_ context.Context, raw map[string]any) ([]record, error) {
+	input, err := decodeArgs[gkitmodels.UnsortedInput](raw)
+	if err != nil {
+		return nil, fmt.Errorf("unsorted: unmarshal input: %w", err)
+	}
+	if input.Action == "link" && input.LinkData != nil && input.LinkData.LeadID != 0 {
+		return []record{{entityType: "leads", id: input.LinkData.LeadID}}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,62 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
+	tgsvc "github.com/tihn/amo-ai-tgbot-go/internal/services/telegram"
+)
+
+// chatConfirmer implements agent.Confirmer: it asks the user in the chat
+// with Confirm/Cancel buttons and waits for the answer from HandleCallback.
+type chatConfirmer struct {
+	h              *Handler
+	b              *bot.Bot
+	chat           tgsvc.Chat
+	telegramUserID int64
+}
+
+// Confirm implements agent.Confirmer.
+func (c *chatConfirmer) Confirm(ctx context.Context, conf agent.Confirmation) (bool, error) {
+	id, text, keyboard := c.h.svc.NewConfirmation(c.telegramUserID, conf)
+	msg, err := c.b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          c.chat.ID,
+		MessageThreadID: c.chat.ThreadID,
+		Text:            text,
+		ParseMode:       models.ParseModeHTML,
+		ReplyMarkup:     keyboard,
+	})
+	if err != nil {
+		c.h.svc.DiscardConfirmation(id)
+		return false, fmt.Errorf("send confirmation: %w", err)
+	}
+
+	c.h.debugLog("⚠️ Waiting for confirmation %s: %s", id, conf.Summary)
+	approved, err := c.h.svc.WaitConfirmation(ctx, id)
+	if errors.Is(err, agent.ErrConfirmationTimeout) {
+		c.h.editMessage(ctx, c.b, c.chat, msg.ID, tgsvc.ConfirmationExpiredText, nil)
+	}
+	return approved, err
+}
+
+// handleConfirmation processes Confirm/Cancel buttons of a pending action.
+func (h *Handler) handleConfirmation(ctx context.Context, b *bot.Bot, query *models.CallbackQuery, id string, approved bool) {
+	text, alert := h.svc.ResolveConfirmation(query.From.ID, id, approved)
+
+	_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: query.ID,
+		Text:            alert,
+		ShowAlert:       alert != "",
+	})
+	if alert != "" {
+		return
+	}
+
+	msg := query.Message.Message
+	h.editMessage(ctx, b, chatOf(msg), msg.ID, text, nil)
+}
@@ -0,0 +1,130 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	tgsvc "github.com/tihn/amo-ai-tgbot-go/internal/services/telegram"
+)
+
+// chatOf returns the chat and forum topic of a message; replies go to the same topic.
+func chatOf(msg *models.Message) tgsvc.Chat {
+	chat := tgsvc.Chat{ID: msg.Chat.ID}
+	// Outside forum topics message_thread_id marks reply threads, which cannot be posted to
+	if msg.IsTopicMessage {
+		chat.ThreadID = msg.MessageThreadID
+	}
+	return chat
+}
+
+// isGroup reports whether the message comes from a group or supergroup.
+func isGroup(msg *models.Message) bool {
+	return msg.Chat.Type == models.ChatTypeGroup || msg.Chat.Type == models.ChatTypeSupergroup
+}
+
+// botUser returns the bot's own account, requested once with getMe.
+func (h *Handler) botUser(ctx context.Context, b *bot.Bot) (*models.User, error) {
+	h.meMu.Lock()
+	defer h.meMu.Unlock()
+	if h.me != nil {
+		return h.me, nil
+	}
+	me, err := b.GetMe(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get bot user: %w", err)
+	}
+	h.me = me
+	return me, nil
+}
+
+// addressedText returns the text meant for the bot with "@bot" removed.
+// In private chats every message is for the bot; in groups only commands,
+// messages mentioning the bot and replies to its messages are, ok is false otherwise.
+func (h *Handler) addressedText(ctx context.Context, b *bot.Bot, msg *models.Message) (text string, ok bool) {
+	content := msg.Text
+	if voiceOf(msg) != nil || uploadOf(msg) != nil {
+		// Voice messages, photos and documents are addressed by their caption
+		content = msg.Caption
+	}
+
+	if !isGroup(msg) {
+		// "/cmd@bot" also works in private chats
+		if strings.HasPrefix(content, "/") {
+			if me, err := h.botUser(ctx, b); err == nil {
+				return normalizeCommand(content, me.Username)
+			}
+		}
+		return content, true
+	}
+
+	me, err := h.botUser(ctx, b)
+	if err != nil {
+		h.debugLog("⚠️ %v", err)
+		return "", false
+	}
+	if strings.HasPrefix(content, "/") {
+		return normalizeCommand(content, me.Username)
+	}
+	if text, found := stripMention(content, me.Username); found {
+		return text, true
+	}
+	// In forum topics every message "replies" to the topic creation message — that is not a reply to the bot
+	if reply := msg.ReplyToMessage; reply != nil && reply.ForumTopicCreated == nil && reply.From != nil && reply.From.ID == me.ID {
+		return content, true
+	}
+	return "", false
+}
+
+// normalizeCommand removes the "@bot" suffix from a command ("/new@my_bot" → "/new").
+// ok is false when the command is addressed to another bot.
+func normalizeCommand(text, botUsername string) (string, bool) {
+	command, args, hasArgs := strings.Cut(text, " ")
+	command, target, addressed := strings.Cut(command, "@")
+	if addressed && !strings.EqualFold(target, botUsername) {
+		return "", false
+	}
+	if hasArgs {
+		return command + " " + args, true
+	}
+	return command, true
+}
+
+// stripMention removes "@bot" mentions from the text (case-insensitive, whole username only).
+// found reports whether there was at least one mention.
+func stripMention(text, botUsername string) (result string, found bool) {
+	if botUsername == "" {
+		return text, false
+	}
+
+	var sb strings.Builder
+	last := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] != '@' {
+			continue
+		}
+		end := i + 1 + len(botUsername)
+		if end > len(text) || !strings.EqualFold(text[i+1:end], botUsername) {
+			continue
+		}
+		if end < len(text) && isUsernameChar(text[end]) {
+			// "@bot_helper" is a different user
+			continue
+		}
+		sb.WriteString(text[last:i])
+		last, found = end, true
+		i = end - 1
+	}
+	if !found {
+		return text, false
+	}
+	sb.WriteString(text[last:])
+	return strings.TrimSpace(sb.String()), true
+}
+
+func isUsernameChar(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
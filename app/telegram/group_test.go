@@ -0,0 +1,53 @@
+package telegram
+
+import "testing"
+
+func TestNormalizeCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		ok       bool
+	}{
+		{name: "plain command", input: "/new", expected: "/new", ok: true},
+		{name: "addressed to the bot", input: "/new@AmoBot", expected: "/new", ok: true},
+		{name: "case-insensitive username", input: "/history@amobot", expected: "/history", ok: true},
+		{name: "arguments kept", input: "/allow@AmoBot 123 a@b.ru", expected: "/allow 123 a@b.ru", ok: true},
+		{name: "email in arguments is not a target", input: "/allow 123 a@b.ru", expected: "/allow 123 a@b.ru", ok: true},
+		{name: "another bot", input: "/new@OtherBot", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := normalizeCommand(tt.input, "AmoBot")
+			if ok != tt.ok || got != tt.expected {
+				t.Errorf("normalizeCommand(%q) = %q, %v; want %q, %v", tt.input, got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestStripMention(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		found    bool
+	}{
+		{name: "mention at start", input: "@AmoBot покажи мои сделки", expected: "покажи мои сделки", found: true},
+		{name: "mention in the middle", input: "Привет, @amobot, что по сделке 123?", expected: "Привет, , что по сделке 123?", found: true},
+		{name: "multiline kept", input: "@AmoBot задачи:\nна сегодня", expected: "задачи:\nна сегодня", found: true},
+		{name: "longer username", input: "@AmoBot_helper привет", expected: "@AmoBot_helper привет", found: false},
+		{name: "no mention", input: "просто сообщение", expected: "просто сообщение", found: false},
+		{name: "only mention", input: "@AmoBot", expected: "", found: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := stripMention(tt.input, "AmoBot")
+			if found != tt.found || got != tt.expected {
+				t.Errorf("stripMention(%q) = %q, %v; want %q, %v", tt.input, got, found, tt.expected, tt.found)
+			}
+		})
+	}
+}
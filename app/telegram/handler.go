@@ -2,9 +2,9 @@ package telegram
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"strings"
+	"sync"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
@@ -16,6 +16,9 @@ import (
 type Handler struct {
 	svc   *tgsvc.Service
 	debug bool
+
+	meMu sync.Mutex
+	me   *models.User // the bot itself, for mentions in groups
 }
 
 // NewHandler creates a new Handler with Telegram service
@@ -34,26 +37,49 @@ func (h *Handler) debugLog(format string, v ...any) {
 
 // HandleMessage handles incoming text messages
 func (h *Handler) HandleMessage(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.Message == nil {
+	if update.Message == nil || update.Message.From == nil {
 		return
 	}
 
-	text := update.Message.Text
-	chatID := update.Message.Chat.ID
+	chat := chatOf(update.Message)
 	telegramUserID := update.Message.From.ID
+	group := isGroup(update.Message)
+
+	h.debugLog("📨 Received message: %q from chat %d (topic %d)", update.Message.Text, chat.ID, chat.ThreadID)
 
-	h.debugLog("📨 Received message: %q from chat %d", text, chatID)
+	// In groups the bot answers only when addressed
+	text, ok := h.addressedText(ctx, b, update.Message)
+	if !ok {
+		return
+	}
+
+	if msg, ok := h.svc.CheckAccess(telegramUserID); !ok {
+		h.debugLog("🔒 User %d is not in the allowlist", telegramUserID)
+		h.sendResponse(ctx, b, chat, msg, nil)
+		return
+	}
+
+	if file := voiceOf(update.Message); file != nil {
+		h.handleVoice(ctx, b, chat, telegramUserID, update.Message, file)
+		return
+	}
+	if file := uploadOf(update.Message); file != nil {
+		h.handleUpload(ctx, b, chat, telegramUserID, text, file)
+		return
+	}
 
 	var response string
 	var keyboard *models.InlineKeyboardMarkup
-	var err error
 
 	// Handle commands
 	switch {
 	case text == "/start":
 		response, keyboard = h.svc.HandleStart(telegramUserID)
+	case group && (text == "/connect" || strings.HasPrefix(text, "/auth ")):
+		// Login links and codes are personal
+		response = "🔐 Подключить аккаунт можно только в личном чате с ботом."
 	case text == "/connect":
-		response, keyboard = h.svc.HandleConnect(telegramUserID, chatID)
+		response, keyboard = h.svc.HandleConnect(telegramUserID, chat.ID)
 	case strings.HasPrefix(text, "/auth "):
 		code := strings.TrimSpace(strings.TrimPrefix(text, "/auth "))
 		response, keyboard = h.svc.HandleAuthCode(ctx, telegramUserID, code)
@@ -67,26 +93,52 @@ func (h *Handler) HandleMessage(ctx context.Context, b *bot.Bot, update *models.
 		response = h.svc.HandleAccount(ctx)
 	case text == "/pipelines":
 		response = h.svc.HandlePipelines(ctx)
+	case text == "/reload":
+		response = h.svc.HandleReload(ctx, telegramUserID)
+	case text == "/new":
+		response, keyboard = h.svc.HandleNewSession(ctx, telegramUserID, chat)
+	case text == "/reset":
+		response, keyboard = h.svc.HandleResetSession(ctx, telegramUserID, chat)
+	case text == "/history":
+		response, keyboard = h.svc.HandleHistory(ctx, telegramUserID, chat)
+	case text == "/cancel":
+		response = h.svc.HandleCancel(telegramUserID, chat)
+	case text == "/bind":
+		response = h.svc.HandleBind(ctx, telegramUserID)
+	case text == "/access":
+		response = h.svc.HandleAccessList(telegramUserID)
+	case text == "/allow" || strings.HasPrefix(text, "/allow "):
+		response = h.svc.HandleAllow(ctx, telegramUserID, strings.TrimPrefix(text, "/allow"))
+	case text == "/deny" || strings.HasPrefix(text, "/deny "):
+		response = h.svc.HandleDeny(telegramUserID, strings.TrimPrefix(text, "/deny"))
+	case text == "/role" || strings.HasPrefix(text, "/role "):
+		response = h.svc.HandleSetRole(telegramUserID, strings.TrimPrefix(text, "/role"))
+	case tgsvc.IsCardCommand(text):
+		response, keyboard = h.svc.HandleCardCommand(ctx, telegramUserID, chat, text)
 	case text != "" && text[0] == '/':
+		if group {
+			// Commands without "@bot" may be meant for another bot in the group
+			return
+		}
 		response = "❓ Неизвестная команда. Используй /start для списка команд."
+	case h.svc.HasCardInput(telegramUserID, chat):
+		h.showCardResult(ctx, b, chat, h.svc.HandleCardInput(ctx, telegramUserID, chat, text))
+		return
 	default:
 		// Check if user is waiting for auth code
-		if h.svc.IsWaitingCode(telegramUserID) {
+		if !group && h.svc.IsWaitingCode(telegramUserID) {
 			h.debugLog("🔐 User is waiting for auth code, processing as code...")
 			response, keyboard = h.svc.HandleAuthCode(ctx, telegramUserID, strings.TrimSpace(text))
+		} else if msg, kb, ok := h.svc.CheckAIAccess(telegramUserID); !ok {
+			response, keyboard = msg, kb
 		} else {
 			h.debugLog("🤖 Processing with AI...")
-			response, err = h.svc.ProcessAI(ctx, telegramUserID, chatID, text)
-			if err != nil {
-				log.Printf("AI error: %v", err)
-				response = fmt.Sprintf("❌ Ошибка AI: %v", err)
-			} else {
-				h.debugLog("🤖 AI response received")
-			}
+			h.processAIStream(ctx, b, chat, telegramUserID, text)
+			return
 		}
 	}
 
-	h.sendResponse(ctx, b, chatID, response, keyboard)
+	h.sendResponse(ctx, b, chat, response, keyboard)
 }
 
 // HandleCallback handles inline button callbacks
@@ -95,27 +147,87 @@ func (h *Handler) HandleCallback(ctx context.Context, b *bot.Bot, update *models
 		return
 	}
 
-	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	chat := chatOf(update.CallbackQuery.Message.Message)
 	messageID := update.CallbackQuery.Message.Message.ID
 	telegramUserID := update.CallbackQuery.From.ID
 	data := update.CallbackQuery.Data
 
 	h.debugLog("📨 Received callback: %q from user %d", data, telegramUserID)
 
+	if msg, ok := h.svc.CheckAccess(telegramUserID); !ok {
+		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			Text:            "🔒 Нет доступа",
+			ShowAlert:       true,
+		})
+		h.sendResponse(ctx, b, chat, msg, nil)
+		return
+	}
+
 	var response string
 	var keyboard *models.InlineKeyboardMarkup
 
-	switch data {
-	case "auth_start":
-		response, keyboard = h.svc.ShowAuthWaiting(telegramUserID, chatID)
-	case "auth_panel":
+	switch {
+	case strings.HasPrefix(data, tgsvc.CallbackConfirmYes):
+		h.handleConfirmation(ctx, b, update.CallbackQuery, strings.TrimPrefix(data, tgsvc.CallbackConfirmYes), true)
+		return
+	case strings.HasPrefix(data, tgsvc.CallbackConfirmNo):
+		h.handleConfirmation(ctx, b, update.CallbackQuery, strings.TrimPrefix(data, tgsvc.CallbackConfirmNo), false)
+		return
+	case strings.HasPrefix(data, tgsvc.CallbackTurnStop):
+		// The message is updated by processAIStream once the run stops
+		alert := h.svc.StopTurn(telegramUserID, strings.TrimPrefix(data, tgsvc.CallbackTurnStop))
+		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			Text:            alert,
+			ShowAlert:       alert != "",
+		})
+		return
+	case strings.HasPrefix(data, tgsvc.CallbackPage):
+		text, keyboard, alert := h.svc.HandlePageCallback(ctx, telegramUserID, chat, strings.TrimPrefix(data, tgsvc.CallbackPage))
+		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			Text:            alert,
+			ShowAlert:       alert != "",
+		})
+		if alert == "" {
+			h.editMessage(ctx, b, chat, messageID, text, keyboard)
+		}
+		return
+	case strings.HasPrefix(data, tgsvc.CallbackCard):
+		result := h.svc.HandleCardCallback(ctx, telegramUserID, chat, messageID, data)
+		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			Text:            result.Alert,
+			ShowAlert:       result.Alert != "",
+		})
+		h.showCardResult(ctx, b, chat, result)
+		return
+	case strings.HasPrefix(data, "auth_") && isGroup(update.CallbackQuery.Message.Message):
+		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			Text:            "🔐 Подключить аккаунт можно только в личном чате с ботом.",
+			ShowAlert:       true,
+		})
+		return
+	case data == "auth_start":
+		response, keyboard = h.svc.ShowAuthWaiting(telegramUserID, chat.ID)
+	case data == "auth_panel":
 		response, keyboard = h.svc.ShowAuthPanel(telegramUserID)
-	case "auth_cancel":
+	case data == "auth_cancel":
 		response, keyboard = h.svc.CancelAuth(telegramUserID)
-	case "auth_disconnect":
+	case data == "auth_disconnect":
 		response, keyboard = h.svc.Disconnect(telegramUserID)
-	case "back_main":
+	case data == "back_main":
 		response, keyboard = h.svc.HandleStart(telegramUserID)
+	case data == tgsvc.CallbackSessionList:
+		response, keyboard = h.svc.HandleHistory(ctx, telegramUserID, chat)
+	case data == tgsvc.CallbackSessionNew:
+		response, keyboard = h.svc.HandleNewSession(ctx, telegramUserID, chat)
+	case strings.HasPrefix(data, tgsvc.CallbackSessionOpen):
+		response, keyboard = h.svc.SwitchSession(ctx, telegramUserID, chat, strings.TrimPrefix(data, tgsvc.CallbackSessionOpen))
+	case strings.HasPrefix(data, tgsvc.CallbackSessionDelete):
+		response, keyboard = h.svc.DeleteSession(ctx, telegramUserID, chat, strings.TrimPrefix(data, tgsvc.CallbackSessionDelete))
 	default:
 		response = "❓ Неизвестное действие."
 	}
@@ -126,41 +238,72 @@ func (h *Handler) HandleCallback(ctx context.Context, b *bot.Bot, update *models
 	})
 
 	// Edit the existing message instead of sending a new one
-	h.editMessage(ctx, b, chatID, messageID, response, keyboard)
+	h.editMessage(ctx, b, chat, messageID, response, keyboard)
 }
 
-func (h *Handler) sendResponse(ctx context.Context, b *bot.Bot, chatID int64, text string, keyboard *models.InlineKeyboardMarkup) {
+// showCardResult updates the card in place and sends the reply of a card action, if any.
+func (h *Handler) showCardResult(ctx context.Context, b *bot.Bot, chat tgsvc.Chat, result tgsvc.CardResult) {
+	if result.Text != "" {
+		h.editMessage(ctx, b, chat, result.MessageID, result.Text, result.Keyboard)
+	}
+	if result.Reply != "" {
+		h.sendResponse(ctx, b, chat, result.Reply, nil)
+	}
+}
+
+func (h *Handler) sendResponse(ctx context.Context, b *bot.Bot, chat tgsvc.Chat, text string, keyboard *models.InlineKeyboardMarkup) {
 	h.debugLog("📤 Sending response (%d chars)...", len(text))
+	h.sendChunks(ctx, b, chat, SplitTelegramHTML(text, TelegramMessageLimit), keyboard, 0)
+}
 
-	params := &bot.SendMessageParams{
-		ChatID:    chatID,
-		Text:      text,
-		ParseMode: models.ParseModeHTML,
-	}
+// sendReply sends the response as a reply to the message.
+func (h *Handler) sendReply(ctx context.Context, b *bot.Bot, chat tgsvc.Chat, replyTo int, text string) {
+	h.sendChunks(ctx, b, chat, SplitTelegramHTML(text, TelegramMessageLimit), nil, replyTo)
+}
 
-	if keyboard != nil {
-		params.ReplyMarkup = keyboard
-	}
+// sendChunks sends message parts in order; the keyboard is attached to the last one only
+// and the first one replies to the replyTo message, if set.
+func (h *Handler) sendChunks(ctx context.Context, b *bot.Bot, chat tgsvc.Chat, chunks []string, keyboard *models.InlineKeyboardMarkup, replyTo int) {
+	for i, chunk := range chunks {
+		params := &bot.SendMessageParams{
+			ChatID:          chat.ID,
+			MessageThreadID: chat.ThreadID,
+			Text:            chunk,
+			ParseMode:       models.ParseModeHTML,
+		}
 
-	_, err := b.SendMessage(ctx, params)
-	if err != nil {
-		log.Printf("❌ SendMessage error: %v", err)
-	} else {
-		h.debugLog("✅ Response sent")
+		if replyTo != 0 && i == 0 {
+			params.ReplyParameters = &models.ReplyParameters{MessageID: replyTo, AllowSendingWithoutReply: true}
+		}
+
+		if keyboard != nil && i == len(chunks)-1 {
+			params.ReplyMarkup = keyboard
+		}
+
+		_, err := b.SendMessage(ctx, params)
+		if err != nil {
+			log.Printf("❌ SendMessage error (part %d/%d): %v", i+1, len(chunks), err)
+			return
+		}
 	}
+	h.debugLog("✅ Response sent (%d parts)", len(chunks))
 }
 
-func (h *Handler) editMessage(ctx context.Context, b *bot.Bot, chatID int64, messageID int, text string, keyboard *models.InlineKeyboardMarkup) {
+// editMessage replaces the message text. If the text exceeds Telegram's limit,
+// the first part goes into the edited message and the rest are sent as new messages.
+func (h *Handler) editMessage(ctx context.Context, b *bot.Bot, chat tgsvc.Chat, messageID int, text string, keyboard *models.InlineKeyboardMarkup) {
 	h.debugLog("📝 Editing message %d...", messageID)
 
+	chunks := SplitTelegramHTML(text, TelegramMessageLimit)
+
 	params := &bot.EditMessageTextParams{
-		ChatID:    chatID,
+		ChatID:    chat.ID,
 		MessageID: messageID,
-		Text:      text,
+		Text:      chunks[0],
 		ParseMode: models.ParseModeHTML,
 	}
 
-	if keyboard != nil {
+	if keyboard != nil && len(chunks) == 1 {
 		params.ReplyMarkup = keyboard
 	}
 
@@ -168,8 +311,28 @@ func (h *Handler) editMessage(ctx context.Context, b *bot.Bot, chatID int64, mes
 	if err != nil {
 		log.Printf("❌ EditMessageText error: %v", err)
 		// Fallback to sending new message
-		h.sendResponse(ctx, b, chatID, text, keyboard)
-	} else {
-		h.debugLog("✅ Message edited")
+		h.sendChunks(ctx, b, chat, chunks, keyboard, 0)
+		return
+	}
+	h.debugLog("✅ Message edited")
+
+	if len(chunks) > 1 {
+		h.sendChunks(ctx, b, chat, chunks[1:], keyboard, 0)
+	}
+}
+
+// HandleInlineQuery answers inline queries ("@bot Альфа") with amoCRM cards.
+func (h *Handler) HandleInlineQuery(ctx context.Context, b *bot.Bot, update *models.Update) {
+	query := update.InlineQuery
+	h.debugLog("🔎 Inline query %q from user %d", query.Query, query.From.ID)
+
+	results := h.svc.HandleInlineQuery(ctx, query.From.ID, query.Query)
+	if _, err := b.AnswerInlineQuery(ctx, &bot.AnswerInlineQueryParams{
+		InlineQueryID: query.ID,
+		Results:       results,
+		CacheTime:     tgsvc.InlineCacheTime,
+		IsPersonal:    true,
+	}); err != nil {
+		log.Printf("❌ AnswerInlineQuery error: %v", err)
 	}
 }
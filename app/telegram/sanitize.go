@@ -2,17 +2,18 @@ package telegram
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
 )
 
-// Telegram Bot API supported HTML tags.
+// Telegram Bot API supported HTML tags (canonical names, see tagAliases).
 // See: https://core.telegram.org/bots/api#html-style
 var allowedTags = map[string]bool{
-	"b": true, "strong": true,
-	"i": true, "em": true,
-	"u": true, "ins": true,
-	"s": true, "strike": true, "del": true,
-	"span": true, // class="tg-spoiler"
+	"b":          true,
+	"i":          true,
+	"u":          true,
+	"s":          true,
+	"span":       true, // class="tg-spoiler"
 	"tg-spoiler": true,
 	"a":          true,
 	"tg-emoji":   true,
@@ -21,24 +22,484 @@ var allowedTags = map[string]bool{
 	"blockquote": true,
 }
 
-// tagRegex matches HTML opening, closing, and self-closing tags.
-var tagRegex = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9-]*)\b([^>]*)(/?)>`)
+// tagAliases maps supported synonyms and headings to canonical Telegram tags.
+var tagAliases = map[string]string{
+	"strong": "b", "em": "i", "ins": "u", "strike": "s", "del": "s",
+	"h1": "b", "h2": "b", "h3": "b", "h4": "b", "h5": "b", "h6": "b",
+}
+
+// droppedTags are common HTML tags the model emits that Telegram does not support.
+// The tag itself is removed, its content is kept. Any other "<...>" is treated as text.
+var droppedTags = map[string]bool{
+	"br": true, "hr": true, "p": true, "div": true, "ul": true, "ol": true, "li": true,
+	"table": true, "thead": true, "tbody": true, "tr": true, "td": true, "th": true,
+	"img": true, "small": true, "big": true, "sup": true, "sub": true, "mark": true,
+	"font": true, "center": true, "section": true, "article": true, "header": true,
+	"footer": true, "nav": true, "html": true, "head": true, "body": true, "title": true,
+}
+
+// voidTags never have a closing tag.
+var voidTags = map[string]bool{"br": true, "hr": true, "img": true}
+
+// maxTagDepth limits nesting of formatting tags; deeper tags are dropped.
+const maxTagDepth = 8
+
+var (
+	// fenceRegex matches Markdown code fences: ```lang\ncode```
+	fenceRegex = regexp.MustCompile("```([a-zA-Z0-9_+#.-]*)\n?([\\s\\S]*?)```")
+	// inlineMarkdownRegex matches `code` and **bold** inside a text node.
+	inlineMarkdownRegex = regexp.MustCompile("`([^`\n]+)`|\\*\\*([^*\n]+?)\\*\\*")
+	// headingRegex matches Markdown headings: "## Title"
+	headingRegex = regexp.MustCompile(`(?m)^#{1,6}[ \t]+(.+)$`)
+	// codeLanguageRegex validates class="language-..." on <code>.
+	codeLanguageRegex = regexp.MustCompile(`^language-[a-zA-Z0-9_+#.-]+$`)
+	// entityRegex matches HTML entities supported by Telegram.
+	entityRegex = regexp.MustCompile(`^&(?:lt|gt|amp|quot|#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6});`)
+)
 
-// SanitizeTelegramHTML strips HTML tags not supported by Telegram Bot API
-// and escapes unmatched < > to prevent parse errors.
+// SanitizeTelegramHTML converts model output into HTML that Telegram Bot API accepts:
+// escapes stray < > & in text, keeps only supported tags with whitelisted attributes,
+// balances and re-nests tags, and converts Markdown (**bold**, `code`, ```fences```, # headings).
 func SanitizeTelegramHTML(s string) string {
-	result := tagRegex.ReplaceAllStringFunc(s, func(match string) string {
-		sub := tagRegex.FindStringSubmatch(match)
-		if sub == nil {
-			return match
+	s = strings.ToValidUTF8(s, "�")
+	s = strings.ReplaceAll(s, "\x00", "")
+	s = convertCodeFences(s)
+
+	san := &sanitizer{}
+	for _, tok := range tokenizeHTML(s) {
+		if tok.tag == "" {
+			san.text(tok.raw)
+			continue
+		}
+		san.tag(tok)
+	}
+	san.closeAll()
+
+	return san.out.String()
+}
+
+// htmlAttr is a single tag attribute.
+type htmlAttr struct {
+	name  string
+	value string
+}
+
+// htmlToken is a tag or a text run.
+type htmlToken struct {
+	raw         string
+	tag         string // lowercase tag name, empty for text
+	closing     bool
+	selfClosing bool
+	attrs       []htmlAttr
+}
+
+// tokenizeHTML splits s into tags and text runs. Only known tag names
+// (see allowedTags, tagAliases, droppedTags) are recognized as tags,
+// so "ООО <Alpha>" stays text.
+func tokenizeHTML(s string) []htmlToken {
+	var tokens []htmlToken
+	textStart := 0
+
+	for i := 0; i < len(s); {
+		if s[i] != '<' {
+			i++
+			continue
+		}
+		tok, n, ok := parseTag(s[i:])
+		if !ok {
+			i++
+			continue
+		}
+		if textStart < i {
+			tokens = append(tokens, htmlToken{raw: s[textStart:i]})
+		}
+		tokens = append(tokens, tok)
+		i += n
+		textStart = i
+	}
+	if textStart < len(s) {
+		tokens = append(tokens, htmlToken{raw: s[textStart:]})
+	}
+	return tokens
+}
+
+// parseTag parses a tag at the start of s. Returns the token and its length.
+func parseTag(s string) (htmlToken, int, bool) {
+	tok := htmlToken{}
+	i := 1
+	if i < len(s) && s[i] == '/' {
+		tok.closing = true
+		i++
+	}
+
+	nameStart := i
+	for i < len(s) && (isASCIILetter(s[i]) || (i > nameStart && (isASCIIDigit(s[i]) || s[i] == '-'))) {
+		i++
+	}
+	if i == nameStart {
+		return tok, 0, false
+	}
+	name := strings.ToLower(s[nameStart:i])
+	if !allowedTags[name] && tagAliases[name] == "" && !droppedTags[name] {
+		return tok, 0, false
+	}
+	tok.tag = name
+
+	for {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			return tok, 0, false
+		}
+		switch {
+		case s[i] == '>':
+			tok.raw = s[:i+1]
+			return tok, i + 1, true
+		case s[i] == '/' && i+1 < len(s) && s[i+1] == '>':
+			tok.selfClosing = true
+			tok.raw = s[:i+2]
+			return tok, i + 2, true
+		case i == nameStart+len(name) && !tok.closing:
+			// Attributes must be separated from the name by whitespace
+			return tok, 0, false
+		}
+
+		attrStart := i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		if i == attrStart {
+			// Stray "/" inside the tag
+			i++
+			continue
 		}
-		tagName := strings.ToLower(sub[2])
-		if allowedTags[tagName] {
-			return match // keep allowed tags as-is
+		attr := htmlAttr{name: strings.ToLower(s[attrStart:i])}
+
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isSpace(s[i]) {
+				i++
+			}
+			if i >= len(s) {
+				return tok, 0, false
+			}
+			if q := s[i]; q == '"' || q == '\'' {
+				end := strings.IndexByte(s[i+1:], q)
+				if end < 0 {
+					return tok, 0, false
+				}
+				attr.value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				valStart := i
+				for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				attr.value = s[valStart:i]
+			}
 		}
-		// Strip disallowed tag, keep inner content
-		return ""
+		tok.attrs = append(tok.attrs, attr)
+	}
+}
+
+// stackEntry is an open element. Entries with emitted=false were dropped,
+// but are tracked so that their closing tags match correctly.
+type stackEntry struct {
+	name    string
+	open    string // rendered opening tag
+	emitted bool
+}
+
+type sanitizer struct {
+	out     strings.Builder
+	stack   []stackEntry
+	emitted map[string]int // number of open emitted elements by name
+	depth   int            // total number of open emitted elements
+}
+
+// push adds an element to the stack, writing its opening tag if it is emitted.
+func (s *sanitizer) push(e stackEntry) {
+	s.stack = append(s.stack, e)
+	if !e.emitted {
+		return
+	}
+	if s.emitted == nil {
+		s.emitted = make(map[string]int)
+	}
+	s.emitted[e.name]++
+	s.depth++
+	s.out.WriteString(e.open)
+}
+
+// pop removes the top element, writing its closing tag if it was emitted.
+func (s *sanitizer) pop() stackEntry {
+	e := s.stack[len(s.stack)-1]
+	s.stack = s.stack[:len(s.stack)-1]
+	if e.emitted {
+		s.emitted[e.name]--
+		s.depth--
+		s.out.WriteString("</" + e.name + ">")
+	}
+	return e
+}
+
+// inCode reports whether the current position is inside <pre> or <code>.
+func (s *sanitizer) inCode() bool {
+	return s.has("pre") || s.has("code")
+}
+
+// has reports whether an emitted element with the given name is open.
+func (s *sanitizer) has(name string) bool {
+	return s.emitted[name] > 0
+}
+
+// text writes a text node, converting Markdown outside of code blocks.
+func (s *sanitizer) text(raw string) {
+	if s.inCode() {
+		s.out.WriteString(escapeText(raw))
+		return
+	}
+
+	raw = headingRegex.ReplaceAllString(raw, "**$1**")
+
+	last := 0
+	for _, m := range inlineMarkdownRegex.FindAllStringSubmatchIndex(raw, -1) {
+		s.out.WriteString(escapeText(raw[last:m[0]]))
+		switch {
+		case m[2] >= 0:
+			s.out.WriteString("<code>" + escapeText(raw[m[2]:m[3]]) + "</code>")
+		case s.has("b"):
+			s.out.WriteString(escapeText(raw[m[4]:m[5]]))
+		default:
+			s.out.WriteString("<b>" + escapeText(raw[m[4]:m[5]]) + "</b>")
+		}
+		last = m[1]
+	}
+	s.out.WriteString(escapeText(raw[last:]))
+}
+
+// tag processes an opening or closing tag.
+func (s *sanitizer) tag(tok htmlToken) {
+	name := tok.tag
+	if alias, ok := tagAliases[name]; ok {
+		name = alias
+	}
+
+	// Inside pre/code everything except closing pre/code and <code> right after <pre> is literal text
+	if s.inCode() {
+		top := s.stack[len(s.stack)-1]
+		switch {
+		case tok.closing && (name == "pre" || name == "code") && s.has(name):
+		case !tok.closing && name == "code" && top.emitted && top.name == "pre":
+		default:
+			s.out.WriteString(escapeText(tok.raw))
+			return
+		}
+	}
+
+	if !allowedTags[name] {
+		s.dropTag(name, tok)
+		return
+	}
+
+	if tok.closing {
+		s.close(name)
+		return
+	}
+	if tok.selfClosing {
+		return
+	}
+
+	open, ok := s.renderOpen(name, tok.attrs)
+	if !ok || s.depth >= maxTagDepth {
+		s.push(stackEntry{name: name})
+		return
+	}
+	s.push(stackEntry{name: name, open: open, emitted: true})
+}
+
+// dropTag removes an unsupported tag, keeping line structure for block tags.
+func (s *sanitizer) dropTag(name string, tok htmlToken) {
+	switch {
+	case name == "br" || name == "hr":
+		s.out.WriteString("\n")
+	case name == "li" && !tok.closing:
+		s.out.WriteString("• ")
+	case tok.closing && (name == "p" || name == "div" || name == "li" || name == "tr"):
+		s.out.WriteString("\n")
+	}
+
+	if voidTags[name] || tok.selfClosing {
+		return
+	}
+	if tok.closing {
+		s.close(name)
+		return
+	}
+	s.push(stackEntry{name: name})
+}
+
+// renderOpen renders a supported opening tag with whitelisted attributes.
+// Returns false if the tag must be dropped in the current context.
+func (s *sanitizer) renderOpen(name string, attrs []htmlAttr) (string, bool) {
+	attr := func(key string) (string, bool) {
+		for _, a := range attrs {
+			if a.name == key {
+				return a.value, true
+			}
+		}
+		return "", false
+	}
+
+	switch name {
+	case "a":
+		href, _ := attr("href")
+		if s.has("a") || !isAllowedURL(href) {
+			return "", false
+		}
+		return `<a href="` + escapeAttr(href) + `">`, true
+	case "span":
+		if class, _ := attr("class"); class != "tg-spoiler" {
+			return "", false
+		}
+		return `<span class="tg-spoiler">`, true
+	case "tg-emoji":
+		id, _ := attr("emoji-id")
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			return "", false
+		}
+		return `<tg-emoji emoji-id="` + id + `">`, true
+	case "code":
+		if class, _ := attr("class"); codeLanguageRegex.MatchString(class) {
+			return `<code class="` + class + `">`, true
+		}
+		return "<code>", true
+	case "blockquote":
+		if s.has("blockquote") {
+			return "", false
+		}
+		if _, ok := attr("expandable"); ok {
+			return "<blockquote expandable>", true
+		}
+		return "<blockquote>", true
+	default:
+		return "<" + name + ">", true
+	}
+}
+
+// close closes the element with the given name. Elements opened inside it
+// are closed first and reopened afterwards, fixing improper nesting like <b><i></b></i>.
+func (s *sanitizer) close(name string) {
+	idx := -1
+	for i := len(s.stack) - 1; i >= 0; i-- {
+		if s.stack[i].name == name {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return
+	}
+
+	reopen := make([]stackEntry, 0, len(s.stack)-idx-1)
+	for len(s.stack) > idx+1 {
+		reopen = append(reopen, s.pop())
+	}
+	s.pop()
+
+	for i := len(reopen) - 1; i >= 0; i-- {
+		// pre/code are not reopened: the code block ends with its parent
+		if e := reopen[i]; !(e.emitted && (e.name == "pre" || e.name == "code")) {
+			s.push(e)
+		}
+	}
+}
+
+// closeAll closes every element left open at the end of the input.
+func (s *sanitizer) closeAll() {
+	for len(s.stack) > 0 {
+		s.pop()
+	}
+}
+
+// convertCodeFences turns ```lang\ncode``` into <pre><code class="language-lang">.
+func convertCodeFences(s string) string {
+	return fenceRegex.ReplaceAllStringFunc(s, func(match string) string {
+		sub := fenceRegex.FindStringSubmatch(match)
+		code := escapeText(strings.TrimRight(sub[2], "\n"))
+		if sub[1] == "" {
+			return "<pre>" + code + "</pre>"
+		}
+		return `<pre><code class="language-` + sub[1] + `">` + code + "</code></pre>"
 	})
+}
 
-	return result
+// escapeText escapes < > and & (except valid entities) for Telegram HTML.
+func escapeText(s string) string {
+	if !strings.ContainsAny(s, "<>&") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '<':
+			sb.WriteString("&lt;")
+		case '>':
+			sb.WriteString("&gt;")
+		case '&':
+			if n := validEntityLen(s[i:]); n > 0 {
+				sb.WriteString(s[i : i+n])
+				i += n - 1
+			} else {
+				sb.WriteString("&amp;")
+			}
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+// escapeAttr escapes an attribute value for use inside double quotes.
+func escapeAttr(s string) string {
+	return strings.ReplaceAll(escapeText(s), `"`, "&quot;")
 }
+
+// validEntityLen returns the length of a Telegram-supported entity at the start of s, or 0.
+func validEntityLen(s string) int {
+	m := entityRegex.FindString(s)
+	if m == "" {
+		return 0
+	}
+	if m[1] == '#' {
+		num := m[2 : len(m)-1]
+		base := 10
+		if num[0] == 'x' || num[0] == 'X' {
+			num, base = num[1:], 16
+		}
+		cp, err := strconv.ParseUint(num, base, 32)
+		if err != nil || cp == 0 || cp > 0x10FFFF || (cp >= 0xD800 && cp <= 0xDFFF) {
+			return 0
+		}
+	}
+	return len(m)
+}
+
+// isAllowedURL reports whether href uses a scheme Telegram accepts in links.
+func isAllowedURL(href string) bool {
+	h := strings.ToLower(strings.TrimSpace(href))
+	for _, prefix := range []string{"http://", "https://", "tg://", "mailto:"} {
+		if strings.HasPrefix(h, prefix) && len(h) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isASCIILetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isASCIIDigit(c byte) bool  { return c >= '0' && c <= '9' }
+func isSpace(c byte) bool       { return c == ' ' || c == '\t' || c == '\n' || c == '\r' }
@@ -0,0 +1,226 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeTelegramHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "plain text",
+			input:    "Привет",
+			expected: "Привет",
+		},
+		{
+			name:     "stray brackets and ampersand",
+			input:    "ООО <Альфа> & Co",
+			expected: "ООО &lt;Альфа&gt; &amp; Co",
+		},
+		{
+			name:     "unknown tag-like text",
+			input:    "ООО <Alpha> Ltd",
+			expected: "ООО &lt;Alpha&gt; Ltd",
+		},
+		{
+			name:     "valid entities kept",
+			input:    "a &lt; b &amp;&amp; &#128512; &nbsp;",
+			expected: "a &lt; b &amp;&amp; &#128512; &amp;nbsp;",
+		},
+		{
+			name:     "aliases",
+			input:    "<strong>x</strong> <em>y</em> <del>z</del>",
+			expected: "<b>x</b> <i>y</i> <s>z</s>",
+		},
+		{
+			name:     "unclosed tags",
+			input:    "<b>Сделка <i>Альфа",
+			expected: "<b>Сделка <i>Альфа</i></b>",
+		},
+		{
+			name:     "misnested tags",
+			input:    "<b>a<i>b</b>c</i>",
+			expected: "<b>a<i>b</i></b><i>c</i>",
+		},
+		{
+			name:     "stray closing tag",
+			input:    "a</b>b",
+			expected: "ab",
+		},
+		{
+			name:     "attributes whitelisted",
+			input:    `<b class="x">a</b> <a href="https://example.amocrm.ru/leads/detail/1" target="_blank">link</a>`,
+			expected: `<b>a</b> <a href="https://example.amocrm.ru/leads/detail/1">link</a>`,
+		},
+		{
+			name:     "javascript link dropped",
+			input:    `<a href="javascript:alert(1)">x</a>`,
+			expected: "x",
+		},
+		{
+			name:     "spoiler span",
+			input:    `<span class="tg-spoiler">s</span><span style="color:red">t</span>`,
+			expected: `<span class="tg-spoiler">s</span>t`,
+		},
+		{
+			name:     "code language",
+			input:    `<pre><code class="language-json" data-x="1">{"a": 1}</code></pre>`,
+			expected: `<pre><code class="language-json">{"a": 1}</code></pre>`,
+		},
+		{
+			name:     "tags inside pre are text",
+			input:    "<pre><b>x</b> & y</pre>",
+			expected: "<pre>&lt;b&gt;x&lt;/b&gt; &amp; y</pre>",
+		},
+		{
+			name:     "unsupported block tags",
+			input:    "<p>Первый</p><ul><li>один</li><li>два</li></ul>a<br>b",
+			expected: "Первый\n• один\n• два\na\nb",
+		},
+		{
+			name:     "markdown bold and code",
+			input:    "**Сделка** ID `123`",
+			expected: "<b>Сделка</b> ID <code>123</code>",
+		},
+		{
+			name:     "markdown heading",
+			input:    "## Итоги\nтекст",
+			expected: "<b>Итоги</b>\nтекст",
+		},
+		{
+			name:     "markdown fence",
+			input:    "```json\n{\"a\": \"<b>\"}\n```",
+			expected: `<pre><code class="language-json">{"a": "&lt;b&gt;"}</code></pre>`,
+		},
+		{
+			name:     "nested blockquote dropped",
+			input:    "<blockquote>a<blockquote>b</blockquote>c</blockquote>",
+			expected: "<blockquote>abc</blockquote>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SanitizeTelegramHTML(tt.input)
+			if got != tt.expected {
+				t.Errorf("SanitizeTelegramHTML(%q)\n got: %q\nwant: %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func FuzzSanitizeTelegramHTML(f *testing.F) {
+	seeds := []string{
+		"ООО <Альфа> & Co",
+		"<b>a<i>b</b>c</i>",
+		`<a href="https://x.ru?a=1&b=2">x</a>`,
+		`<pre><code class="language-go">if a < b {}</code></pre>`,
+		"**bold** `code` ```go\nx := 1\n```",
+		"<blockquote expandable><b>x</blockquote>",
+		"&#0; &#xD800; &amp &lt",
+		"<span class=tg-spoiler>x</span><tg-emoji emoji-id=\"5368324170671202286\">👍</tg-emoji>",
+		"<b/><br/><a>\xff</a>",
+	}
+	for _, s := range seeds {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		out := SanitizeTelegramHTML(input)
+		if err := validateTelegramHTML(out); err != nil {
+			t.Fatalf("invalid output for %q:\n%q\n%v", input, out, err)
+		}
+		for _, chunk := range SplitTelegramHTML(out, 64) {
+			if err := validateTelegramHTML(chunk); err != nil {
+				t.Fatalf("invalid chunk for %q:\n%q\n%v", input, chunk, err)
+			}
+		}
+	})
+}
+
+// validateTelegramHTML checks output against Telegram Bot API HTML rules:
+// only supported tags and attributes, proper nesting, no raw < > and only supported entities.
+func validateTelegramHTML(s string) error {
+	if !utf8.ValidString(s) {
+		return fmt.Errorf("invalid UTF-8")
+	}
+
+	var stack []string
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '>':
+			return fmt.Errorf("raw '>' at %d", i)
+		case '&':
+			if validEntityLen(s[i:]) == 0 {
+				return fmt.Errorf("bad entity at %d", i)
+			}
+		case '<':
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 {
+				return fmt.Errorf("unterminated tag at %d", i)
+			}
+			tag := s[i+1 : i+end]
+			i += end
+
+			if strings.HasPrefix(tag, "/") {
+				name := tag[1:]
+				if len(stack) == 0 || stack[len(stack)-1] != name {
+					return fmt.Errorf("unexpected </%s>, open: %v", name, stack)
+				}
+				stack = stack[:len(stack)-1]
+				continue
+			}
+
+			name, attrs, _ := strings.Cut(tag, " ")
+			if !allowedTags[name] {
+				return fmt.Errorf("unsupported tag <%s>", tag)
+			}
+			if err := validateAttrs(name, attrs); err != nil {
+				return err
+			}
+			for _, open := range stack {
+				if (open == "pre" && !(name == "code" && stack[len(stack)-1] == "pre")) || open == "code" {
+					return fmt.Errorf("<%s> inside <%s>", name, open)
+				}
+				if open == name && (name == "a" || name == "blockquote") {
+					return fmt.Errorf("nested <%s>", name)
+				}
+			}
+			stack = append(stack, name)
+		}
+	}
+	if len(stack) > 0 {
+		return fmt.Errorf("unclosed tags: %v", stack)
+	}
+	return nil
+}
+
+func validateAttrs(name, attrs string) error {
+	switch {
+	case attrs == "":
+		if name == "a" || name == "tg-emoji" || name == "span" {
+			return fmt.Errorf("<%s> without required attribute", name)
+		}
+		return nil
+	case name == "a" && strings.HasPrefix(attrs, `href="`) && strings.HasSuffix(attrs, `"`):
+		if strings.Contains(attrs[6:len(attrs)-1], `"`) {
+			return fmt.Errorf("unescaped quote in href")
+		}
+		return nil
+	case name == "span" && attrs == `class="tg-spoiler"`:
+		return nil
+	case name == "code" && codeLanguageRegex.MatchString(strings.TrimSuffix(strings.TrimPrefix(attrs, `class="`), `"`)):
+		return nil
+	case name == "blockquote" && attrs == "expandable":
+		return nil
+	case name == "tg-emoji" && strings.HasPrefix(attrs, `emoji-id="`):
+		return nil
+	}
+	return fmt.Errorf("unexpected attributes <%s %s>", name, attrs)
+}
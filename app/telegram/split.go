@@ -0,0 +1,302 @@
+package telegram
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// TelegramMessageLimit is the maximum length of a Telegram message text (in UTF-16 code units).
+const TelegramMessageLimit = 4096
+
+// Break priorities for cut points, higher is better.
+const (
+	breakNone = iota
+	breakSpace
+	breakLine
+	breakParagraph
+)
+
+// htmlUnit is an indivisible piece of Telegram HTML: a tag, a word or a whitespace run.
+type htmlUnit struct {
+	raw     string
+	tag     string // lowercase tag name, empty for text
+	closing bool
+	hidden  bool // opening tag too long for any chunk, dropped with its closing tag
+	brk     int  // break priority after this unit
+}
+
+// openTag is an element that is open at some point of the document.
+type openTag struct {
+	name   string
+	raw    string // original opening tag, used to reopen it in the next chunk
+	hidden bool   // not reopened: the tag is too long to repeat, e.g. <a> with a huge URL
+}
+
+// SplitTelegramHTML splits Telegram HTML into chunks of at most limit UTF-16 code units.
+// Cuts are made on paragraph, line or word boundaries when possible; tags open at the cut
+// are closed at the end of the chunk and reopened at the start of the next one.
+func SplitTelegramHTML(s string, limit int) []string {
+	if textLen(s) <= limit {
+		return []string{s}
+	}
+
+	units := splitHTMLUnits(s, limit/4)
+	for i, u := range units {
+		if u.tag != "" && !u.closing && textLen(u.raw)+len(u.tag)+3 > limit/2 {
+			units[i].hidden = true
+		}
+	}
+
+	var chunks []string
+	var stack []openTag
+	start := 0
+
+	for start < len(units) {
+		// Reopened tags count toward the limit; keep them to half a chunk
+		stack = fitReopen(stack, limit/2)
+		prefix := reopenTags(stack)
+		size := textLen(prefix)
+
+		type candidate struct {
+			end   int // exclusive unit index
+			stack []openTag
+			size  int
+			brk   int
+		}
+		var candidates []candidate
+
+		// Stacks returned by applyTag are never mutated, so candidates can share them
+		cur := stack
+		end := start
+		for end < len(units) {
+			u := units[end]
+			next := applyTag(cur, u)
+			if size+unitLen(u)+closeTagsLen(next) > limit && end > start {
+				break
+			}
+			size += unitLen(u)
+			cur = next
+			end++
+			if u.brk != breakNone {
+				candidates = append(candidates, candidate{end: end, stack: cur, size: size, brk: u.brk})
+			}
+		}
+
+		cutEnd, cutStack := end, cur
+		if end < len(units) && len(candidates) > 0 {
+			// Prefer the best break in the second half of the chunk, otherwise the latest one.
+			best := -1
+			for i, c := range candidates {
+				if c.size < limit/2 {
+					continue
+				}
+				if best < 0 || c.brk >= candidates[best].brk {
+					best = i
+				}
+			}
+			if best < 0 {
+				best = len(candidates) - 1
+			}
+			cutEnd, cutStack = candidates[best].end, candidates[best].stack
+		}
+
+		var sb strings.Builder
+		sb.WriteString(prefix)
+		open := stack
+		for _, u := range units[start:cutEnd] {
+			next := applyTag(open, u)
+			// A hidden element is written without its tags
+			if !u.hidden && !(u.closing && len(next) < len(open) && open[len(next)].hidden) {
+				sb.WriteString(u.raw)
+			}
+			open = next
+		}
+		chunk := strings.TrimRight(sb.String(), " \t\n")
+		chunk += closeTags(cutStack)
+		if strings.TrimSpace(chunk) != "" {
+			chunks = append(chunks, chunk)
+		}
+
+		start, stack = cutEnd, cutStack
+		// Skip leading whitespace of the next chunk
+		for start < len(units) && units[start].tag == "" && strings.TrimSpace(units[start].raw) == "" {
+			start++
+		}
+	}
+
+	if len(chunks) == 0 {
+		return []string{strings.TrimSpace(s)}
+	}
+	return chunks
+}
+
+// splitHTMLUnits tokenizes HTML into tags, words and whitespace runs.
+// Words longer than maxWord are hard-split without breaking HTML entities.
+func splitHTMLUnits(s string, maxWord int) []htmlUnit {
+	var units []htmlUnit
+	for _, tok := range tokenizeHTML(s) {
+		switch {
+		case tok.tag == "":
+			units = append(units, splitTextUnits(tok.raw, maxWord)...)
+		case tok.selfClosing || voidTags[tok.tag]:
+			units = append(units, htmlUnit{raw: tok.raw})
+		default:
+			units = append(units, htmlUnit{raw: tok.raw, tag: tok.tag, closing: tok.closing})
+		}
+	}
+	return units
+}
+
+// splitTextUnits splits plain text into words and whitespace runs.
+func splitTextUnits(text string, maxWord int) []htmlUnit {
+	var units []htmlUnit
+	for len(text) > 0 {
+		isSpace := strings.IndexAny(text[:1], " \t\n") == 0
+		n := 0
+		for n < len(text) && (strings.IndexAny(text[n:n+1], " \t\n") == 0) == isSpace {
+			n++
+		}
+		piece := text[:n]
+		text = text[n:]
+
+		if isSpace {
+			brk := breakSpace
+			if c := strings.Count(piece, "\n"); c >= 2 {
+				brk = breakParagraph
+			} else if c == 1 {
+				brk = breakLine
+			}
+			units = append(units, htmlUnit{raw: piece, brk: brk})
+			continue
+		}
+
+		for maxWord > 0 && textLen(piece) > maxWord {
+			cut := hardCut(piece, maxWord)
+			units = append(units, htmlUnit{raw: piece[:cut], brk: breakSpace})
+			piece = piece[cut:]
+		}
+		units = append(units, htmlUnit{raw: piece})
+	}
+	return units
+}
+
+// hardCut returns a byte offset to cut a long word at, not exceeding limit
+// UTF-16 units and not splitting a rune or an HTML entity.
+func hardCut(word string, limit int) int {
+	cut, size := 0, 0
+	for i, r := range word {
+		l := textLen(string(r))
+		if size+l > limit {
+			break
+		}
+		size += l
+		cut = i + utf8.RuneLen(r)
+	}
+	if cut == 0 {
+		_, cut = utf8.DecodeRuneInString(word)
+	}
+	// Do not cut inside an entity: move the cut before it, or past it if the word starts with it
+	if amp := strings.LastIndexByte(word[:cut], '&'); amp >= 0 {
+		if n := validEntityLen(word[amp:]); amp+n > cut {
+			if amp > 0 {
+				cut = amp
+			} else {
+				cut = n
+			}
+		}
+	}
+	return cut
+}
+
+// applyTag returns the open-tag stack after unit u.
+func applyTag(stack []openTag, u htmlUnit) []openTag {
+	if u.tag == "" {
+		return stack
+	}
+	if !u.closing {
+		return append(stack[:len(stack):len(stack)], openTag{name: u.tag, raw: u.raw, hidden: u.hidden})
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].name == u.tag {
+			return stack[:i:i]
+		}
+	}
+	return stack
+}
+
+// fitReopen hides the longest opening tags until reopening and closing the stack
+// takes at most budget units. The stack is copied before it is changed.
+func fitReopen(stack []openTag, budget int) []openTag {
+	copied := false
+	for textLen(reopenTags(stack))+closeTagsLen(stack) > budget {
+		longest := -1
+		for i, t := range stack {
+			if !t.hidden && (longest < 0 || len(t.raw) > len(stack[longest].raw)) {
+				longest = i
+			}
+		}
+		if longest < 0 {
+			break
+		}
+		if !copied {
+			stack = append([]openTag(nil), stack...)
+			copied = true
+		}
+		stack[longest].hidden = true
+	}
+	return stack
+}
+
+// reopenTags renders opening tags for the stack (outermost first).
+func reopenTags(stack []openTag) string {
+	var sb strings.Builder
+	for _, t := range stack {
+		if !t.hidden {
+			sb.WriteString(t.raw)
+		}
+	}
+	return sb.String()
+}
+
+// closeTags renders closing tags for the stack (innermost first).
+func closeTags(stack []openTag) string {
+	var sb strings.Builder
+	for i := len(stack) - 1; i >= 0; i-- {
+		if !stack[i].hidden {
+			sb.WriteString("</" + stack[i].name + ">")
+		}
+	}
+	return sb.String()
+}
+
+// closeTagsLen returns the length of closeTags(stack) without building it.
+func closeTagsLen(stack []openTag) int {
+	n := 0
+	for _, t := range stack {
+		if !t.hidden {
+			n += len(t.name) + 3
+		}
+	}
+	return n
+}
+
+// unitLen returns the length of u in a chunk: hidden tags are not written.
+func unitLen(u htmlUnit) int {
+	if u.hidden {
+		return 0
+	}
+	return textLen(u.raw)
+}
+
+// textLen returns the string length in UTF-16 code units, as Telegram counts it.
+func textLen(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
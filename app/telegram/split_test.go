@@ -0,0 +1,135 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitTelegramHTMLLongLinkPrefix(t *testing.T) {
+	const limit = 100
+	words := strings.Repeat("слово ", 60)
+	inputs := map[string]string{
+		"tag longer than half a chunk": `<b><a href="https://example.amocrm.ru/leads/detail/1?` + strings.Repeat("x", 80) + `">` +
+			words + "</a></b> конец",
+		"reopened stack too long": `<a href="https://ex.ru/leads/1234"><b><i><u><s>` + words + "</s></u></i></b></a> конец",
+	}
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			chunks := SplitTelegramHTML(input, limit)
+			if len(chunks) < 2 {
+				t.Fatalf("got %d chunks, want several", len(chunks))
+			}
+			for i, c := range chunks {
+				if n := textLen(c); n > limit {
+					t.Errorf("chunk %d has %d units, limit %d: %q", i, n, limit, c)
+				}
+				if SanitizeTelegramHTML(c) != c {
+					t.Errorf("chunk %d is not balanced HTML: %q", i, c)
+				}
+			}
+			if !strings.HasSuffix(chunks[len(chunks)-1], " конец") {
+				t.Errorf("last chunk = %q", chunks[len(chunks)-1])
+			}
+		})
+	}
+}
+
+func TestSplitTelegramHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		limit int
+		want  []string
+	}{
+		{
+			name:  "fits exactly",
+			input: "aaaaaaaaa bbbbbbbbb ccccccccc dddddddd",
+			limit: 38,
+			want:  []string{"aaaaaaaaa bbbbbbbbb ccccccccc dddddddd"},
+		},
+		{
+			name:  "one over the limit",
+			input: "aaaaaaaaa bbbbbbbbb ccccccccc ddddddddd",
+			limit: 38,
+			want:  []string{"aaaaaaaaa bbbbbbbbb ccccccccc", "ddddddddd"},
+		},
+		{
+			name:  "utf-16 length",
+			input: "😀😀 😀😀 😀😀 😀😀 😀😀",
+			limit: 20,
+			want:  []string{"😀😀 😀😀 😀😀 😀😀", "😀😀"},
+		},
+		{
+			name:  "paragraph preferred",
+			input: "first line\nsecond one\n\nthird one two three",
+			limit: 40,
+			want:  []string{"first line\nsecond one", "third one two three"},
+		},
+		{
+			name:  "tags reopened",
+			input: "<b>aaaaaaaaa bbbbbbbbb ccccccccc ddddddddd</b>",
+			limit: 40,
+			want:  []string{"<b>aaaaaaaaa bbbbbbbbb ccccccccc</b>", "<b>ddddddddd</b>"},
+		},
+		{
+			name:  "nested tags reopened with attributes",
+			input: `<a href="u"><i>aaaaaaaaa bbbbbbbbb ccccccccc ddddddddd</i></a>`,
+			limit: 60,
+			want:  []string{`<a href="u"><i>aaaaaaaaa bbbbbbbbb ccccccccc</i></a>`, `<a href="u"><i>ddddddddd</i></a>`},
+		},
+		{
+			name:  "unclosed tags closed",
+			input: "<b>aaaaaaaaa bbbbbbbbb <i>ccccccccc ddddddddd",
+			limit: 40,
+			want:  []string{"<b>aaaaaaaaa bbbbbbbbb</b>", "<b><i>ccccccccc ddddddddd</i></b>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitTelegramHTML(tt.input, tt.limit)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("SplitTelegramHTML(%q, %d) = %q, want %q", tt.input, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHardCutKeepsEntities(t *testing.T) {
+	tests := []struct {
+		word  string
+		limit int
+		want  int
+	}{
+		{word: "aaaaaaaaaa", limit: 4, want: 4},
+		{word: "aaaaaaaa&amp;b", limit: 10, want: 8},
+		{word: "&amp;bbbb", limit: 3, want: 5},
+		{word: "&#128512;b", limit: 4, want: 9},
+		{word: "ёёё", limit: 2, want: 4},
+	}
+	for _, tt := range tests {
+		if got := hardCut(tt.word, tt.limit); got != tt.want {
+			t.Errorf("hardCut(%q, %d) = %d, want %d", tt.word, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestSplitTelegramHTMLLongURL(t *testing.T) {
+	url := "https://example.amocrm.ru/leads/detail/1?utm=" + strings.Repeat("x&amp;y", 2000)
+	chunks := SplitTelegramHTML("Ссылка: "+url, TelegramMessageLimit)
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want several", len(chunks))
+	}
+	for i, c := range chunks {
+		if n := textLen(c); n > TelegramMessageLimit {
+			t.Errorf("chunk %d has %d units", i, n)
+		}
+		if !strings.HasSuffix(c, "x") && !strings.HasSuffix(c, "y") {
+			t.Errorf("chunk %d cuts an entity: ...%q", i, c[len(c)-10:])
+		}
+	}
+	if got := strings.Join(chunks, ""); got != "Ссылка: "+url {
+		t.Errorf("chunks do not add up to the input")
+	}
+}
@@ -0,0 +1,190 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
+	tgsvc "github.com/tihn/amo-ai-tgbot-go/internal/services/telegram"
+)
+
+const (
+	// streamEditInterval limits how often the reply is edited (Telegram rate-limits edits).
+	streamEditInterval = 1500 * time.Millisecond
+	// typingInterval — "typing" chat action expires after ~5s, so it is resent periodically.
+	typingInterval = 4 * time.Second
+	// streamPreviewLimit keeps intermediate previews under Telegram's 4096-character limit.
+	streamPreviewLimit = 3500
+
+	streamPlaceholder = "⏳ <i>Думаю…</i>"
+	queuePlaceholder  = "🕒 <i>В очереди: %d. Отвечу, когда закончу с предыдущим запросом.</i>"
+	cancelledText     = "⏹ <i>Запрос остановлен.</i>"
+)
+
+// processAIStream sends a placeholder message and progressively edits it
+// while the agent is working, until the final sanitized answer lands.
+// Requests of the same conversation wait in a queue; the Stop button and /cancel interrupt them.
+func (h *Handler) processAIStream(ctx context.Context, b *bot.Bot, chat tgsvc.Chat, telegramUserID int64, text string) {
+	turn, position := h.svc.EnqueueTurn(ctx, telegramUserID, chat)
+	defer h.svc.FinishTurn(turn)
+	stop := turn.StopKeyboard()
+
+	placeholderText := streamPlaceholder
+	if position > 0 {
+		placeholderText = fmt.Sprintf(queuePlaceholder, position)
+	}
+	placeholder, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          chat.ID,
+		MessageThreadID: chat.ThreadID,
+		Text:            placeholderText,
+		ParseMode:       models.ParseModeHTML,
+		ReplyMarkup:     stop,
+	})
+	if err != nil {
+		log.Printf("❌ SendMessage (placeholder) error: %v", err)
+		return
+	}
+
+	if err := h.svc.WaitTurn(turn); err != nil {
+		h.editMessage(ctx, b, chat, placeholder.ID, cancelledText, nil)
+		return
+	}
+	if position > 0 {
+		h.editMessage(ctx, b, chat, placeholder.ID, streamPlaceholder, stop)
+	}
+
+	typingCtx, stopTyping := context.WithCancel(turn.Context())
+	defer stopTyping()
+	go h.keepTyping(typingCtx, b, chat)
+
+	// The run uses the turn's context, so that Stop cancels it; messages are still edited with ctx.
+	// Destructive tool actions ask this user in this chat before running
+	runCtx := agent.ContextWithConfirmer(turn.Context(), &chatConfirmer{h: h, b: b, chat: chat, telegramUserID: telegramUserID})
+
+	var answer, progress, shown string
+	var lastEdit time.Time
+	pages := h.svc.NewPageTracker()
+	records := h.svc.NewCardTracker()
+
+	for event, err := range h.svc.ProcessAIStream(runCtx, turn, text) {
+		if err != nil {
+			stopTyping()
+			if errors.Is(context.Cause(runCtx), tgsvc.ErrTurnCancelled) {
+				h.debugLog("⏹ Turn cancelled by user")
+				h.editMessage(ctx, b, chat, placeholder.ID, stoppedAnswer(answer), nil)
+				return
+			}
+			log.Printf("AI error: %v", err)
+			h.editMessage(ctx, b, chat, placeholder.ID, fmt.Sprintf("❌ Ошибка AI: %v", err), nil)
+			return
+		}
+
+		pages.Observe(event)
+		records.Observe(event)
+		switch event.Kind {
+		case agent.StreamEventToolCall:
+			progress = tgsvc.ToolProgressLabel(event.ToolName, event.ToolArgs)
+			h.debugLog("🔧 Tool call: %s %v", event.ToolName, event.ToolArgs)
+		case agent.StreamEventText:
+			answer = event.Text
+			progress = ""
+		case agent.StreamEventDone:
+			answer = event.Text
+		}
+
+		if event.Kind == agent.StreamEventDone || time.Since(lastEdit) < streamEditInterval {
+			continue
+		}
+
+		preview := renderStreamPreview(answer, progress)
+		if preview == "" || preview == shown {
+			continue
+		}
+		if _, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      chat.ID,
+			MessageID:   placeholder.ID,
+			Text:        preview,
+			ParseMode:   models.ParseModeHTML,
+			ReplyMarkup: stop,
+		}); err != nil {
+			// Intermediate previews may contain half-written tags — skip and wait for the next one
+			h.debugLog("⚠️ Preview edit skipped: %v", err)
+		} else {
+			shown = preview
+		}
+		lastEdit = time.Now()
+	}
+
+	stopTyping()
+	if errors.Is(context.Cause(runCtx), tgsvc.ErrTurnCancelled) {
+		// Stopped right as the run was ending
+		h.editMessage(ctx, b, chat, placeholder.ID, stoppedAnswer(answer), nil)
+		return
+	}
+	h.debugLog("🤖 AI response received")
+
+	final := SanitizeTelegramHTML(answer)
+	if final == "" {
+		final = "🤷 AI вернул пустой ответ."
+	}
+	// Long search results can be browsed with buttons, without asking the AI for more
+	h.editMessage(ctx, b, chat, placeholder.ID, final, h.svc.PageKeyboard(telegramUserID, chat, pages))
+
+	// A record the agent got, created or changed comes with its card and buttons
+	if card := h.svc.AgentCard(ctx, telegramUserID, chat, records); card.Text != "" {
+		h.sendResponse(ctx, b, chat, card.Text, card.Keyboard)
+	}
+}
+
+// stoppedAnswer is the final message of a cancelled turn: the partial answer, if any, with a note.
+func stoppedAnswer(answer string) string {
+	if partial := SanitizeTelegramHTML(answer); partial != "" {
+		return partial + "\n\n" + cancelledText
+	}
+	return cancelledText
+}
+
+// keepTyping sends the "typing" chat action until ctx is cancelled.
+func (h *Handler) keepTyping(ctx context.Context, b *bot.Bot, chat tgsvc.Chat) {
+	ticker := time.NewTicker(typingInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := b.SendChatAction(ctx, &bot.SendChatActionParams{
+			ChatID:          chat.ID,
+			MessageThreadID: chat.ThreadID,
+			Action:          models.ChatActionTyping,
+		}); err != nil && ctx.Err() == nil {
+			h.debugLog("⚠️ SendChatAction error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// renderStreamPreview builds an intermediate message from partial answer and current tool progress.
+func renderStreamPreview(answer, progress string) string {
+	if r := []rune(answer); len(r) > streamPreviewLimit {
+		answer = string(r[:streamPreviewLimit]) + "…"
+	}
+	preview := SanitizeTelegramHTML(answer)
+
+	switch {
+	case progress != "" && preview != "":
+		return preview + "\n\n<i>" + progress + "</i>"
+	case progress != "":
+		return "<i>" + progress + "</i>"
+	default:
+		return preview
+	}
+}
@@ -0,0 +1,62 @@
+package telegram
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
+	tgsvc "github.com/tihn/amo-ai-tgbot-go/internal/services/telegram"
+)
+
+// uploadFile is the photo or document of a message.
+type uploadFile struct {
+	id     string
+	upload tgsvc.Upload
+}
+
+// uploadOf returns the photo or document of a message, nil for other messages.
+func uploadOf(msg *models.Message) *uploadFile {
+	switch {
+	case msg.Document != nil:
+		return &uploadFile{id: msg.Document.FileID, upload: tgsvc.Upload{
+			FileName: msg.Document.FileName,
+			MIMEType: msg.Document.MimeType,
+			Size:     msg.Document.FileSize,
+		}}
+	case len(msg.Photo) > 0:
+		// Sizes go from the smallest to the original
+		photo := msg.Photo[len(msg.Photo)-1]
+		return &uploadFile{id: photo.FileID, upload: tgsvc.Upload{
+			FileName: "photo_" + time.Unix(int64(msg.Date), 0).Format("20060102_150405") + ".jpg",
+			MIMEType: "image/jpeg",
+			Size:     int64(photo.FileSize),
+		}}
+	}
+	return nil
+}
+
+// handleUpload uploads a photo or document to amoCRM and tells the agent about it.
+func (h *Handler) handleUpload(ctx context.Context, b *bot.Bot, chat tgsvc.Chat, telegramUserID int64, caption string, file *uploadFile) {
+	h.debugLog("📎 Uploading %q to amoCRM...", file.upload.FileName)
+	file.upload.Caption = caption
+	file.upload.Open = func(ctx context.Context) (io.ReadCloser, error) {
+		return downloadFile(ctx, b, file.id)
+	}
+	// A record found by the caption's name is attached only after the user confirms it
+	confirmCtx := agent.ContextWithConfirmer(ctx, &chatConfirmer{h: h, b: b, chat: chat, telegramUserID: telegramUserID})
+	result := h.svc.HandleUpload(confirmCtx, telegramUserID, chat, file.upload)
+	h.sendResponse(ctx, b, chat, result.Reply, nil)
+
+	if result.Prompt == "" {
+		return
+	}
+	// The upload works without AI; the agent only learns about the file when available
+	if _, _, ok := h.svc.CheckAIAccess(telegramUserID); !ok {
+		return
+	}
+	h.processAIStream(ctx, b, chat, telegramUserID, result.Prompt)
+}
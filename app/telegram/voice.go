@@ -0,0 +1,105 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	tgsvc "github.com/tihn/amo-ai-tgbot-go/internal/services/telegram"
+)
+
+// voiceFile is the voice note or audio file of a message.
+type voiceFile struct {
+	id    string
+	voice tgsvc.Voice
+}
+
+// voiceOf returns the voice note or audio file of a message, nil for other messages.
+func voiceOf(msg *models.Message) *voiceFile {
+	switch {
+	case msg.Voice != nil:
+		return &voiceFile{id: msg.Voice.FileID, voice: tgsvc.Voice{
+			FileName: "voice.ogg", // voice notes are always OGG/Opus
+			Duration: time.Duration(msg.Voice.Duration) * time.Second,
+			Size:     msg.Voice.FileSize,
+		}}
+	case msg.Audio != nil:
+		name := msg.Audio.FileName
+		if name == "" {
+			name = "audio.mp3"
+		}
+		return &voiceFile{id: msg.Audio.FileID, voice: tgsvc.Voice{
+			FileName: name,
+			Duration: time.Duration(msg.Audio.Duration) * time.Second,
+			Size:     msg.Audio.FileSize,
+		}}
+	}
+	return nil
+}
+
+// handleVoice transcribes a voice message, shows the transcript as a reply
+// and handles it like a typed message: card input or an AI request.
+func (h *Handler) handleVoice(ctx context.Context, b *bot.Bot, chat tgsvc.Chat, telegramUserID int64, msg *models.Message, file *voiceFile) {
+	cardInput := h.svc.HasCardInput(telegramUserID, chat)
+	if !cardInput {
+		// Do not spend time on transcription when the answer is "no AI"
+		if text, kb, ok := h.svc.CheckAIAccess(telegramUserID); !ok {
+			h.sendResponse(ctx, b, chat, text, kb)
+			return
+		}
+	}
+
+	h.debugLog("🎙 Transcribing voice message (%s)...", file.voice.Duration)
+	file.voice.Open = func(ctx context.Context) (io.ReadCloser, error) {
+		return downloadFile(ctx, b, file.id)
+	}
+	transcript, failure := h.svc.TranscribeVoice(ctx, file.voice)
+	if failure != "" {
+		h.sendResponse(ctx, b, chat, failure, nil)
+		return
+	}
+
+	// Echo the transcript so the user sees what the bot heard; a long one is split like any answer
+	h.sendReply(ctx, b, chat, msg.ID, "🎙 <i>"+html.EscapeString(transcript)+"</i>")
+
+	if cardInput {
+		h.showCardResult(ctx, b, chat, h.svc.HandleCardInput(ctx, telegramUserID, chat, transcript))
+		return
+	}
+	h.debugLog("🤖 Processing transcript with AI...")
+	h.processAIStream(ctx, b, chat, telegramUserID, transcript)
+}
+
+// downloadFile downloads a file sent to the bot.
+func downloadFile(ctx context.Context, b *bot.Bot, fileID string) (io.ReadCloser, error) {
+	file, err := b.GetFile(ctx, &bot.GetFileParams{FileID: fileID})
+	if err != nil {
+		return nil, fmt.Errorf("get file: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.FileDownloadLink(file), nil)
+	if err != nil {
+		return nil, fmt.Errorf("download file: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// The link contains the bot token, keep it out of the logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("download file: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download file: %s", resp.Status)
+	}
+	return resp.Body, nil
+}
@@ -6,8 +6,12 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
 	"github.com/joho/godotenv"
 
 	"google.golang.org/adk/agent"
@@ -20,7 +24,15 @@ import (
 	"github.com/tihn/amo-ai-tgbot-go/config"
 	"github.com/tihn/amo-ai-tgbot-go/internal/infrastructure/crm"
 	"github.com/tihn/amo-ai-tgbot-go/internal/infrastructure/llm"
+	"github.com/tihn/amo-ai-tgbot-go/internal/infrastructure/sessionstore"
+	"github.com/tihn/amo-ai-tgbot-go/internal/infrastructure/stt"
+	tgInfra "github.com/tihn/amo-ai-tgbot-go/internal/infrastructure/telegram"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/access"
+	accessdir "github.com/tihn/amo-ai-tgbot-go/internal/services/access/directory"
+	accountContext "github.com/tihn/amo-ai-tgbot-go/internal/services/account_context"
 	"github.com/tihn/amo-ai-tgbot-go/internal/services/auth"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/cards"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/cards/crmsource"
 	crmActivities "github.com/tihn/amo-ai-tgbot-go/internal/services/crm/activities"
 	crmAdminIntegrations "github.com/tihn/amo-ai-tgbot-go/internal/services/crm/admin_integrations"
 	crmAdminPipelines "github.com/tihn/amo-ai-tgbot-go/internal/services/crm/admin_pipelines"
@@ -33,7 +45,12 @@ import (
 	crmFiles "github.com/tihn/amo-ai-tgbot-go/internal/services/crm/files"
 	crmProducts "github.com/tihn/amo-ai-tgbot-go/internal/services/crm/products"
 	crmUnsorted "github.com/tihn/amo-ai-tgbot-go/internal/services/crm/unsorted"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/paging/crmpages"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/speech"
 	"github.com/tihn/amo-ai-tgbot-go/internal/services/telegram"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/uploads"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/uploads/crmdrive"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/usermodel"
 )
 
 func init() {
@@ -52,7 +69,7 @@ func main() {
 		log.Fatalf("Config error: %v", err)
 	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
 	// === Infrastructure ===
@@ -79,36 +96,53 @@ func main() {
 
 	// === Application ===
 
-	// LLM provider (Ollama via OpenAI-compatible API)
-	llmModel := llm.NewProvider(cfg)
+	// LLM provider selected by AI_PROVIDER, with AI_FALLBACK_PROVIDERS as failover chain
+	llmModel, err := llm.NewProvider(ctx, cfg)
+	if err != nil {
+		log.Fatalf("Failed to init LLM provider: %v", err)
+	}
+
+	// Per-user models: users with a connected Google account run AI on their own token
+	llmSettings, err := llm.ParseSettings(cfg, llm.ProviderGeminiCLI)
+	if err != nil {
+		log.Fatalf("Failed to parse LLM settings: %v", err)
+	}
+	userModels := usermodel.New(authService, usermodel.Config{
+		ModelName:   cfg.GeminiModel,
+		Project:     cfg.GoogleCloudProject,
+		Settings:    llmSettings,
+		RequireAuth: cfg.AIRequireUserAuth,
+	})
 
 	// === CRM Services ===
 	sdk := crmClient.SDK()
 
-	entitiesSvc, err := crmEntities.New(ctx, sdk)
+	// Account reference data (users, pipelines, statuses, fields) is loaded once and shared
+	accountCtx, err := accountContext.Load(ctx, sdk)
 	if err != nil {
-		log.Fatalf("Failed to init entities service: %v", err)
+		log.Fatalf("Failed to load account context: %v", err)
 	}
-	activitiesSvc, err := crmActivities.New(ctx, sdk)
-	if err != nil {
-		log.Fatalf("Failed to init activities service: %v", err)
+	if st := accountCtx.Stats(); len(st.Failed) > 0 {
+		log.Printf("⚠️ Account context loaded partially: %s; failed: %s", st, strings.Join(st.Failed, "; "))
+	} else {
+		log.Printf("Account context loaded: %s", st)
 	}
-	complexCreateSvc, err := crmComplexCreate.New(ctx, sdk)
+	resolver := accountCtx.Resolver()
+	accountCtxTTL, err := time.ParseDuration(cfg.AccountContextTTL)
 	if err != nil {
-		log.Fatalf("Failed to init complex_create service: %v", err)
+		log.Fatalf("Invalid ACCOUNT_CONTEXT_TTL %q: %v", cfg.AccountContextTTL, err)
 	}
+	go accountCtx.Run(ctx, accountCtxTTL)
+
+	entitiesSvc := crmEntities.New(sdk, resolver)
+	activitiesSvc := crmActivities.New(sdk, resolver)
+	complexCreateSvc := crmComplexCreate.New(sdk, resolver)
 	catalogsSvc, err := crmCatalogs.New(ctx, sdk)
 	if err != nil {
 		log.Fatalf("Failed to init catalogs service: %v", err)
 	}
-	unsortedSvc, err := crmUnsorted.New(ctx, sdk)
-	if err != nil {
-		log.Fatalf("Failed to init unsorted service: %v", err)
-	}
-	customersSvc, err := crmCustomers.New(ctx, sdk)
-	if err != nil {
-		log.Fatalf("Failed to init customers service: %v", err)
-	}
+	unsortedSvc := crmUnsorted.New(sdk, resolver)
+	customersSvc := crmCustomers.New(sdk, resolver)
 
 	productsSvc := crmProducts.NewService(sdk)
 	filesSvc := crmFiles.NewService(sdk)
@@ -122,10 +156,22 @@ func main() {
 		entitiesSvc, activitiesSvc, complexCreateSvc, productsSvc,
 		catalogsSvc, filesSvc, unsortedSvc, customersSvc,
 		adminSchemaSvc, adminPipelinesSvc, adminUsersSvc, adminIntegrationsSvc,
+		accountCtx,
 	)
 
+	// Session storage (conversation history survives restarts with SESSION_STORAGE=file)
+	sessionService, err := sessionstore.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to init session storage: %v", err)
+	}
+
 	// AI agent with CRM tools
-	aiAgent, err := appagent.NewAgent(ctx, llmModel, crmToolset)
+	historyPolicy := appagent.HistoryPolicy{
+		MaxTokens:   cfg.HistoryMaxTokens,
+		KeepTurns:   cfg.HistoryKeepTurns,
+		DigestChars: cfg.HistoryDigestChars,
+	}
+	aiAgent, err := appagent.NewAgent(ctx, llm.NewRouter(llmModel), sessionService, historyPolicy, crmToolset)
 	if err != nil {
 		log.Fatalf("Failed to init AI agent: %v", err)
 	}
@@ -147,8 +193,76 @@ func main() {
 
 	// === Telegram Bot ===
 
+	// Access control: only allowlisted Telegram users, bound to amoCRM users
+	accessAdmins, err := access.ParseIDs(cfg.AccessAdmins)
+	if err != nil {
+		log.Fatalf("Invalid ACCESS_ADMINS: %v", err)
+	}
+	accessAllowlist, err := access.ParseIDs(cfg.AccessAllowlist)
+	if err != nil {
+		log.Fatalf("Invalid ACCESS_ALLOWLIST: %v", err)
+	}
+	accessDefaultRole, err := access.ParseRole(cfg.AccessDefaultRole)
+	if err != nil {
+		log.Fatalf("Invalid ACCESS_DEFAULT_ROLE: %v", err)
+	}
+	if len(accessAdmins) == 0 {
+		log.Print("⚠️ ACCESS_ADMINS is empty: nobody can manage the allowlist with /allow")
+	}
+	accessSvc, err := access.New(access.Config{
+		Path:        cfg.AccessFile,
+		Admins:      accessAdmins,
+		Allowlist:   accessAllowlist,
+		DefaultRole: accessDefaultRole,
+	}, accessdir.NewAmo(adminUsersSvc))
+	if err != nil {
+		log.Fatalf("Failed to init access control: %v", err)
+	}
+
 	// Telegram service (business logic)
-	telegramSvc := telegram.NewService(aiAgent, crmClient, authService)
+	// Inline search and card buttons work with amoCRM directly, without the AI agent
+	cardsSvc := cards.New(
+		crmsource.New(entitiesSvc, cfg.AmoCRMBaseURL),
+		crmsource.NewEditor(entitiesSvc, activitiesSvc, cfg.AmoCRMBaseURL),
+	)
+
+	// Page buttons repeat the agent's last search with another page
+	pages := crmpages.New(entitiesSvc, catalogsSvc, filesSvc, productsSvc)
+
+	// Voice messages are transcribed when a speech-to-text endpoint is configured
+	var transcriber speech.Transcriber
+	if cfg.STTURL != "" {
+		sttClient, err := stt.New(stt.Config{
+			URL:      cfg.STTURL,
+			APIKey:   cfg.STTAPIKey,
+			Model:    cfg.STTModel,
+			Language: cfg.STTLanguage,
+		})
+		if err != nil {
+			log.Fatalf("Invalid STT_URL: %v", err)
+		}
+		transcriber = sttClient
+	} else {
+		log.Print("STT_URL is empty: voice messages are not recognized")
+	}
+
+	// Photos and documents sent to the bot go to amoCRM Drive
+	uploadsSvc := uploads.New(crmdrive.New(filesSvc, activitiesSvc))
+
+	var providers telegram.ProviderStats
+	if fb, ok := llmModel.(*llm.Fallback); ok {
+		providers = fb
+	}
+	telegramSvc := telegram.NewService(aiAgent, crmClient, authService, telegram.Options{
+		Models:     userModels,
+		Access:     accessSvc,
+		Cards:      cardsSvc,
+		Pages:      pages,
+		Speech:     transcriber,
+		Uploads:    uploadsSvc,
+		References: accountCtx,
+		Providers:  providers,
+	})
 
 	// Telegram handler
 	handler := tgHandler.NewHandler(telegramSvc, cfg.Debug)
@@ -158,6 +272,24 @@ func main() {
 		bot.WithSkipGetMe(),
 	}
 
+	// Updates: long polling or webhook (for running behind a load balancer / several replicas)
+	var webhook *tgInfra.Webhook
+	switch cfg.TelegramMode {
+	case "polling":
+	case "webhook":
+		webhook, err = tgInfra.NewWebhook(tgInfra.WebhookConfig{
+			URL:    cfg.WebhookURL,
+			Listen: cfg.WebhookListen,
+			Secret: cfg.WebhookSecret,
+		})
+		if err != nil {
+			log.Fatalf("Invalid webhook config: %v", err)
+		}
+		opts = append(opts, bot.WithMiddlewares(webhook.Middleware()))
+	default:
+		log.Fatalf("Invalid TELEGRAM_MODE %q: expected polling or webhook", cfg.TelegramMode)
+	}
+
 	b, err := bot.New(cfg.TelegramToken, opts...)
 	if err != nil {
 		log.Fatal(err)
@@ -165,7 +297,23 @@ func main() {
 
 	// Register callback handler for inline buttons
 	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "", bot.MatchTypePrefix, handler.HandleCallback)
+	// Inline mode must be enabled for the bot in @BotFather (/setinline)
+	b.RegisterHandlerMatchFunc(func(update *models.Update) bool {
+		return update.InlineQuery != nil
+	}, handler.HandleInlineQuery)
 
+	if webhook != nil {
+		log.Print("Bot started in webhook mode (AI agent: ADK Runner, Web UI: http://localhost:8080)")
+		if err := webhook.Run(ctx, b); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// getUpdates does not work while a webhook is set, e.g. after switching back from webhook mode
+	if _, err := b.DeleteWebhook(ctx, &bot.DeleteWebhookParams{}); err != nil {
+		log.Printf("⚠️ Failed to delete webhook: %v", err)
+	}
 	log.Print("Bot started (AI agent: ADK Runner, Web UI: http://localhost:8080)")
 	b.Start(ctx)
 }
@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 )
 
 // AuthMode определяет способ авторизации amoCRM
@@ -12,20 +14,94 @@ const (
 	AuthModeOAuth AuthMode = "oauth"
 )
 
+// SessionStorage определяет где хранятся ADK-сессии (история диалогов)
+type SessionStorage string
+
+const (
+	SessionStorageMemory SessionStorage = "memory"
+	SessionStorageFile   SessionStorage = "file"
+)
+
 // Config holds all application configuration
 type Config struct {
 	TelegramToken string
 	Debug         bool
 
+	// Получение обновлений Telegram: "polling" (long polling) или "webhook".
+	// В режиме webhook бот регистрирует WebhookURL через SetWebhook и слушает WebhookListen
+	// (отдельный порт, :8080 занят ADK Web UI); запросы без WebhookSecret отклоняются.
+	TelegramMode  string
+	WebhookURL    string
+	WebhookListen string
+	WebhookSecret string
+
 	// Ollama settings
 	OllamaURL   string
 	OllamaModel string
 
 	// AI Provider selection
-	AIProvider string // "ollama" or "gemini-cli"
+	AIProvider string // "ollama", "openai", "gemini" or "gemini-cli"
+
+	// Резервные провайдеры через запятую, например "openai,gemini".
+	// При таймауте, 429, 5xx или переполнении контекста запрос уходит следующему.
+	AIFallback string
+
+	// OpenAI-compatible API (OpenAI, OpenRouter, vLLM, ...)
+	OpenAIBaseURL string
+	OpenAIAPIKey  string
+	OpenAIModel   string
+
+	// Gemini settings: API key for "gemini", model is shared with "gemini-cli"
+	GeminiAPIKey string
+	GeminiModel  string
 
 	// Gemini CLI settings (Code Assist)
 	GeminiCLICredsPath string // Path to cached OAuth credentials
+	GoogleCloudProject string // Code Assist project (required for paid tiers)
+	NoBrowser          bool   // OAuth via manual code copy-paste instead of local redirect
+
+	// AI от имени пользователя: запросы идут через Code Assist с Google токеном пользователя,
+	// при AIRequireUserAuth без подключённого аккаунта AI недоступен
+	AIRequireUserAuth bool
+
+	// Параметры генерации для всех провайдеров. Хранятся как строки,
+	// разбираются и проверяются в llm.NewProvider при старте.
+	LLMTemperature string // пусто — значение по умолчанию у модели
+	LLMMaxTokens   string // пусто или 0 — без ограничения
+	LLMTimeout     string // time.Duration, таймаут одного запроса к модели
+
+	// Параметры генерации отдельных провайдеров (OPENAI_TEMPERATURE, GEMINI_CLI_TIMEOUT, ...),
+	// ключ — значение AI_PROVIDER. Пустое поле — берётся общее LLM_*.
+	LLMProviders map[string]LLMSettings
+
+	// Agent sessions
+	SessionStorage SessionStorage // "memory" or "file"
+	SessionDir     string         // Directory for file storage
+
+	// История диалога: бюджет в токенах (оценка), сколько последних ходов хранить целиком,
+	// до какого размера сжимать старые ответы инструментов
+	HistoryMaxTokens   int
+	HistoryKeepTurns   int
+	HistoryDigestChars int
+
+	// Доступ к боту: администраторы и статический allowlist (Telegram ID через запятую),
+	// файл с пользователями, добавленными командой /allow, и их привязками к amoCRM,
+	// роль по умолчанию для пользователей без явно назначенной роли (/role)
+	AccessAdmins      string
+	AccessAllowlist   string
+	AccessFile        string
+	AccessDefaultRole string
+
+	// Распознавание голосовых сообщений: OpenAI-совместимый /audio/transcriptions
+	// (OpenAI, whisper.cpp server, faster-whisper-server). Пустой STTURL — голосовые не распознаются
+	STTURL      string
+	STTAPIKey   string
+	STTModel    string
+	STTLanguage string // подсказка языка ISO-639-1, пусто — автоопределение
+
+	// Как часто перезагружать справочники аккаунта (воронки, статусы, пользователи, поля),
+	// time.Duration; "0" — только после изменений через admin-инструменты и по /reload
+	AccountContextTTL string
 
 	// amoCRM
 	AmoCRMAuthMode     AuthMode
@@ -36,6 +112,34 @@ type Config struct {
 	AmoCRMRedirectURI  string
 }
 
+// LLMSettings — параметры генерации одного провайдера в виде строк из окружения.
+type LLMSettings struct {
+	Temperature string
+	MaxTokens   string
+	Timeout     string
+}
+
+// llmProviders — провайдеры AI_PROVIDER, у которых могут быть свои параметры генерации.
+var llmProviders = []string{"ollama", "openai", "gemini", "gemini-cli"}
+
+// LLMEnvPrefix возвращает префикс переменных провайдера: "gemini-cli" → "GEMINI_CLI".
+func LLMEnvPrefix(provider string) string {
+	return strings.ToUpper(strings.ReplaceAll(provider, "-", "_"))
+}
+
+func loadLLMProviders() map[string]LLMSettings {
+	settings := make(map[string]LLMSettings, len(llmProviders))
+	for _, provider := range llmProviders {
+		prefix := LLMEnvPrefix(provider)
+		settings[provider] = LLMSettings{
+			Temperature: os.Getenv(prefix + "_TEMPERATURE"),
+			MaxTokens:   os.Getenv(prefix + "_MAX_TOKENS"),
+			Timeout:     os.Getenv(prefix + "_TIMEOUT"),
+		}
+	}
+	return settings
+}
+
 // Load loads configuration from environment variables
 func Load() *Config {
 	authMode := AuthMode(getEnvOrDefault("AMOCRM_AUTH_MODE", "token"))
@@ -46,10 +150,41 @@ func Load() *Config {
 	return &Config{
 		TelegramToken:      os.Getenv("TELEGRAM_BOT_TOKEN"),
 		Debug:              os.Getenv("DEBUG") == "true" || os.Getenv("DEBUG") == "1",
+		TelegramMode:       getEnvOrDefault("TELEGRAM_MODE", "polling"),
+		WebhookURL:         os.Getenv("TELEGRAM_WEBHOOK_URL"),
+		WebhookListen:      getEnvOrDefault("TELEGRAM_WEBHOOK_LISTEN", ":8081"),
+		WebhookSecret:      os.Getenv("TELEGRAM_WEBHOOK_SECRET"),
 		OllamaURL:          getEnvOrDefault("OLLAMA_URL", "http://localhost:11434"),
 		OllamaModel:        getEnvOrDefault("OLLAMA_MODEL", "gpt-oss:120b-cloud"),
 		AIProvider:         getEnvOrDefault("AI_PROVIDER", "ollama"),
+		AIFallback:         os.Getenv("AI_FALLBACK_PROVIDERS"),
+		OpenAIBaseURL:      getEnvOrDefault("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+		OpenAIAPIKey:       os.Getenv("OPENAI_API_KEY"),
+		OpenAIModel:        getEnvOrDefault("OPENAI_MODEL", "gpt-4o-mini"),
+		GeminiAPIKey:       os.Getenv("GEMINI_API_KEY"),
+		GeminiModel:        getEnvOrDefault("GEMINI_MODEL", "gemini-2.5-flash"),
 		GeminiCLICredsPath: getEnvOrDefault("GEMINI_CLI_CREDS_PATH", ".gemini-cli-oauth.json"),
+		GoogleCloudProject: os.Getenv("GOOGLE_CLOUD_PROJECT"),
+		NoBrowser:          os.Getenv("NO_BROWSER") == "true" || os.Getenv("NO_BROWSER") == "1",
+		AIRequireUserAuth:  os.Getenv("AI_REQUIRE_USER_AUTH") == "true" || os.Getenv("AI_REQUIRE_USER_AUTH") == "1",
+		LLMTemperature:     os.Getenv("LLM_TEMPERATURE"),
+		LLMMaxTokens:       os.Getenv("LLM_MAX_TOKENS"),
+		LLMTimeout:         getEnvOrDefault("LLM_TIMEOUT", "2m"),
+		LLMProviders:       loadLLMProviders(),
+		SessionStorage:     SessionStorage(getEnvOrDefault("SESSION_STORAGE", string(SessionStorageFile))),
+		SessionDir:         getEnvOrDefault("SESSION_DIR", ".sessions"),
+		HistoryMaxTokens:   getEnvIntOrDefault("HISTORY_MAX_TOKENS", 24000),
+		HistoryKeepTurns:   getEnvIntOrDefault("HISTORY_KEEP_TURNS", 6),
+		HistoryDigestChars: getEnvIntOrDefault("HISTORY_DIGEST_CHARS", 400),
+		AccessAdmins:       os.Getenv("ACCESS_ADMINS"),
+		AccessAllowlist:    os.Getenv("ACCESS_ALLOWLIST"),
+		AccessFile:         getEnvOrDefault("ACCESS_FILE", ".access.json"),
+		AccessDefaultRole:  getEnvOrDefault("ACCESS_DEFAULT_ROLE", "sales"),
+		STTURL:             os.Getenv("STT_URL"),
+		STTAPIKey:          os.Getenv("STT_API_KEY"),
+		STTModel:           getEnvOrDefault("STT_MODEL", "whisper-1"),
+		STTLanguage:        getEnvOrDefault("STT_LANGUAGE", "ru"),
+		AccountContextTTL:  getEnvOrDefault("ACCOUNT_CONTEXT_TTL", "15m"),
 		AmoCRMAuthMode:     authMode,
 		AmoCRMBaseURL:      os.Getenv("AMOCRM_BASE_URL"),
 		AmoCRMToken:        os.Getenv("AMOCRM_ACCESS_TOKEN"),
@@ -79,3 +214,10 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n >= 0 {
+		return n
+	}
+	return defaultValue
+}
@@ -9,6 +9,7 @@ require github.com/go-telegram/bot v1.17.0
 require (
 	github.com/achetronic/adk-utils-go v0.13.0
 	github.com/alextixru/amocrm-sdk-go v1.1.0
+	github.com/google/uuid v1.6.0
 	github.com/joho/godotenv v1.5.1
 	github.com/zalando/go-keyring v0.2.6
 	golang.org/x/oauth2 v0.35.0
@@ -35,7 +36,6 @@ require (
 	github.com/google/go-cmp v0.7.0 // indirect
 	github.com/google/s2a-go v0.1.9 // indirect
 	github.com/google/safehtml v0.1.0 // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/googleapis/enterprise-certificate-proxy v0.3.6 // indirect
 	github.com/googleapis/gax-go/v2 v2.15.0 // indirect
 	github.com/gorilla/mux v1.8.1 // indirect
@@ -0,0 +1,442 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// codeAssistEndpoint is the Gemini Code Assist API used by gemini-cli.
+const codeAssistEndpoint = "https://cloudcode-pa.googleapis.com/v1internal"
+
+// Onboarding of a new Code Assist user is a long-running operation polled by repeating the call.
+const (
+	onboardAttempts = 12
+	onboardInterval = 5 * time.Second
+)
+
+// CodeAssistModel is a model.LLM backed by Gemini Code Assist
+// (the free/Standard tier used by gemini-cli), authorized with Google OAuth.
+type CodeAssistModel struct {
+	name    string
+	client  *http.Client
+	project string
+}
+
+// NewCodeAssist creates a Code Assist model for the given OAuth token source.
+// It resolves (and onboards, if needed) the Code Assist project of the account;
+// projectID is required only for tiers where the user must choose a project.
+func NewCodeAssist(ctx context.Context, modelName string, ts oauth2.TokenSource, projectID string) (*CodeAssistModel, error) {
+	m := &CodeAssistModel{
+		name:   modelName,
+		client: oauth2.NewClient(ctx, ts),
+	}
+
+	project, err := m.setupProject(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("code assist setup: %w", err)
+	}
+	m.project = project
+	return m, nil
+}
+
+// Name implements model.LLM.
+func (m *CodeAssistModel) Name() string {
+	return m.name
+}
+
+// GenerateContent implements model.LLM.
+// In streaming mode text chunks are yielded as partial responses,
+// followed by one aggregated final response.
+func (m *CodeAssistModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		body := m.buildRequest(req)
+
+		if !stream {
+			var resp codeAssistResponse
+			if err := m.call(ctx, "generateContent", body, &resp); err != nil {
+				yield(nil, err)
+				return
+			}
+			yield(toLLMResponse(resp.Response), nil)
+			return
+		}
+
+		httpResp, err := m.post(ctx, "streamGenerateContent?alt=sse", body)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer httpResp.Body.Close()
+
+		var agg streamAggregator
+		for chunk, err := range readSSE(httpResp.Body) {
+			if err != nil {
+				yield(nil, fmt.Errorf("code assist stream: %w", err))
+				return
+			}
+			resp := toLLMResponse(chunk.Response)
+			if partial := agg.add(resp); partial != nil {
+				if !yield(partial, nil) {
+					return
+				}
+			}
+		}
+		yield(agg.final(), nil)
+	}
+}
+
+// === Request / response types ===
+
+type codeAssistRequest struct {
+	Model   string            `json:"model"`
+	Project string            `json:"project,omitempty"`
+	Request generateRequestV1 `json:"request"`
+}
+
+type generateRequestV1 struct {
+	Contents          []*genai.Content       `json:"contents"`
+	SystemInstruction *genai.Content         `json:"systemInstruction,omitempty"`
+	Tools             []*genai.Tool          `json:"tools,omitempty"`
+	ToolConfig        *genai.ToolConfig      `json:"toolConfig,omitempty"`
+	SafetySettings    []*genai.SafetySetting `json:"safetySettings,omitempty"`
+	GenerationConfig  *generationConfigV1    `json:"generationConfig,omitempty"`
+}
+
+type generationConfigV1 struct {
+	Temperature      *float32              `json:"temperature,omitempty"`
+	TopP             *float32              `json:"topP,omitempty"`
+	TopK             *float32              `json:"topK,omitempty"`
+	MaxOutputTokens  int32                 `json:"maxOutputTokens,omitempty"`
+	StopSequences    []string              `json:"stopSequences,omitempty"`
+	ResponseMIMEType string                `json:"responseMimeType,omitempty"`
+	ThinkingConfig   *genai.ThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+type codeAssistResponse struct {
+	Response *genai.GenerateContentResponse `json:"response"`
+}
+
+func (m *CodeAssistModel) buildRequest(req *model.LLMRequest) *codeAssistRequest {
+	modelName := m.name
+	if req.Model != "" {
+		modelName = req.Model
+	}
+
+	body := &codeAssistRequest{
+		Model:   modelName,
+		Project: m.project,
+		Request: generateRequestV1{Contents: req.Contents},
+	}
+
+	if cfg := req.Config; cfg != nil {
+		body.Request.SystemInstruction = cfg.SystemInstruction
+		body.Request.Tools = cfg.Tools
+		body.Request.ToolConfig = cfg.ToolConfig
+		body.Request.SafetySettings = cfg.SafetySettings
+		body.Request.GenerationConfig = &generationConfigV1{
+			Temperature:      cfg.Temperature,
+			TopP:             cfg.TopP,
+			TopK:             cfg.TopK,
+			MaxOutputTokens:  cfg.MaxOutputTokens,
+			StopSequences:    cfg.StopSequences,
+			ResponseMIMEType: cfg.ResponseMIMEType,
+			ThinkingConfig:   cfg.ThinkingConfig,
+		}
+	}
+	return body
+}
+
+// toLLMResponse converts the first candidate of a Gemini response.
+func toLLMResponse(resp *genai.GenerateContentResponse) *model.LLMResponse {
+	if resp == nil {
+		return &model.LLMResponse{ErrorCode: "EMPTY_RESPONSE", ErrorMessage: "empty response"}
+	}
+	if len(resp.Candidates) == 0 {
+		out := &model.LLMResponse{UsageMetadata: resp.UsageMetadata}
+		if pf := resp.PromptFeedback; pf != nil {
+			out.ErrorCode = string(pf.BlockReason)
+			out.ErrorMessage = pf.BlockReasonMessage
+		}
+		return out
+	}
+
+	c := resp.Candidates[0]
+	if c.Content != nil && c.Content.Role == "" {
+		c.Content.Role = genai.RoleModel
+	}
+	return &model.LLMResponse{
+		Content:           c.Content,
+		CitationMetadata:  c.CitationMetadata,
+		GroundingMetadata: c.GroundingMetadata,
+		UsageMetadata:     resp.UsageMetadata,
+		FinishReason:      c.FinishReason,
+		AvgLogprobs:       c.AvgLogprobs,
+		ModelVersion:      resp.ModelVersion,
+	}
+}
+
+// streamAggregator merges streamed chunks into a single final response.
+type streamAggregator struct {
+	text, thought strings.Builder
+	signature     []byte
+	calls         []*genai.Part
+	last          *model.LLMResponse
+}
+
+// add records a chunk and returns the partial response to forward, if it carries text.
+func (a *streamAggregator) add(resp *model.LLMResponse) *model.LLMResponse {
+	a.last = resp
+	if resp.Content == nil {
+		return nil
+	}
+
+	// Only answer text goes to partial responses, thoughts and calls wait for the final one
+	var textParts []*genai.Part
+	for _, part := range resp.Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			a.calls = append(a.calls, part)
+		case part.Thought:
+			a.thought.WriteString(part.Text)
+		case part.Text != "":
+			a.text.WriteString(part.Text)
+			textParts = append(textParts, &genai.Part{Text: part.Text})
+		}
+		if len(part.ThoughtSignature) > 0 && part.FunctionCall == nil {
+			a.signature = part.ThoughtSignature
+		}
+	}
+	if len(textParts) == 0 {
+		return nil
+	}
+
+	partial := *resp
+	partial.Content = &genai.Content{Role: genai.RoleModel, Parts: textParts}
+	partial.Partial = true
+	return &partial
+}
+
+// final returns the aggregated response: thoughts, text and function calls.
+func (a *streamAggregator) final() *model.LLMResponse {
+	var parts []*genai.Part
+	if a.thought.Len() > 0 {
+		parts = append(parts, &genai.Part{Text: a.thought.String(), Thought: true})
+	}
+	if a.text.Len() > 0 {
+		parts = append(parts, &genai.Part{Text: a.text.String(), ThoughtSignature: a.signature})
+	}
+	parts = append(parts, a.calls...)
+
+	out := &model.LLMResponse{TurnComplete: true}
+	if a.last != nil {
+		out.UsageMetadata = a.last.UsageMetadata
+		out.FinishReason = a.last.FinishReason
+		out.ModelVersion = a.last.ModelVersion
+		out.ErrorCode = a.last.ErrorCode
+		out.ErrorMessage = a.last.ErrorMessage
+	}
+	if len(parts) > 0 {
+		out.Content = &genai.Content{Role: genai.RoleModel, Parts: parts}
+	}
+	return out
+}
+
+// readSSE reads "data: {...}" events of a Code Assist stream.
+func readSSE(r io.Reader) iter.Seq2[*codeAssistResponse, error] {
+	return func(yield func(*codeAssistResponse, error) bool) {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+		var data strings.Builder
+		flush := func() bool {
+			if data.Len() == 0 {
+				return true
+			}
+			var chunk codeAssistResponse
+			err := json.Unmarshal([]byte(data.String()), &chunk)
+			data.Reset()
+			if err != nil {
+				yield(nil, fmt.Errorf("decode chunk: %w", err))
+				return false
+			}
+			return yield(&chunk, nil)
+		}
+
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "" {
+				if !flush() {
+					return
+				}
+				continue
+			}
+			if payload, ok := strings.CutPrefix(line, "data:"); ok {
+				data.WriteString(strings.TrimPrefix(payload, " "))
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(nil, err)
+			return
+		}
+		flush()
+	}
+}
+
+// === Project setup ===
+
+type clientMetadata struct {
+	IDEType     string `json:"ideType"`
+	Platform    string `json:"platform"`
+	PluginType  string `json:"pluginType"`
+	DuetProject string `json:"duetProject,omitempty"`
+}
+
+type userTier struct {
+	ID                                 string `json:"id"`
+	IsDefault                          bool   `json:"isDefault"`
+	UserDefinedCloudaicompanionProject bool   `json:"userDefinedCloudaicompanionProject"`
+}
+
+type loadCodeAssistResponse struct {
+	CurrentTier             *userTier  `json:"currentTier"`
+	AllowedTiers            []userTier `json:"allowedTiers"`
+	CloudaicompanionProject string     `json:"cloudaicompanionProject"`
+}
+
+type onboardUserResponse struct {
+	Done     bool `json:"done"`
+	Response struct {
+		CloudaicompanionProject struct {
+			ID string `json:"id"`
+		} `json:"cloudaicompanionProject"`
+	} `json:"response"`
+}
+
+// setupProject returns the Code Assist project of the account, onboarding the user if needed.
+func (m *CodeAssistModel) setupProject(ctx context.Context, projectID string) (string, error) {
+	metadata := clientMetadata{
+		IDEType:     "IDE_UNSPECIFIED",
+		Platform:    "PLATFORM_UNSPECIFIED",
+		PluginType:  "GEMINI",
+		DuetProject: projectID,
+	}
+
+	loadReq := map[string]any{"metadata": metadata}
+	if projectID != "" {
+		loadReq["cloudaicompanionProject"] = projectID
+	}
+
+	var load loadCodeAssistResponse
+	if err := m.call(ctx, "loadCodeAssist", loadReq, &load); err != nil {
+		return "", fmt.Errorf("load code assist: %w", err)
+	}
+
+	if load.CurrentTier != nil {
+		if load.CloudaicompanionProject != "" {
+			return load.CloudaicompanionProject, nil
+		}
+		if projectID != "" {
+			return projectID, nil
+		}
+		return "", fmt.Errorf("account requires GOOGLE_CLOUD_PROJECT")
+	}
+
+	tier := userTier{ID: "legacy-tier", UserDefinedCloudaicompanionProject: true}
+	for _, t := range load.AllowedTiers {
+		if t.IsDefault {
+			tier = t
+			break
+		}
+	}
+	if tier.UserDefinedCloudaicompanionProject && projectID == "" {
+		return "", fmt.Errorf("tier %q requires GOOGLE_CLOUD_PROJECT", tier.ID)
+	}
+
+	onboard := map[string]any{"tierId": tier.ID, "metadata": metadata}
+	if tier.UserDefinedCloudaicompanionProject {
+		onboard["cloudaicompanionProject"] = projectID
+	}
+
+	for attempt := 0; attempt < onboardAttempts; attempt++ {
+		var op onboardUserResponse
+		if err := m.call(ctx, "onboardUser", onboard, &op); err != nil {
+			return "", fmt.Errorf("onboard user: %w", err)
+		}
+		if op.Done {
+			if id := op.Response.CloudaicompanionProject.ID; id != "" {
+				return id, nil
+			}
+			return projectID, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(onboardInterval):
+		}
+	}
+	return "", fmt.Errorf("onboarding did not complete in %v", onboardAttempts*onboardInterval)
+}
+
+// === HTTP ===
+
+// APIError is a non-2xx response of an LLM HTTP API.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Body)
+}
+
+// call posts a JSON request to a Code Assist method and decodes the JSON response.
+func (m *CodeAssistModel) call(ctx context.Context, method string, body, out any) error {
+	resp, err := m.post(ctx, method, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s response: %w", method, err)
+	}
+	return nil
+}
+
+// post sends a request and returns the response if its status is 2xx.
+func (m *CodeAssistModel) post(ctx context.Context, method string, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s request: %w", method, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, codeAssistEndpoint+":"+method, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("%s: %w", method, &APIError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(msg))})
+	}
+	return resp, nil
+}
@@ -0,0 +1,300 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"log"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// Circuit breaker: after breakerThreshold consecutive failures a provider is skipped
+// for breakerCooldown, then a single trial request decides whether it is healthy again.
+const (
+	breakerThreshold = 3
+	breakerCooldown  = time.Minute
+)
+
+// ErrorClass is the kind of an LLM call failure.
+type ErrorClass string
+
+const (
+	ErrorTimeout       ErrorClass = "timeout"
+	ErrorRateLimit     ErrorClass = "rate_limit"
+	ErrorServer        ErrorClass = "server"
+	ErrorContextLength ErrorClass = "context_length"
+	ErrorUnavailable   ErrorClass = "unavailable"
+	ErrorCanceled      ErrorClass = "canceled"
+	ErrorOther         ErrorClass = "other"
+)
+
+// Retryable reports whether another provider may succeed where this one failed.
+func (c ErrorClass) Retryable() bool {
+	switch c {
+	case ErrorTimeout, ErrorRateLimit, ErrorServer, ErrorContextLength, ErrorUnavailable:
+		return true
+	}
+	return false
+}
+
+// statusRegex extracts an HTTP status from OpenAI SDK errors: `POST "url": 429 Too Many Requests`.
+var statusRegex = regexp.MustCompile(`": (\d{3}) `)
+
+// ClassifyError determines the kind of an LLM error.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorCanceled
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorTimeout
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"context length", "context_length", "maximum context", "too many tokens", "prompt is too long", "input token count"} {
+		if strings.Contains(msg, s) {
+			return ErrorContextLength
+		}
+	}
+
+	status := 0
+	var apiErr *APIError
+	var genaiErr genai.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.StatusCode
+	case errors.As(err, &genaiErr):
+		status = genaiErr.Code
+	default:
+		if m := statusRegex.FindStringSubmatch(err.Error()); m != nil {
+			status, _ = strconv.Atoi(m[1])
+		}
+	}
+	switch {
+	case status == 429:
+		return ErrorRateLimit
+	case status == 408 || status == 504:
+		return ErrorTimeout
+	case status >= 500:
+		return ErrorServer
+	case status != 0:
+		return ErrorOther
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ErrorTimeout
+		}
+		return ErrorUnavailable
+	}
+	if strings.Contains(msg, "connection refused") || strings.Contains(msg, "no such host") || strings.Contains(msg, "eof") {
+		return ErrorUnavailable
+	}
+	return ErrorOther
+}
+
+// Backend is a named provider in a fallback chain.
+type Backend struct {
+	Name string // provider name for logs, e.g. "ollama"
+	LLM  model.LLM
+}
+
+// BackendStats is the state of a provider in a fallback chain.
+type BackendStats struct {
+	Name      string
+	Model     string
+	Answered  int64 // requests answered by this provider
+	Failed    int64 // failed attempts
+	Open      bool  // circuit breaker is open, provider is skipped
+	LastError string
+}
+
+// backend holds circuit breaker state of a provider.
+type backend struct {
+	Backend
+
+	mu        sync.Mutex
+	failures  int // consecutive failures
+	openUntil time.Time
+	trial     bool // a half-open trial request is in flight
+	answered  int64
+	failed    int64
+	lastErr   string
+}
+
+// Fallback is a model.LLM that tries providers in order, moving to the next one
+// on retryable errors (timeouts, 429, 5xx, context length, network).
+type Fallback struct {
+	backends []*backend
+}
+
+// NewFallback creates a fallback chain. The first backend is the primary one.
+func NewFallback(backends ...Backend) *Fallback {
+	f := &Fallback{}
+	for _, b := range backends {
+		f.backends = append(f.backends, &backend{Backend: b})
+	}
+	return f
+}
+
+// Name implements model.LLM and returns the primary model name.
+func (f *Fallback) Name() string {
+	return f.backends[0].LLM.Name()
+}
+
+// Stats returns per-provider counters and breaker state.
+func (f *Fallback) Stats() []BackendStats {
+	stats := make([]BackendStats, 0, len(f.backends))
+	now := time.Now()
+	for _, b := range f.backends {
+		b.mu.Lock()
+		stats = append(stats, BackendStats{
+			Name:      b.Name,
+			Model:     b.LLM.Name(),
+			Answered:  b.answered,
+			Failed:    b.failed,
+			Open:      now.Before(b.openUntil),
+			LastError: b.lastErr,
+		})
+		b.mu.Unlock()
+	}
+	return stats
+}
+
+// GenerateContent implements model.LLM.
+// Failover happens only before the first response is yielded: once partial text
+// reached the caller, an error of the same provider is returned as is.
+func (f *Fallback) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		var errs []error
+		failed := 0
+
+		try := func(b *backend) (done bool) {
+			if ctx.Err() != nil {
+				yield(nil, ctx.Err())
+				return true
+			}
+			yielded, err := f.attempt(ctx, b, req, stream, yield)
+			if err == nil {
+				if failed > 0 {
+					log.Printf("[llm] answered by %s (%s) after %d failed provider(s)", b.Name, b.LLM.Name(), failed)
+				}
+				return true
+			}
+			if yielded || !ClassifyError(err).Retryable() {
+				yield(nil, err)
+				return true
+			}
+			failed++
+			errs = append(errs, fmt.Errorf("%s: %w", b.Name, err))
+			return false
+		}
+
+		tried := 0
+		for _, b := range f.backends {
+			if !b.allow() {
+				continue
+			}
+			tried++
+			if try(b) {
+				return
+			}
+		}
+		if tried == 0 {
+			// Every breaker is open: try all providers anyway, a slow answer beats no answer
+			for _, b := range f.backends {
+				if try(b) {
+					return
+				}
+			}
+		}
+		yield(nil, fmt.Errorf("all LLM providers failed: %w", errors.Join(errs...)))
+	}
+}
+
+// attempt runs a request on one provider, forwarding its responses, and updates the breaker.
+// yielded reports whether any response reached the caller before err.
+func (f *Fallback) attempt(ctx context.Context, b *backend, req *model.LLMRequest, stream bool, yield func(*model.LLMResponse, error) bool) (yielded bool, err error) {
+	for resp, err := range b.LLM.GenerateContent(ctx, req, stream) {
+		if err != nil {
+			class := ClassifyError(err)
+			switch {
+			case class == ErrorCanceled && ctx.Err() != nil:
+				b.release()
+			case !class.Retryable():
+				// Bad requests say nothing about provider health
+				b.release()
+				log.Printf("[llm] %s (%s) failed [%s]: %v", b.Name, b.LLM.Name(), class, err)
+			default:
+				b.failure(err)
+				log.Printf("[llm] %s (%s) failed [%s]: %v", b.Name, b.LLM.Name(), class, err)
+			}
+			return yielded, err
+		}
+		yielded = true
+		if !yield(resp, nil) {
+			b.success()
+			// The caller stopped: report as done without error
+			return true, nil
+		}
+	}
+	b.success()
+	return yielded, nil
+}
+
+// allow reports whether the breaker lets a request through.
+func (b *backend) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < breakerThreshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.trial {
+		return false
+	}
+	// Half-open: let one trial request through
+	b.trial = true
+	return true
+}
+
+func (b *backend) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trial = false
+	b.answered++
+}
+
+// release ends a half-open trial without changing the breaker state.
+func (b *backend) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+func (b *backend) failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.failed++
+	b.trial = false
+	b.lastErr = err.Error()
+	if b.failures >= breakerThreshold {
+		b.openUntil = time.Now().Add(breakerCooldown)
+	}
+}
@@ -0,0 +1,222 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		want      ErrorClass
+		retryable bool
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "canceled", err: fmt.Errorf("generate: %w", context.Canceled), want: ErrorCanceled},
+		{name: "deadline", err: fmt.Errorf("generate: %w", context.DeadlineExceeded), want: ErrorTimeout, retryable: true},
+		{name: "context length", err: errors.New("This model's maximum context length is 8192 tokens"), want: ErrorContextLength, retryable: true},
+		{name: "prompt too long", err: &APIError{StatusCode: 400, Body: "prompt is too long"}, want: ErrorContextLength, retryable: true},
+		{name: "code assist 429", err: &APIError{StatusCode: 429, Body: "quota"}, want: ErrorRateLimit, retryable: true},
+		{name: "code assist 500", err: fmt.Errorf("stream: %w", &APIError{StatusCode: 500}), want: ErrorServer, retryable: true},
+		{name: "code assist 408", err: &APIError{StatusCode: 408}, want: ErrorTimeout, retryable: true},
+		{name: "code assist 400", err: &APIError{StatusCode: 400, Body: "invalid argument"}, want: ErrorOther},
+		{name: "code assist 401", err: &APIError{StatusCode: 401}, want: ErrorOther},
+		{name: "genai 503", err: genai.APIError{Code: 503, Message: "overloaded"}, want: ErrorServer, retryable: true},
+		{name: "genai 504", err: genai.APIError{Code: 504}, want: ErrorTimeout, retryable: true},
+		{name: "genai 403", err: genai.APIError{Code: 403, Message: "permission denied"}, want: ErrorOther},
+		{name: "openai 429", err: errors.New(`POST "https://api.openai.com/v1/chat/completions": 429 Too Many Requests`), want: ErrorRateLimit, retryable: true},
+		{name: "openai 404", err: errors.New(`POST "http://localhost:11434/v1/chat/completions": 404 Not Found`), want: ErrorOther},
+		{name: "net timeout", err: &net.OpError{Op: "dial", Err: timeoutError{}}, want: ErrorTimeout, retryable: true},
+		{name: "connection refused", err: errors.New("dial tcp 127.0.0.1:11434: connect: connection refused"), want: ErrorUnavailable, retryable: true},
+		{name: "unexpected eof", err: errors.New("unexpected EOF"), want: ErrorUnavailable, retryable: true},
+		{name: "other", err: errors.New("invalid tool schema"), want: ErrorOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyError(tt.err)
+			if got != tt.want {
+				t.Errorf("ClassifyError(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+			if got.Retryable() != tt.retryable {
+				t.Errorf("%q.Retryable() = %v, want %v", got, got.Retryable(), tt.retryable)
+			}
+		})
+	}
+}
+
+// timeoutError is a net.Error that reports a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// fakeLLM yields parts as responses, then fails with err if it is set.
+type fakeLLM struct {
+	name  string
+	parts []string
+	err   error
+	calls int
+}
+
+func (f *fakeLLM) Name() string { return f.name }
+
+func (f *fakeLLM) GenerateContent(context.Context, *model.LLMRequest, bool) iter.Seq2[*model.LLMResponse, error] {
+	f.calls++
+	return func(yield func(*model.LLMResponse, error) bool) {
+		for _, part := range f.parts {
+			if !yield(&model.LLMResponse{Content: genai.NewContentFromText(part, genai.RoleModel)}, nil) {
+				return
+			}
+		}
+		if f.err != nil {
+			yield(nil, f.err)
+		}
+	}
+}
+
+// generate collects the text and the final error of one request.
+func generate(f *Fallback) (string, error) {
+	var text strings.Builder
+	for resp, err := range f.GenerateContent(context.Background(), &model.LLMRequest{}, true) {
+		if err != nil {
+			return text.String(), err
+		}
+		for _, part := range resp.Content.Parts {
+			text.WriteString(part.Text)
+		}
+	}
+	return text.String(), nil
+}
+
+func TestFallbackFailover(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		failover  bool
+		wantError bool
+	}{
+		{name: "server error", err: &APIError{StatusCode: 503}, failover: true},
+		{name: "rate limit", err: errors.New(`POST "https://api.openai.com/v1/chat/completions": 429 Too Many Requests`), failover: true},
+		{name: "bad request", err: &APIError{StatusCode: 400, Body: "invalid argument"}, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &fakeLLM{name: "gemini", err: tt.err}
+			secondary := &fakeLLM{name: "llama", parts: []string{"ok"}}
+			f := NewFallback(Backend{Name: "google", LLM: primary}, Backend{Name: "ollama", LLM: secondary})
+
+			text, err := generate(f)
+			if tt.wantError != (err != nil) {
+				t.Fatalf("err = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantError && !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want the primary error as is", err)
+			}
+			if tt.failover != (secondary.calls == 1) || tt.failover != (text == "ok") {
+				t.Errorf("secondary calls = %d, text = %q, failover %v", secondary.calls, text, tt.failover)
+			}
+			stats := f.Stats()
+			if tt.failover && (stats[0].Failed != 1 || stats[1].Answered != 1) {
+				t.Errorf("stats = %+v", stats)
+			}
+		})
+	}
+}
+
+func TestFallbackNoFailoverAfterPartialResponse(t *testing.T) {
+	primary := &fakeLLM{name: "gemini", parts: []string{"Сделка "}, err: &APIError{StatusCode: 503}}
+	secondary := &fakeLLM{name: "llama", parts: []string{"ok"}}
+	f := NewFallback(Backend{Name: "google", LLM: primary}, Backend{Name: "ollama", LLM: secondary})
+
+	text, err := generate(f)
+	if text != "Сделка " {
+		t.Errorf("text = %q, want only the partial primary response", text)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || strings.Contains(err.Error(), "all LLM providers failed") {
+		t.Errorf("err = %v, want the primary error as is", err)
+	}
+	if secondary.calls != 0 {
+		t.Errorf("secondary called %d times after a partial response", secondary.calls)
+	}
+}
+
+func TestFallbackBreaker(t *testing.T) {
+	primary := &fakeLLM{name: "gemini", err: &APIError{StatusCode: 500}}
+	secondary := &fakeLLM{name: "llama", parts: []string{"ok"}}
+	f := NewFallback(Backend{Name: "google", LLM: primary}, Backend{Name: "ollama", LLM: secondary})
+	// expire ends the cooldown of the primary's open breaker
+	expire := func() {
+		b := f.backends[0]
+		b.mu.Lock()
+		b.openUntil = time.Now().Add(-time.Second)
+		b.mu.Unlock()
+	}
+
+	// Consecutive failures open the breaker: the primary is skipped
+	for range breakerThreshold {
+		if _, err := generate(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !f.Stats()[0].Open {
+		t.Fatalf("breaker closed after %d failures", breakerThreshold)
+	}
+	if _, err := generate(f); err != nil || primary.calls != breakerThreshold {
+		t.Fatalf("open breaker: primary calls = %d, err = %v", primary.calls, err)
+	}
+
+	// Half-open: one trial request; a failed trial opens the breaker again
+	expire()
+	if _, err := generate(f); err != nil || primary.calls != breakerThreshold+1 {
+		t.Fatalf("half-open: primary calls = %d, err = %v", primary.calls, err)
+	}
+	if !f.Stats()[0].Open {
+		t.Fatal("breaker closed after a failed trial")
+	}
+
+	// A successful trial closes the breaker
+	expire()
+	primary.parts, primary.err = []string{"primary"}, nil
+	if text, err := generate(f); err != nil || text != "primary" {
+		t.Fatalf("trial = %q, %v", text, err)
+	}
+	if text, err := generate(f); err != nil || text != "primary" {
+		t.Fatalf("after trial = %q, %v", text, err)
+	}
+	if st := f.Stats()[0]; st.Open || st.Answered != 2 || st.Failed != breakerThreshold+1 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestFallbackAllFailed(t *testing.T) {
+	f := NewFallback(
+		Backend{Name: "google", LLM: &fakeLLM{name: "gemini", err: &APIError{StatusCode: 429, Body: "quota"}}},
+		Backend{Name: "ollama", LLM: &fakeLLM{name: "llama", err: errors.New("dial tcp 127.0.0.1:11434: connect: connection refused")}},
+	)
+
+	_, err := generate(f)
+	if err == nil {
+		t.Fatal("want an error")
+	}
+	for _, s := range []string{"all LLM providers failed", "google:", "ollama:", "connection refused"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("err = %q, want it to contain %q", err, s)
+		}
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 429 {
+		t.Errorf("provider errors are not wrapped: %v", err)
+	}
+}
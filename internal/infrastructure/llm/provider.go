@@ -2,18 +2,112 @@
 package llm
 
 import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
 	genaiopenai "github.com/achetronic/adk-utils-go/genai/openai"
 	"google.golang.org/adk/model"
+	"google.golang.org/adk/model/gemini"
+	"google.golang.org/genai"
 
 	"github.com/tihn/amo-ai-tgbot-go/config"
+	"github.com/tihn/amo-ai-tgbot-go/internal/infrastructure/google/oauth"
+)
+
+// Supported AI_PROVIDER values.
+const (
+	ProviderOllama    = "ollama"
+	ProviderOpenAI    = "openai"
+	ProviderGemini    = "gemini"
+	ProviderGeminiCLI = "gemini-cli"
 )
 
 // NewProvider creates an ADK-compatible LLM model from application config.
-// Currently supports Ollama via OpenAI-compatible API.
-func NewProvider(cfg *config.Config) model.LLM {
-	return genaiopenai.New(genaiopenai.Config{
-		BaseURL:   cfg.OllamaURL + "/v1",
-		ModelName: cfg.OllamaModel,
-		APIKey:    "ollama", // Ollama doesn't require a key, but the field is mandatory
-	})
+// The provider is selected by AI_PROVIDER; generation settings of each provider are validated here,
+// so a misconfigured bot fails at startup rather than on the first message.
+// With AI_FALLBACK_PROVIDERS the result is a Fallback chain with AI_PROVIDER first.
+func NewProvider(ctx context.Context, cfg *config.Config) (model.LLM, error) {
+	names := []string{cfg.AIProvider}
+	for _, name := range strings.Split(cfg.AIFallback, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if slices.Contains(names, name) {
+			return nil, fmt.Errorf("llm: provider %q is listed twice in AI_PROVIDER/AI_FALLBACK_PROVIDERS", name)
+		}
+		names = append(names, name)
+	}
+
+	backends := make([]Backend, 0, len(names))
+	for _, name := range names {
+		settings, err := ParseSettings(cfg, name)
+		if err != nil {
+			return nil, err
+		}
+		base, err := newBase(ctx, cfg, name)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, Backend{Name: name, LLM: WithSettings(base, settings)})
+	}
+
+	if len(backends) == 1 {
+		return backends[0].LLM, nil
+	}
+	return NewFallback(backends...), nil
+}
+
+// newBase creates a provider model without generation settings.
+func newBase(ctx context.Context, cfg *config.Config, provider string) (model.LLM, error) {
+	switch provider {
+	case ProviderOllama:
+		return genaiopenai.New(genaiopenai.Config{
+			BaseURL:   cfg.OllamaURL + "/v1",
+			ModelName: cfg.OllamaModel,
+			APIKey:    "ollama", // Ollama doesn't require a key, but the field is mandatory
+		}), nil
+
+	case ProviderOpenAI:
+		if cfg.OpenAIAPIKey == "" {
+			return nil, fmt.Errorf("llm: OPENAI_API_KEY is required for provider %s", ProviderOpenAI)
+		}
+		if cfg.OpenAIModel == "" {
+			return nil, fmt.Errorf("llm: OPENAI_MODEL is required for provider %s", ProviderOpenAI)
+		}
+		return genaiopenai.New(genaiopenai.Config{
+			BaseURL:   cfg.OpenAIBaseURL,
+			ModelName: cfg.OpenAIModel,
+			APIKey:    cfg.OpenAIAPIKey,
+		}), nil
+
+	case ProviderGemini:
+		if cfg.GeminiAPIKey == "" {
+			return nil, fmt.Errorf("llm: GEMINI_API_KEY is required for provider %s", ProviderGemini)
+		}
+		m, err := gemini.NewModel(ctx, cfg.GeminiModel, &genai.ClientConfig{
+			APIKey:  cfg.GeminiAPIKey,
+			Backend: genai.BackendGeminiAPI,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("llm: create gemini model: %w", err)
+		}
+		return m, nil
+
+	case ProviderGeminiCLI:
+		ts, err := oauth.GetTokenSource(ctx, cfg.GeminiCLICredsPath, cfg.NoBrowser)
+		if err != nil {
+			return nil, fmt.Errorf("llm: gemini-cli auth: %w", err)
+		}
+		m, err := NewCodeAssist(ctx, cfg.GeminiModel, ts, cfg.GoogleCloudProject)
+		if err != nil {
+			return nil, fmt.Errorf("llm: %w", err)
+		}
+		return m, nil
+	}
+
+	return nil, fmt.Errorf("llm: unknown provider %q (expected: %s, %s, %s, %s)",
+		provider, ProviderOllama, ProviderOpenAI, ProviderGemini, ProviderGeminiCLI)
 }
@@ -0,0 +1,45 @@
+package llm
+
+import (
+	"context"
+	"iter"
+
+	"google.golang.org/adk/model"
+)
+
+type modelContextKey struct{}
+
+// ContextWithModel returns a context that makes a Router use m for requests made with it.
+// The agent passes the run context down to the model, so a model chosen per user
+// before runner.Run is used for every LLM call of that run.
+func ContextWithModel(ctx context.Context, m model.LLM) context.Context {
+	return context.WithValue(ctx, modelContextKey{}, m)
+}
+
+// ModelFromContext returns the model stored by ContextWithModel, if any.
+func ModelFromContext(ctx context.Context) (model.LLM, bool) {
+	m, ok := ctx.Value(modelContextKey{}).(model.LLM)
+	return m, ok && m != nil
+}
+
+// router sends requests to the model from the context, or to the fallback model.
+type router struct {
+	fallback model.LLM
+}
+
+// NewRouter creates a model that dispatches each request to the model stored
+// in the request context (see ContextWithModel), falling back to fallback.
+func NewRouter(fallback model.LLM) model.LLM {
+	return &router{fallback: fallback}
+}
+
+func (r *router) Name() string {
+	return r.fallback.Name()
+}
+
+func (r *router) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	if m, ok := ModelFromContext(ctx); ok {
+		return m.GenerateContent(ctx, req, stream)
+	}
+	return r.fallback.GenerateContent(ctx, req, stream)
+}
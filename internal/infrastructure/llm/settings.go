@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strconv"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+
+	"github.com/tihn/amo-ai-tgbot-go/config"
+)
+
+// Settings are generation parameters applied to every request of a provider.
+type Settings struct {
+	Temperature *float32      // nil — model default
+	MaxTokens   int32         // 0 — no limit
+	Timeout     time.Duration // per request, including streaming
+}
+
+// ParseSettings parses and validates generation settings of a provider:
+// <PROVIDER>_TEMPERATURE, <PROVIDER>_MAX_TOKENS and <PROVIDER>_TIMEOUT (e.g. OPENAI_TIMEOUT),
+// each falling back to LLM_TEMPERATURE, LLM_MAX_TOKENS and LLM_TIMEOUT.
+func ParseSettings(cfg *config.Config, provider string) (Settings, error) {
+	var s Settings
+	own := cfg.LLMProviders[provider]
+	prefix := config.LLMEnvPrefix(provider)
+
+	if name, value := pick(prefix+"_TEMPERATURE", own.Temperature, "LLM_TEMPERATURE", cfg.LLMTemperature); value != "" {
+		t, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return s, fmt.Errorf("llm: invalid %s %q: %w", name, value, err)
+		}
+		if t < 0 || t > 2 {
+			return s, fmt.Errorf("llm: %s must be between 0 and 2, got %v", name, t)
+		}
+		temp := float32(t)
+		s.Temperature = &temp
+	}
+
+	if name, value := pick(prefix+"_MAX_TOKENS", own.MaxTokens, "LLM_MAX_TOKENS", cfg.LLMMaxTokens); value != "" {
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return s, fmt.Errorf("llm: invalid %s %q: %w", name, value, err)
+		}
+		if n < 0 {
+			return s, fmt.Errorf("llm: %s must not be negative, got %d", name, n)
+		}
+		s.MaxTokens = int32(n)
+	}
+
+	if name, value := pick(prefix+"_TIMEOUT", own.Timeout, "LLM_TIMEOUT", cfg.LLMTimeout); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return s, fmt.Errorf("llm: invalid %s %q: %w", name, value, err)
+		}
+		if d <= 0 {
+			return s, fmt.Errorf("llm: %s must be positive, got %v", name, d)
+		}
+		s.Timeout = d
+	}
+
+	return s, nil
+}
+
+// pick returns the provider's own setting if set, otherwise the shared one,
+// with the variable name for error messages.
+func pick(ownName, own, sharedName, shared string) (name, value string) {
+	if own != "" {
+		return ownName, own
+	}
+	return sharedName, shared
+}
+
+// configuredModel applies Settings to requests of the wrapped model.
+type configuredModel struct {
+	base     model.LLM
+	settings Settings
+}
+
+// WithSettings wraps a model so that every request gets the configured
+// temperature, token limit and timeout. Values set explicitly in a request win.
+func WithSettings(base model.LLM, settings Settings) model.LLM {
+	return &configuredModel{base: base, settings: settings}
+}
+
+func (m *configuredModel) Name() string {
+	return m.base.Name()
+}
+
+func (m *configuredModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		if m.settings.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, m.settings.Timeout)
+			defer cancel()
+		}
+
+		// The request and its config are shared with the caller (and other providers
+		// of a fallback chain), so the settings go into copies
+		configured := *req
+		if req.Config != nil {
+			cfg := *req.Config
+			configured.Config = &cfg
+		} else {
+			configured.Config = &genai.GenerateContentConfig{}
+		}
+		if configured.Config.Temperature == nil && m.settings.Temperature != nil {
+			configured.Config.Temperature = m.settings.Temperature
+		}
+		if configured.Config.MaxOutputTokens == 0 && m.settings.MaxTokens > 0 {
+			configured.Config.MaxOutputTokens = m.settings.MaxTokens
+		}
+		req = &configured
+
+		for resp, err := range m.base.GenerateContent(ctx, req, stream) {
+			if err != nil && ctx.Err() == context.DeadlineExceeded {
+				err = fmt.Errorf("%w (timeout %v)", err, m.settings.Timeout)
+			}
+			if !yield(resp, err) {
+				return
+			}
+		}
+	}
+}
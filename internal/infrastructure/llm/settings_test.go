@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"context"
+	"iter"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+
+	"github.com/tihn/amo-ai-tgbot-go/config"
+)
+
+func TestParseSettings(t *testing.T) {
+	cfg := &config.Config{
+		LLMTemperature: "0.2",
+		LLMMaxTokens:   "8192",
+		LLMTimeout:     "2m",
+		LLMProviders: map[string]config.LLMSettings{
+			ProviderOllama:    {Timeout: "5m", Temperature: "0.7"},
+			ProviderGeminiCLI: {MaxTokens: "1024"},
+			ProviderOpenAI:    {Timeout: "soon"},
+		},
+	}
+
+	ollama, err := ParseSettings(cfg, ProviderOllama)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *ollama.Temperature != 0.7 || ollama.MaxTokens != 8192 || ollama.Timeout != 5*time.Minute {
+		t.Errorf("ollama settings = %v %d %v", *ollama.Temperature, ollama.MaxTokens, ollama.Timeout)
+	}
+
+	cli, err := ParseSettings(cfg, ProviderGeminiCLI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *cli.Temperature != 0.2 || cli.MaxTokens != 1024 || cli.Timeout != 2*time.Minute {
+		t.Errorf("gemini-cli settings = %v %d %v", *cli.Temperature, cli.MaxTokens, cli.Timeout)
+	}
+
+	if _, err := ParseSettings(cfg, ProviderOpenAI); err == nil || !strings.Contains(err.Error(), "OPENAI_TIMEOUT") {
+		t.Errorf("error = %v, want it to name OPENAI_TIMEOUT", err)
+	}
+	cfg.LLMTemperature = "3"
+	if _, err := ParseSettings(cfg, ProviderGemini); err == nil || !strings.Contains(err.Error(), "LLM_TEMPERATURE") {
+		t.Errorf("error = %v, want it to name LLM_TEMPERATURE", err)
+	}
+}
+
+// recordingLLM records the config of the last request.
+type recordingLLM struct {
+	got *genai.GenerateContentConfig
+}
+
+func (m *recordingLLM) Name() string { return "recording" }
+
+func (m *recordingLLM) GenerateContent(_ context.Context, req *model.LLMRequest, _ bool) iter.Seq2[*model.LLMResponse, error] {
+	m.got = req.Config
+	return func(yield func(*model.LLMResponse, error) bool) {}
+}
+
+func TestWithSettingsDoesNotChangeRequest(t *testing.T) {
+	temp := float32(0.3)
+	base := &recordingLLM{}
+	m := WithSettings(base, Settings{Temperature: &temp, MaxTokens: 100})
+
+	shared := &genai.GenerateContentConfig{SystemInstruction: genai.NewContentFromText("sys", genai.RoleUser)}
+	req := &model.LLMRequest{Config: shared}
+	for range m.GenerateContent(context.Background(), req, false) {
+	}
+
+	if shared.Temperature != nil || shared.MaxOutputTokens != 0 || req.Config != shared {
+		t.Error("caller's request config was changed")
+	}
+	if base.got == nil || *base.got.Temperature != 0.3 || base.got.MaxOutputTokens != 100 || base.got.SystemInstruction == nil {
+		t.Errorf("provider got config %+v", base.got)
+	}
+
+	own := float32(1)
+	shared.Temperature = &own
+	for range m.GenerateContent(context.Background(), req, false) {
+	}
+	if *base.got.Temperature != 1 {
+		t.Error("explicit request temperature must win")
+	}
+}
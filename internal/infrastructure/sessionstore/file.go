@@ -0,0 +1,587 @@
+package sessionstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/adk/session"
+)
+
+// FileService is an append-only file implementation of session.Service.
+// All sessions are kept in memory and every change is appended to disk.
+//
+// Layout:
+//
+//	{baseDir}/{app}/app_state.json
+//	{baseDir}/{app}/users/{user}/user_state.json
+//	{baseDir}/{app}/users/{user}/sessions/{session}.jsonl
+//
+// Each session file starts with a "create" record followed by one "event" record per appended event.
+type FileService struct {
+	baseDir string
+
+	mu        sync.RWMutex
+	sessions  map[sessionKey]*storedSession
+	appState  map[string]map[string]any
+	userState map[string]map[string]map[string]any // app -> user -> state
+}
+
+type sessionKey struct {
+	appName, userID, sessionID string
+}
+
+type storedSession struct {
+	key       sessionKey
+	state     map[string]any
+	events    []*session.Event
+	updatedAt time.Time
+}
+
+// record is a single line of a session file.
+type record struct {
+	Type  string         `json:"type"` // "create" or "event"
+	Time  time.Time      `json:"time"`
+	State map[string]any `json:"state,omitempty"`
+	Event *session.Event `json:"event,omitempty"`
+}
+
+const (
+	recordCreate = "create"
+	recordEvent  = "event"
+)
+
+// NewFileService creates a file-based session service and loads existing sessions from baseDir.
+func NewFileService(baseDir string) (*FileService, error) {
+	if err := os.MkdirAll(baseDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	s := &FileService{
+		baseDir:   baseDir,
+		sessions:  make(map[sessionKey]*storedSession),
+		appState:  make(map[string]map[string]any),
+		userState: make(map[string]map[string]map[string]any),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Create implements session.Service.
+func (s *FileService) Create(ctx context.Context, req *session.CreateRequest) (*session.CreateResponse, error) {
+	if req.AppName == "" || req.UserID == "" {
+		return nil, fmt.Errorf("app_name and user_id are required, got app_name: %q, user_id: %q", req.AppName, req.UserID)
+	}
+
+	sessionID := req.SessionID
+	if sessionID == "" {
+		sessionID = uuid.NewString()
+	}
+	if err := checkNames(req.AppName, req.UserID, sessionID); err != nil {
+		return nil, err
+	}
+	key := sessionKey{appName: req.AppName, userID: req.UserID, sessionID: sessionID}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[key]; ok {
+		return nil, fmt.Errorf("session %s already exists", sessionID)
+	}
+
+	appDelta, userDelta, sessionState := extractStateDeltas(req.State)
+	now := time.Now()
+
+	if err := s.appendRecord(key, record{Type: recordCreate, Time: now, State: sessionState}); err != nil {
+		return nil, err
+	}
+	if err := s.updateScopedStateLocked(key, appDelta, userDelta); err != nil {
+		return nil, err
+	}
+
+	stored := &storedSession{key: key, state: sessionState, updatedAt: now}
+	s.sessions[key] = stored
+
+	return &session.CreateResponse{Session: s.snapshotLocked(stored, nil)}, nil
+}
+
+// Get implements session.Service.
+func (s *FileService) Get(ctx context.Context, req *session.GetRequest) (*session.GetResponse, error) {
+	if req.AppName == "" || req.UserID == "" || req.SessionID == "" {
+		return nil, fmt.Errorf("app_name, user_id, session_id are required, got app_name: %q, user_id: %q, session_id: %q", req.AppName, req.UserID, req.SessionID)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, ok := s.sessions[sessionKey{appName: req.AppName, userID: req.UserID, sessionID: req.SessionID}]
+	if !ok {
+		return nil, fmt.Errorf("session %s not found", req.SessionID)
+	}
+
+	events := stored.events
+	if req.NumRecentEvents > 0 && len(events) > req.NumRecentEvents {
+		events = events[len(events)-req.NumRecentEvents:]
+	}
+	if !req.After.IsZero() {
+		first := sort.Search(len(events), func(i int) bool {
+			return !events[i].Timestamp.Before(req.After)
+		})
+		events = events[first:]
+	}
+
+	return &session.GetResponse{Session: s.snapshotLocked(stored, events)}, nil
+}
+
+// List implements session.Service. Sessions are returned without events.
+func (s *FileService) List(ctx context.Context, req *session.ListRequest) (*session.ListResponse, error) {
+	if req.AppName == "" {
+		return nil, fmt.Errorf("app_name is required, got app_name: %q", req.AppName)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := make([]session.Session, 0)
+	for key, stored := range s.sessions {
+		if key.appName != req.AppName || (req.UserID != "" && key.userID != req.UserID) {
+			continue
+		}
+		sessions = append(sessions, s.snapshotLocked(stored, nil))
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUpdateTime().After(sessions[j].LastUpdateTime())
+	})
+
+	return &session.ListResponse{Sessions: sessions}, nil
+}
+
+// Delete implements session.Service.
+func (s *FileService) Delete(ctx context.Context, req *session.DeleteRequest) error {
+	if req.AppName == "" || req.UserID == "" || req.SessionID == "" {
+		return fmt.Errorf("app_name, user_id, session_id are required, got app_name: %q, user_id: %q, session_id: %q", req.AppName, req.UserID, req.SessionID)
+	}
+
+	if err := checkNames(req.AppName, req.UserID, req.SessionID); err != nil {
+		return err
+	}
+	key := sessionKey{appName: req.AppName, userID: req.UserID, sessionID: req.SessionID}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.sessionPath(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete session file: %w", err)
+	}
+	delete(s.sessions, key)
+	return nil
+}
+
+// AppendEvent implements session.Service. Partial events are not stored.
+func (s *FileService) AppendEvent(ctx context.Context, curSession session.Session, event *session.Event) error {
+	if curSession == nil {
+		return fmt.Errorf("session is nil")
+	}
+	if event == nil {
+		return fmt.Errorf("event is nil")
+	}
+	if event.Partial {
+		return nil
+	}
+
+	sess, ok := curSession.(*fileSession)
+	if !ok {
+		return fmt.Errorf("unexpected session type %T for session ID %s", curSession, curSession.ID())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.sessions[sess.key]
+	if !ok {
+		return fmt.Errorf("session not found, cannot apply event")
+	}
+
+	sess.appendEvent(event, event.Actions.StateDelta)
+	// Temporary keys live only within the invocation
+	event.Actions.StateDelta = trimTempKeys(event.Actions.StateDelta)
+
+	if err := s.appendRecord(stored.key, record{Type: recordEvent, Time: event.Timestamp, Event: event}); err != nil {
+		return err
+	}
+
+	appDelta, userDelta, sessionDelta := extractStateDeltas(event.Actions.StateDelta)
+	if err := s.updateScopedStateLocked(stored.key, appDelta, userDelta); err != nil {
+		return err
+	}
+	maps.Copy(stored.state, sessionDelta)
+	stored.events = append(stored.events, event)
+	stored.updatedAt = event.Timestamp
+
+	return nil
+}
+
+// Replace atomically replaces the state and events of an existing session: the new
+// session file is written next to the old one and renamed over it, so a failure leaves
+// the old history intact. Events are stored as given; their state deltas are not applied.
+// Used to compact history without a window where the session does not exist.
+func (s *FileService) Replace(ctx context.Context, appName, userID, sessionID string, state map[string]any, events []*session.Event) error {
+	key := sessionKey{appName: appName, userID: userID, sessionID: sessionID}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.sessions[key]
+	if !ok {
+		return fmt.Errorf("session %s not found", sessionID)
+	}
+
+	_, _, sessionState := extractStateDeltas(state)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if err := enc.Encode(record{Type: recordCreate, Time: stored.updatedAt, State: sessionState}); err != nil {
+		return fmt.Errorf("failed to marshal session record: %w", err)
+	}
+	for _, event := range events {
+		if err := enc.Encode(record{Type: recordEvent, Time: event.Timestamp, Event: event}); err != nil {
+			return fmt.Errorf("failed to marshal session record: %w", err)
+		}
+	}
+
+	path := s.sessionPath(key)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace session file: %w", err)
+	}
+
+	stored.state = sessionState
+	stored.events = slices.Clone(events)
+	return nil
+}
+
+// snapshotLocked returns a copy of the stored session with merged app/user state.
+func (s *FileService) snapshotLocked(stored *storedSession, events []*session.Event) *fileSession {
+	state := make(map[string]any, len(stored.state))
+	maps.Copy(state, stored.state)
+	for k, v := range s.appState[stored.key.appName] {
+		state[session.KeyPrefixApp+k] = v
+	}
+	for k, v := range s.userState[stored.key.appName][stored.key.userID] {
+		state[session.KeyPrefixUser+k] = v
+	}
+
+	return &fileSession{
+		key:       stored.key,
+		state:     state,
+		events:    slices.Clone(events),
+		updatedAt: stored.updatedAt,
+	}
+}
+
+// updateScopedStateLocked applies app:/user: state deltas and persists them.
+func (s *FileService) updateScopedStateLocked(key sessionKey, appDelta, userDelta map[string]any) error {
+	if len(appDelta) > 0 {
+		if s.appState[key.appName] == nil {
+			s.appState[key.appName] = make(map[string]any)
+		}
+		maps.Copy(s.appState[key.appName], appDelta)
+		if err := writeJSON(s.appStatePath(key.appName), s.appState[key.appName]); err != nil {
+			return err
+		}
+	}
+
+	if len(userDelta) > 0 {
+		if s.userState[key.appName] == nil {
+			s.userState[key.appName] = make(map[string]map[string]any)
+		}
+		if s.userState[key.appName][key.userID] == nil {
+			s.userState[key.appName][key.userID] = make(map[string]any)
+		}
+		maps.Copy(s.userState[key.appName][key.userID], userDelta)
+		if err := writeJSON(s.userStatePath(key.appName, key.userID), s.userState[key.appName][key.userID]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// appendRecord appends a record line to the session file.
+func (s *FileService) appendRecord(key sessionKey, rec record) error {
+	path := s.sessionPath(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session record: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open session file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	return nil
+}
+
+// load reads all sessions and scoped state from baseDir.
+func (s *FileService) load() error {
+	apps, err := os.ReadDir(s.baseDir)
+	if err != nil {
+		return fmt.Errorf("failed to read session directory: %w", err)
+	}
+
+	for _, app := range apps {
+		if !app.IsDir() {
+			continue
+		}
+		appName := unescapeName(app.Name())
+
+		var appState map[string]any
+		if err := readJSON(s.appStatePath(appName), &appState); err != nil {
+			return err
+		}
+		if appState != nil {
+			s.appState[appName] = appState
+		}
+
+		users, err := os.ReadDir(filepath.Join(s.baseDir, app.Name(), "users"))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read users directory: %w", err)
+		}
+		for _, user := range users {
+			if !user.IsDir() {
+				continue
+			}
+			if err := s.loadUser(appName, unescapeName(user.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *FileService) loadUser(appName, userID string) error {
+	var userState map[string]any
+	if err := readJSON(s.userStatePath(appName, userID), &userState); err != nil {
+		return err
+	}
+	if userState != nil {
+		if s.userState[appName] == nil {
+			s.userState[appName] = make(map[string]map[string]any)
+		}
+		s.userState[appName][userID] = userState
+	}
+
+	dir := filepath.Join(s.baseDir, escapeName(appName), "users", escapeName(userID), "sessions")
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read sessions directory: %w", err)
+	}
+
+	for _, file := range files {
+		name, ok := strings.CutSuffix(file.Name(), ".jsonl")
+		if !ok || file.IsDir() {
+			continue
+		}
+		key := sessionKey{appName: appName, userID: userID, sessionID: unescapeName(name)}
+		stored, err := loadSessionFile(filepath.Join(dir, file.Name()), key)
+		if err != nil {
+			return err
+		}
+		s.sessions[key] = stored
+	}
+	return nil
+}
+
+// loadSessionFile replays a session file. A torn last line (crash during write) is dropped
+// and cut off the file, so the next append starts on a fresh line; a corrupt line
+// anywhere else is an error.
+func loadSessionFile(path string, key sessionKey) (*storedSession, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session file %s: %w", path, err)
+	}
+
+	stored := &storedSession{key: key, state: make(map[string]any)}
+
+	offset := 0 // end of the last complete record
+	for line := 1; offset < len(data); line++ {
+		raw, rest, terminated := bytes.Cut(data[offset:], []byte{'\n'})
+		last := len(rest) == 0
+
+		var rec record
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			if !last {
+				return nil, fmt.Errorf("session file %s line %d is corrupt: %w", path, line, err)
+			}
+			if err := os.Truncate(path, int64(offset)); err != nil {
+				return nil, fmt.Errorf("failed to cut torn record off session file %s: %w", path, err)
+			}
+			break
+		}
+		switch rec.Type {
+		case recordCreate:
+			maps.Copy(stored.state, rec.State)
+			stored.updatedAt = rec.Time
+		case recordEvent:
+			if rec.Event != nil {
+				_, _, sessionDelta := extractStateDeltas(rec.Event.Actions.StateDelta)
+				maps.Copy(stored.state, sessionDelta)
+				stored.events = append(stored.events, rec.Event)
+				stored.updatedAt = rec.Event.Timestamp
+			}
+		default:
+			return nil, fmt.Errorf("session file %s line %d: unknown record type %q", path, line, rec.Type)
+		}
+
+		offset += len(raw) + 1
+		if !terminated {
+			// The record is whole but its newline was lost: restore it before appending
+			if err := appendNewline(path); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return stored, nil
+}
+
+func appendNewline(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open session file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte{'\n'}); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	return nil
+}
+
+func (s *FileService) sessionPath(key sessionKey) string {
+	return filepath.Join(s.baseDir, escapeName(key.appName), "users", escapeName(key.userID), "sessions", escapeName(key.sessionID)+".jsonl")
+}
+
+func (s *FileService) appStatePath(appName string) string {
+	return filepath.Join(s.baseDir, escapeName(appName), "app_state.json")
+}
+
+func (s *FileService) userStatePath(appName, userID string) string {
+	return filepath.Join(s.baseDir, escapeName(appName), "users", escapeName(userID), "user_state.json")
+}
+
+// extractStateDeltas splits a state delta into app, user and session parts
+// (prefixes stripped for app/user, temp: keys dropped).
+func extractStateDeltas(delta map[string]any) (appDelta, userDelta, sessionDelta map[string]any) {
+	appDelta = make(map[string]any)
+	userDelta = make(map[string]any)
+	sessionDelta = make(map[string]any)
+	for key, value := range delta {
+		if k, ok := strings.CutPrefix(key, session.KeyPrefixApp); ok {
+			appDelta[k] = value
+		} else if k, ok := strings.CutPrefix(key, session.KeyPrefixUser); ok {
+			userDelta[k] = value
+		} else if !strings.HasPrefix(key, session.KeyPrefixTemp) {
+			sessionDelta[key] = value
+		}
+	}
+	return appDelta, userDelta, sessionDelta
+}
+
+func trimTempKeys(delta map[string]any) map[string]any {
+	if len(delta) == 0 {
+		return delta
+	}
+	filtered := make(map[string]any, len(delta))
+	for k, v := range delta {
+		if !strings.HasPrefix(k, session.KeyPrefixTemp) {
+			filtered[k] = v
+		}
+	}
+	return filtered
+}
+
+func writeJSON(path string, v any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	// Write to a temp file and rename, so a crash never leaves a half-written state file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return nil
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal state file %s: %w", path, err)
+	}
+	return nil
+}
+
+// checkNames rejects IDs that cannot be stored as file names: empty, "." and "..".
+// Other characters, including path separators, are escaped by escapeName.
+func checkNames(names ...string) error {
+	for _, name := range names {
+		if name == "" || name == "." || name == ".." {
+			return fmt.Errorf("invalid name %q: empty, \".\" and \"..\" are not allowed", name)
+		}
+	}
+	return nil
+}
+
+// escapeName makes an ID safe to use as a file name.
+func escapeName(name string) string {
+	return url.PathEscape(name)
+}
+
+func unescapeName(name string) string {
+	if n, err := url.PathUnescape(name); err == nil {
+		return n
+	}
+	return name
+}
+
+var _ session.Service = (*FileService)(nil)
@@ -0,0 +1,216 @@
+package sessionstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/session"
+)
+
+func newTestEvent(author string, delta map[string]any) *session.Event {
+	event := session.NewEvent("invocation")
+	event.Author = author
+	event.Timestamp = time.Now()
+	event.Actions.StateDelta = delta
+	return event
+}
+
+func createWithEvents(t *testing.T, svc *FileService, userID, sessionID string, events ...*session.Event) {
+	t.Helper()
+	ctx := context.Background()
+	resp, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: userID, SessionID: sessionID, State: map[string]any{"k": "v"}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for _, event := range events {
+		if err := svc.AppendEvent(ctx, resp.Session, event); err != nil {
+			t.Fatalf("AppendEvent: %v", err)
+		}
+	}
+}
+
+func loadEvents(t *testing.T, svc *FileService, userID, sessionID string) session.Session {
+	t.Helper()
+	resp, err := svc.Get(context.Background(), &session.GetRequest{AppName: "app", UserID: userID, SessionID: sessionID})
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	return resp.Session
+}
+
+func TestFileServiceRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	svc, err := NewFileService(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createWithEvents(t, svc, "42", "s1",
+		newTestEvent("user", map[string]any{"step": 1.0, "user:lang": "ru", "temp:x": 1.0}),
+		newTestEvent("agent", nil),
+	)
+
+	reloaded, err := NewFileService(dir)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	sess := loadEvents(t, reloaded, "42", "s1")
+	if n := sess.Events().Len(); n != 2 {
+		t.Fatalf("events = %d, want 2", n)
+	}
+	for key, want := range map[string]any{"k": "v", "step": 1.0, "user:lang": "ru"} {
+		if got, err := sess.State().Get(key); err != nil || got != want {
+			t.Errorf("state %s = %v, %v; want %v", key, got, err, want)
+		}
+	}
+	if _, err := sess.State().Get("temp:x"); err == nil {
+		t.Error("temp: key was persisted")
+	}
+}
+
+func TestFileServiceTornTail(t *testing.T) {
+	dir := t.TempDir()
+	svc, err := NewFileService(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createWithEvents(t, svc, "42", "s1", newTestEvent("user", nil))
+
+	path := svc.sessionPath(sessionKey{appName: "app", userID: "42", sessionID: "s1"})
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"type":"event","time":"2024-`)
+	f.Close()
+
+	reloaded, err := NewFileService(dir)
+	if err != nil {
+		t.Fatalf("torn tail must be dropped: %v", err)
+	}
+	sess := loadEvents(t, reloaded, "42", "s1")
+	if n := sess.Events().Len(); n != 1 {
+		t.Fatalf("events = %d, want 1", n)
+	}
+
+	// The next record must not be glued onto the torn line
+	if err := reloaded.AppendEvent(context.Background(), sess, newTestEvent("agent", nil)); err != nil {
+		t.Fatal(err)
+	}
+	again, err := NewFileService(dir)
+	if err != nil {
+		t.Fatalf("reload after append: %v", err)
+	}
+	if n := loadEvents(t, again, "42", "s1").Events().Len(); n != 2 {
+		t.Fatalf("events after append = %d, want 2", n)
+	}
+}
+
+func TestFileServiceCorruptLine(t *testing.T) {
+	dir := t.TempDir()
+	svc, err := NewFileService(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createWithEvents(t, svc, "42", "s1", newTestEvent("user", nil), newTestEvent("agent", nil))
+
+	path := svc.sessionPath(sessionKey{appName: "app", userID: "42", sessionID: "s1"})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)/2] = '\n' // break a record in the middle of the file
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFileService(dir); err == nil {
+		t.Fatal("corrupt record in the middle must fail loading")
+	}
+}
+
+func TestFileServiceNames(t *testing.T) {
+	dir := t.TempDir()
+	svc, err := NewFileService(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, name := range []string{".", ".."} {
+		if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: name, SessionID: "s"}); err == nil {
+			t.Errorf("user %q accepted", name)
+		}
+		if _, err := svc.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "42", SessionID: name}); err == nil {
+			t.Errorf("session %q accepted", name)
+		}
+		if err := svc.Delete(ctx, &session.DeleteRequest{AppName: "app", UserID: "42", SessionID: name}); err == nil {
+			t.Errorf("delete of session %q accepted", name)
+		}
+	}
+
+	createWithEvents(t, svc, "../chat/1", "a/../b", newTestEvent("user", nil))
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if rel, _ := filepath.Rel(dir, path); filepath.Dir(rel) != filepath.Join("app", "users", "..%2Fchat%2F1", "sessions") && filepath.Base(rel) != "user_state.json" {
+				t.Errorf("unexpected file %s", rel)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewFileService(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := loadEvents(t, reloaded, "../chat/1", "a/../b").Events().Len(); n != 1 {
+		t.Fatalf("events = %d, want 1", n)
+	}
+}
+
+func TestFileServiceReplace(t *testing.T) {
+	dir := t.TempDir()
+	svc, err := NewFileService(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createWithEvents(t, svc, "42", "s1", newTestEvent("user", nil), newTestEvent("agent", nil), newTestEvent("user", nil))
+
+	kept := newTestEvent("user", nil)
+	if err := svc.Replace(context.Background(), "app", "42", "s1", map[string]any{"summary": "кратко"}, []*session.Event{kept}); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	if err := svc.Replace(context.Background(), "app", "42", "missing", nil, nil); err == nil {
+		t.Error("Replace of a missing session must fail")
+	}
+
+	for _, s := range []*FileService{svc, mustReload(t, dir)} {
+		sess := loadEvents(t, s, "42", "s1")
+		if n := sess.Events().Len(); n != 1 {
+			t.Errorf("events = %d, want 1", n)
+		}
+		if v, _ := sess.State().Get("summary"); v != "кратко" {
+			t.Errorf("summary = %v", v)
+		}
+		if _, err := sess.State().Get("k"); err == nil {
+			t.Error("old state was not replaced")
+		}
+	}
+	if _, err := os.Stat(svc.sessionPath(sessionKey{appName: "app", userID: "42", sessionID: "s1"}) + ".tmp"); !os.IsNotExist(err) {
+		t.Error("temp file left behind")
+	}
+}
+
+func mustReload(t *testing.T, dir string) *FileService {
+	t.Helper()
+	svc, err := NewFileService(dir)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	return svc
+}
@@ -0,0 +1,111 @@
+package sessionstore
+
+import (
+	"iter"
+	"maps"
+	"sync"
+	"time"
+
+	"google.golang.org/adk/session"
+)
+
+// fileSession is a session snapshot returned by FileService.
+// The runner mutates it through AppendEvent during an invocation.
+type fileSession struct {
+	key sessionKey
+
+	mu        sync.RWMutex
+	state     map[string]any
+	events    []*session.Event
+	updatedAt time.Time
+}
+
+func (s *fileSession) ID() string      { return s.key.sessionID }
+func (s *fileSession) AppName() string { return s.key.appName }
+func (s *fileSession) UserID() string  { return s.key.userID }
+
+func (s *fileSession) State() session.State {
+	return &fileState{mu: &s.mu, state: s.state}
+}
+
+func (s *fileSession) Events() session.Events {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fileEvents(s.events)
+}
+
+func (s *fileSession) LastUpdateTime() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.updatedAt
+}
+
+// appendEvent applies the full state delta (including temp: keys, which stay
+// visible for the rest of the invocation) and records the event.
+func (s *fileSession) appendEvent(event *session.Event, delta map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	maps.Copy(s.state, delta)
+	s.events = append(s.events, event)
+	s.updatedAt = event.Timestamp
+}
+
+type fileEvents []*session.Event
+
+func (e fileEvents) All() iter.Seq[*session.Event] {
+	return func(yield func(*session.Event) bool) {
+		for _, event := range e {
+			if !yield(event) {
+				return
+			}
+		}
+	}
+}
+
+func (e fileEvents) Len() int { return len(e) }
+
+func (e fileEvents) At(i int) *session.Event {
+	if i >= 0 && i < len(e) {
+		return e[i]
+	}
+	return nil
+}
+
+type fileState struct {
+	mu    *sync.RWMutex
+	state map[string]any
+}
+
+func (s *fileState) Get(key string) (any, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	val, ok := s.state[key]
+	if !ok {
+		return nil, session.ErrStateKeyNotExist
+	}
+	return val, nil
+}
+
+func (s *fileState) All() iter.Seq2[string, any] {
+	s.mu.RLock()
+	stateCopy := maps.Clone(s.state)
+	s.mu.RUnlock()
+
+	return func(yield func(string, any) bool) {
+		for k, v := range stateCopy {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+func (s *fileState) Set(key string, value any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state[key] = value
+	return nil
+}
@@ -0,0 +1,27 @@
+// Package sessionstore provides ADK session.Service implementations that survive restarts.
+package sessionstore
+
+import (
+	"fmt"
+
+	"google.golang.org/adk/session"
+
+	"github.com/tihn/amo-ai-tgbot-go/config"
+)
+
+// New creates a session service from application config.
+// "memory" keeps sessions in process memory, "file" stores them under cfg.SessionDir.
+func New(cfg *config.Config) (session.Service, error) {
+	switch cfg.SessionStorage {
+	case config.SessionStorageMemory:
+		return session.InMemoryService(), nil
+	case config.SessionStorageFile:
+		svc, err := NewFileService(cfg.SessionDir)
+		if err != nil {
+			return nil, fmt.Errorf("sessionstore: %w", err)
+		}
+		return svc, nil
+	default:
+		return nil, fmt.Errorf("sessionstore: unknown SESSION_STORAGE %q (expected: memory, file)", cfg.SessionStorage)
+	}
+}
@@ -0,0 +1,108 @@
+// Package stt transcribes speech through an OpenAI-compatible /audio/transcriptions endpoint:
+// OpenAI, whisper.cpp server, faster-whisper-server and others that accept the same form.
+package stt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultTimeout bounds a transcription; a few minutes of audio take seconds on a GPU
+	// but may take a minute on a CPU-only whisper.cpp.
+	defaultTimeout = 2 * time.Minute
+	// maxErrorBody is how much of an error response is included in the error.
+	maxErrorBody = 1024
+)
+
+// Config configures the transcription endpoint.
+type Config struct {
+	URL      string // full endpoint URL, e.g. https://api.openai.com/v1/audio/transcriptions
+	APIKey   string // sent as a Bearer token, empty for local servers
+	Model    string // e.g. "whisper-1"; local servers usually ignore it
+	Language string // ISO-639-1 hint, e.g. "ru"; empty — detect automatically
+	Timeout  time.Duration
+}
+
+// Client calls the transcription endpoint.
+type Client struct {
+	cfg  Config
+	http *http.Client
+}
+
+// New validates the config.
+func New(cfg Config) (*Client, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("stt: URL must be an absolute http(s) URL, got %q", cfg.URL)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	return &Client{cfg: cfg, http: &http.Client{Timeout: cfg.Timeout}}, nil
+}
+
+// Transcribe implements speech.Transcriber.
+func (c *Client) Transcribe(ctx context.Context, audio io.Reader, filename string) (string, error) {
+	// Voice notes are small (Telegram bots download up to 20 MB), so the form is built in memory
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return "", fmt.Errorf("stt: build request: %w", err)
+	}
+	if _, err := io.Copy(part, audio); err != nil {
+		return "", fmt.Errorf("stt: read audio: %w", err)
+	}
+	fields := map[string]string{
+		"model":           c.cfg.Model,
+		"language":        c.cfg.Language,
+		"response_format": "json",
+	}
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := form.WriteField(name, value); err != nil {
+			return "", fmt.Errorf("stt: build request: %w", err)
+		}
+	}
+	if err := form.Close(); err != nil {
+		return "", fmt.Errorf("stt: build request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.URL, &body)
+	if err != nil {
+		return "", fmt.Errorf("stt: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if c.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("stt: request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return "", fmt.Errorf("stt: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("stt: decode response: %w", err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
@@ -0,0 +1,175 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	// shutdownTimeout bounds how long shutdown waits for in-flight HTTP requests
+	// and for handlers that are still processing updates (AI replies may take a while).
+	shutdownTimeout = 30 * time.Second
+
+	// secretTokenHeader carries the secret token given to SetWebhook.
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+	// maxUpdateSize limits the request body; updates are JSON of a few kilobytes.
+	maxUpdateSize = 1 << 20
+)
+
+// secretTokenRe matches the characters Telegram allows in a webhook secret token.
+var secretTokenRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// WebhookConfig configures receiving updates via webhook.
+type WebhookConfig struct {
+	URL    string // public HTTPS URL registered with SetWebhook; the server handles its path
+	Listen string // address of the HTTP server, e.g. ":8081"
+	Secret string // secret token Telegram sends in X-Telegram-Bot-Api-Secret-Token
+}
+
+// Webhook receives updates over HTTP instead of long polling.
+// Several replicas may serve the same webhook URL behind a load balancer.
+//
+// Updates are passed to the bot's handlers right from the HTTP request, bypassing
+// the bot's buffered updates channel: an update Telegram got 200 OK for is already
+// counted as in flight and shutdown waits for it, none is left in a buffer.
+type Webhook struct {
+	cfg  WebhookConfig
+	path string
+
+	inflight sync.WaitGroup
+}
+
+// NewWebhook validates the config.
+func NewWebhook(cfg WebhookConfig) (*Webhook, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("telegram webhook: URL must be an absolute https URL, got %q", cfg.URL)
+	}
+	if cfg.Listen == "" {
+		return nil, errors.New("telegram webhook: listen address is required")
+	}
+	if !secretTokenRe.MatchString(cfg.Secret) {
+		return nil, errors.New("telegram webhook: secret token is required (1-256 characters A-Z, a-z, 0-9, _ and -)")
+	}
+
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	return &Webhook{cfg: cfg, path: path}, nil
+}
+
+// Middleware marks an update as processed when its handler returns; the update was
+// counted as in flight by the HTTP handler before it was handed to the bot.
+// It is required in webhook mode: pass it to bot.New with bot.WithMiddlewares.
+func (w *Webhook) Middleware() bot.Middleware {
+	return func(next bot.HandlerFunc) bot.HandlerFunc {
+		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			defer w.inflight.Done()
+			next(ctx, b, update)
+		}
+	}
+}
+
+// Run registers the webhook with Telegram and serves updates until ctx is cancelled.
+// On shutdown it stops accepting requests, waits for in-flight handlers
+// (up to shutdownTimeout) and only then cancels their context.
+func (w *Webhook) Run(ctx context.Context, b *bot.Bot) error {
+	// Handlers outlive ctx during shutdown, so they get their own context
+	botCtx, stopBot := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopBot()
+
+	mux := http.NewServeMux()
+	mux.Handle("POST "+w.path, w.checkSecret(w.updateHandler(botCtx, b)))
+	mux.HandleFunc("GET /healthz", func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+	srv := &http.Server{
+		Addr:              w.cfg.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	// Registering on every start is idempotent, so replicas may do it concurrently
+	if _, err := b.SetWebhook(ctx, &bot.SetWebhookParams{
+		URL:         w.cfg.URL,
+		SecretToken: w.cfg.Secret,
+	}); err != nil {
+		_ = srv.Close()
+		return fmt.Errorf("telegram webhook: set webhook: %w", err)
+	}
+	log.Printf("Telegram webhook registered: %s (listening on %s)", w.cfg.URL, w.cfg.Listen)
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("telegram webhook: serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	log.Print("Telegram webhook: shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Telegram webhook: HTTP shutdown: %v", err)
+	}
+
+	handlersDone := make(chan struct{})
+	go func() {
+		w.inflight.Wait()
+		close(handlersDone)
+	}()
+	select {
+	case <-handlersDone:
+	case <-shutdownCtx.Done():
+		log.Print("Telegram webhook: shutdown timeout, cancelling in-flight handlers")
+	}
+
+	stopBot()
+	return nil
+}
+
+// updateHandler decodes an update and starts its handler. The update is counted
+// as in flight before the response, so shutdown, which waits for the HTTP server first,
+// never misses an update Telegram considers delivered.
+func (w *Webhook) updateHandler(ctx context.Context, b *bot.Bot) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var update models.Update
+		if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxUpdateSize)).Decode(&update); err != nil {
+			log.Printf("Telegram webhook: bad update: %v", err)
+			http.Error(rw, "bad update", http.StatusBadRequest)
+			return
+		}
+		w.inflight.Add(1)
+		b.ProcessUpdate(ctx, &update) // runs the handler in a goroutine, Middleware calls Done
+	})
+}
+
+// checkSecret rejects requests without the secret token, so that only Telegram can post updates.
+func (w *Webhook) checkSecret(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get(secretTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(got), []byte(w.cfg.Secret)) != 1 {
+			http.Error(rw, "invalid secret token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
@@ -0,0 +1,100 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func TestCheckSecret(t *testing.T) {
+	w := &Webhook{cfg: WebhookConfig{Secret: "s3cret_token"}}
+	next := http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+	h := w.checkSecret(next)
+
+	tests := []struct {
+		name   string
+		header string
+		set    bool
+		want   int
+	}{
+		{name: "missing", want: http.StatusUnauthorized},
+		{name: "empty", header: "", set: true, want: http.StatusUnauthorized},
+		{name: "wrong", header: "other", set: true, want: http.StatusUnauthorized},
+		{name: "prefix", header: "s3cret", set: true, want: http.StatusUnauthorized},
+		{name: "longer", header: "s3cret_token_", set: true, want: http.StatusUnauthorized},
+		{name: "valid", header: "s3cret_token", set: true, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader("{}"))
+			if tt.set {
+				req.Header.Set(secretTokenHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateHandlerCountsInflight(t *testing.T) {
+	w := &Webhook{}
+	release := make(chan struct{})
+	started := make(chan int64, 1)
+	b, err := bot.New("test-token",
+		bot.WithSkipGetMe(),
+		bot.WithMiddlewares(w.Middleware()),
+		bot.WithDefaultHandler(func(_ context.Context, _ *bot.Bot, update *models.Update) {
+			started <- update.ID
+			<-release
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := w.updateHandler(context.Background(), b)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(`{"update_id": 42}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+
+	// The update is in flight as soon as the response is written
+	done := make(chan struct{})
+	go func() {
+		w.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("shutdown would not wait for the update")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if id := <-started; id != 42 {
+		t.Errorf("update ID = %d", id)
+	}
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler finished but the update is still in flight")
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(`{"update_id":`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("bad update: status = %d", rec.Code)
+	}
+}
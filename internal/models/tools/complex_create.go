@@ -24,7 +24,7 @@ type LeadData struct {
 	PipelineName        string         `json:"pipeline_name,omitempty" jsonschema_description:"Название воронки"`
 	StatusName          string         `json:"status_name,omitempty" jsonschema_description:"Название статуса в воронке"`
 	ResponsibleUserName string         `json:"responsible_user_name,omitempty" jsonschema_description:"Имя ответственного пользователя"`
-	CustomFieldsValues  map[string]any `json:"custom_fields_values,omitempty" jsonschema_description:"Кастомные поля сделки. Формат: {field_id: [{value: значение}]}"`
+	CustomFieldsValues  map[string]any `json:"custom_fields_values,omitempty" jsonschema_description:"Кастомные поля сделки: {название, код или ID поля: значение}"`
 	Tags                []string       `json:"tags,omitempty" jsonschema_description:"Теги сделки (названия)"`
 }
 
@@ -37,12 +37,12 @@ type ContactData struct {
 	Email               string         `json:"email,omitempty" jsonschema_description:"Email (будет добавлен как кастомное поле EMAIL)"`
 	IsMain              bool           `json:"is_main,omitempty" jsonschema_description:"Основной контакт"`
 	ResponsibleUserName string         `json:"responsible_user_name,omitempty" jsonschema_description:"Имя ответственного пользователя"`
-	CustomFieldsValues  map[string]any `json:"custom_fields_values,omitempty" jsonschema_description:"Прочие кастомные поля контакта"`
+	CustomFieldsValues  map[string]any `json:"custom_fields_values,omitempty" jsonschema_description:"Прочие кастомные поля контакта: {название, код или ID поля: значение}"`
 }
 
 // CompanyData данные компании
 type CompanyData struct {
 	Name                string         `json:"name" jsonschema_description:"Название компании"`
 	ResponsibleUserName string         `json:"responsible_user_name,omitempty" jsonschema_description:"Имя ответственного пользователя"`
-	CustomFieldsValues  map[string]any `json:"custom_fields_values,omitempty" jsonschema_description:"Кастомные поля компании"`
+	CustomFieldsValues  map[string]any `json:"custom_fields_values,omitempty" jsonschema_description:"Кастомные поля компании: {название, код или ID поля: значение}"`
 }
@@ -78,7 +78,7 @@ type EntitiesFilter struct {
 
 // CustomFieldFilter фильтр по кастомному полю
 type CustomFieldFilter struct {
-	FieldCode string `json:"field_code" jsonschema_description:"Символьный код кастомного поля (например PHONE, EMAIL, UTM_SOURCE)"`
+	FieldCode string   `json:"field_code" jsonschema_description:"Название, символьный код (например PHONE, UTM_SOURCE) или ID кастомного поля"`
 	Values    []string `json:"values" jsonschema_description:"Значения для фильтрации"`
 }
 
@@ -101,7 +101,7 @@ type EntityData struct {
 	FirstName string `json:"first_name,omitempty" jsonschema_description:"Имя контакта (только contacts)"`
 	LastName  string `json:"last_name,omitempty" jsonschema_description:"Фамилия контакта (только contacts)"`
 
-	CustomFieldsValues map[string]any `json:"custom_fields_values,omitempty" jsonschema_description:"Значения кастомных полей. Ключ — code поля (например PHONE), значение — строка или массив {value, enum_code}"`
+	CustomFieldsValues map[string]any `json:"custom_fields_values,omitempty" jsonschema_description:"Значения кастомных полей. Ключ — название, код (например PHONE) или ID поля; значение приводится к типу поля: вариант списка по названию, дата, да/нет, число, массив {value, enum_code} для телефонов и email"`
 	Tags               []EntityTag    `json:"tags,omitempty" jsonschema_description:"Теги сущности"`
 
 	// Embedded связанные сущности (для create с привязкой)
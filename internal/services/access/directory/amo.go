@@ -0,0 +1,36 @@
+// Package directory provides access.UserDirectory implementations.
+package directory
+
+import (
+	"context"
+	"strings"
+
+	gkitmodels "github.com/tihn/amo-ai-tgbot-go/internal/models/tools"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/access"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/crm/admin_users"
+)
+
+// amoDirectory finds amoCRM users through the admin_users service.
+type amoDirectory struct {
+	users admin_users.Service
+}
+
+// NewAmo creates an access.UserDirectory backed by amoCRM users.
+func NewAmo(users admin_users.Service) access.UserDirectory {
+	return &amoDirectory{users: users}
+}
+
+// FindByEmail implements access.UserDirectory.
+func (d *amoDirectory) FindByEmail(ctx context.Context, email string) (*access.AmoUser, error) {
+	// The service filters by email substring; the exact match is checked here
+	result, err := d.users.ListUsers(ctx, &gkitmodels.AdminUsersFilter{Email: email, Limit: 250})
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range result.Items {
+		if strings.EqualFold(strings.TrimSpace(u.Email), strings.TrimSpace(email)) {
+			return &access.AmoUser{ID: u.ID, Name: u.Name, Email: u.Email}, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,30 @@
+package access
+
+import "fmt"
+
+// Role is a permission profile of a bot user. Which tools and actions each role
+// may use is defined by the tool policies of the agent.
+type Role string
+
+const (
+	// RoleReadOnly may only search and read CRM data.
+	RoleReadOnly Role = "readonly"
+	// RoleSales works with deals, contacts and tasks but cannot delete
+	// or change account settings, and may update only their own records.
+	RoleSales Role = "sales"
+	// RoleAdmin has full access.
+	RoleAdmin Role = "admin"
+)
+
+// Roles lists all roles from the least to the most privileged.
+var Roles = []Role{RoleReadOnly, RoleSales, RoleAdmin}
+
+// ParseRole validates a role name.
+func ParseRole(s string) (Role, error) {
+	for _, r := range Roles {
+		if string(r) == s {
+			return r, nil
+		}
+	}
+	return "", fmt.Errorf("unknown role %q (expected: %s, %s, %s)", s, RoleReadOnly, RoleSales, RoleAdmin)
+}
@@ -0,0 +1,383 @@
+// Package access controls who may use the bot and binds Telegram users to amoCRM users.
+package access
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotAllowed is returned for operations on users outside the allowlist.
+	ErrNotAllowed = errors.New("user is not in the allowlist")
+	// ErrAmoUserNotFound is returned when no amoCRM user has the given email.
+	ErrAmoUserNotFound = errors.New("amoCRM user with this email not found")
+	// ErrConfigAdmin is returned on attempts to remove or demote an admin listed in config.
+	ErrConfigAdmin = errors.New("admin from ACCESS_ADMINS cannot be changed")
+	// ErrNoUsers is returned by New when nobody could use the bot.
+	ErrNoUsers = errors.New("nobody may use the bot: set ACCESS_ADMINS or ACCESS_ALLOWLIST")
+)
+
+// Member is an allowlisted Telegram user.
+type Member struct {
+	TelegramUserID int64     `json:"telegram_user_id"`
+	AmoUserID      int       `json:"amo_user_id,omitempty"`
+	AmoUserName    string    `json:"amo_user_name,omitempty"`
+	AmoEmail       string    `json:"amo_email,omitempty"`
+	Role           Role      `json:"role,omitempty"`     // empty — Config.DefaultRole
+	AddedBy        int64     `json:"added_by,omitempty"` // 0 — from config
+	AddedAt        time.Time `json:"added_at"`
+	// FromConfig records only keep the role and binding of a user allowed by
+	// ACCESS_ADMINS or ACCESS_ALLOWLIST: they do not allow the user by themselves,
+	// so removing the user from config takes access away.
+	FromConfig bool `json:"from_config,omitempty"`
+	Admin      bool `json:"-"`
+}
+
+// Bound reports whether the member is bound to an amoCRM user.
+func (m Member) Bound() bool {
+	return m.AmoUserID != 0
+}
+
+// AmoUser is an amoCRM user a Telegram user can be bound to.
+type AmoUser struct {
+	ID    int
+	Name  string
+	Email string
+}
+
+// UserDirectory looks up amoCRM users.
+type UserDirectory interface {
+	// FindByEmail returns the user with exactly this email (case-insensitive) or nil.
+	FindByEmail(ctx context.Context, email string) (*AmoUser, error)
+}
+
+// Config holds static access settings.
+type Config struct {
+	Path      string  // JSON file with members added by admin commands
+	Admins    []int64 // always allowed, may manage the allowlist
+	Allowlist []int64 // always allowed
+
+	// DefaultRole applies to members without an explicit role; admins always have RoleAdmin
+	DefaultRole Role
+}
+
+// Service keeps the allowlist and amoCRM bindings.
+// Members added by admins are persisted to Config.Path; admins and the static
+// allowlist come from config and cannot be removed with commands.
+type Service struct {
+	path        string
+	admins      map[int64]bool
+	static      map[int64]bool
+	defaultRole Role
+	directory   UserDirectory
+
+	mu      sync.RWMutex
+	members map[int64]*Member
+}
+
+// New loads the allowlist file (a missing file means no members yet).
+// It fails with ErrNoUsers when there are no admins, no static allowlist and no
+// stored members: such a bot would reject everyone and could not be fixed from Telegram.
+func New(cfg Config, directory UserDirectory) (*Service, error) {
+	s := &Service{
+		path:        cfg.Path,
+		admins:      make(map[int64]bool),
+		static:      make(map[int64]bool),
+		defaultRole: cfg.DefaultRole,
+		directory:   directory,
+		members:     make(map[int64]*Member),
+	}
+	if s.defaultRole == "" {
+		s.defaultRole = RoleSales
+	}
+	for _, id := range cfg.Admins {
+		s.admins[id] = true
+	}
+	for _, id := range cfg.Allowlist {
+		s.static[id] = true
+	}
+
+	data, err := os.ReadFile(cfg.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("access: read %s: %w", cfg.Path, err)
+	}
+	if err == nil {
+		var members []*Member
+		if err := json.Unmarshal(data, &members); err != nil {
+			return nil, fmt.Errorf("access: parse %s: %w", cfg.Path, err)
+		}
+		for _, m := range members {
+			// Older files have no from_config: records that nobody added with /allow
+			// were created by /role or /bind for users from config
+			if m.AddedBy == 0 || m.AddedBy == m.TelegramUserID {
+				m.FromConfig = true
+			}
+			s.members[m.TelegramUserID] = m
+		}
+	}
+	if len(s.admins) == 0 && len(s.static) == 0 && len(s.Members()) == 0 {
+		return nil, ErrNoUsers
+	}
+	return s, nil
+}
+
+// ParseIDs parses a comma-separated list of Telegram user IDs.
+func ParseIDs(s string) ([]int64, error) {
+	var ids []int64
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Telegram user ID %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// IsAllowed reports whether the Telegram user may use the bot.
+func (s *Service) IsAllowed(telegramUserID int64) bool {
+	if s.configured(telegramUserID) {
+		return true
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m, ok := s.members[telegramUserID]
+	return ok && !m.FromConfig
+}
+
+// configured reports whether config allows the user: ACCESS_ADMINS or ACCESS_ALLOWLIST.
+func (s *Service) configured(telegramUserID int64) bool {
+	return s.admins[telegramUserID] || s.static[telegramUserID]
+}
+
+// IsAdmin reports whether the Telegram user may manage the allowlist.
+func (s *Service) IsAdmin(telegramUserID int64) bool {
+	return s.admins[telegramUserID]
+}
+
+// Member returns the allowlist entry of a user. ok is false for users outside the allowlist.
+func (s *Service) Member(telegramUserID int64) (Member, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.memberLocked(telegramUserID)
+}
+
+func (s *Service) memberLocked(telegramUserID int64) (Member, bool) {
+	var m Member
+	stored, ok := s.members[telegramUserID]
+	switch {
+	case ok && (!stored.FromConfig || s.configured(telegramUserID)):
+		m = *stored
+	case s.configured(telegramUserID):
+		m = Member{TelegramUserID: telegramUserID}
+	default:
+		return Member{}, false
+	}
+
+	m.Admin = s.admins[telegramUserID]
+	switch {
+	case m.Admin:
+		m.Role = RoleAdmin
+	case m.Role == "":
+		m.Role = s.defaultRole
+	}
+	return m, true
+}
+
+// Members returns all allowed users, including those from config, ordered by Telegram ID.
+func (s *Service) Members() []Member {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]int64, 0, len(s.members)+len(s.admins)+len(s.static))
+	for id := range s.members {
+		ids = append(ids, id)
+	}
+	for id := range s.admins {
+		ids = append(ids, id)
+	}
+	for id := range s.static {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	result := make([]Member, 0, len(ids))
+	for _, id := range ids {
+		// Records of users since removed from config are skipped
+		if m, ok := s.memberLocked(id); ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// Allow adds a user to the allowlist. If email is set, the user is also bound
+// to the amoCRM user with that email. Adding an existing member updates the binding.
+// A user allowed by config becomes a member of their own and keeps access without it.
+func (s *Service) Allow(ctx context.Context, telegramUserID, addedBy int64, email string) (Member, error) {
+	var amoUser *AmoUser
+	if email != "" {
+		var err error
+		if amoUser, err = s.findAmoUser(ctx, email); err != nil {
+			return Member{}, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.members[telegramUserID]
+	if !ok || m.FromConfig {
+		if !ok {
+			m = &Member{TelegramUserID: telegramUserID}
+			s.members[telegramUserID] = m
+		}
+		m.AddedBy, m.AddedAt, m.FromConfig = addedBy, time.Now(), false
+	}
+	if amoUser != nil {
+		m.AmoUserID, m.AmoUserName, m.AmoEmail = amoUser.ID, amoUser.Name, amoUser.Email
+	}
+	if err := s.saveLocked(); err != nil {
+		return Member{}, err
+	}
+	member, _ := s.memberLocked(telegramUserID)
+	return member, nil
+}
+
+// Deny removes a user from the allowlist together with the amoCRM binding.
+func (s *Service) Deny(telegramUserID int64) error {
+	if s.admins[telegramUserID] {
+		return ErrConfigAdmin
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if m, ok := s.members[telegramUserID]; !ok || m.FromConfig {
+		if s.static[telegramUserID] {
+			return fmt.Errorf("user %d is listed in ACCESS_ALLOWLIST, remove them from config", telegramUserID)
+		}
+		return ErrNotAllowed
+	}
+	delete(s.members, telegramUserID)
+	return s.saveLocked()
+}
+
+// SetRole changes the role of an allowed user.
+func (s *Service) SetRole(telegramUserID int64, role Role) (Member, error) {
+	if s.admins[telegramUserID] {
+		return Member{}, ErrConfigAdmin
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.recordLocked(telegramUserID)
+	if err != nil {
+		return Member{}, err
+	}
+	m.Role = role
+	if err := s.saveLocked(); err != nil {
+		return Member{}, err
+	}
+	member, _ := s.memberLocked(telegramUserID)
+	return member, nil
+}
+
+// Bind binds an allowed user to the amoCRM user with the given email.
+// The caller is responsible for verifying that the email belongs to the user.
+func (s *Service) Bind(ctx context.Context, telegramUserID int64, email string) (Member, error) {
+	if !s.IsAllowed(telegramUserID) {
+		return Member{}, ErrNotAllowed
+	}
+	amoUser, err := s.findAmoUser(ctx, email)
+	if err != nil {
+		return Member{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := s.recordLocked(telegramUserID)
+	if err != nil {
+		return Member{}, err
+	}
+	m.AmoUserID, m.AmoUserName, m.AmoEmail = amoUser.ID, amoUser.Name, amoUser.Email
+	if err := s.saveLocked(); err != nil {
+		return Member{}, err
+	}
+	member, _ := s.memberLocked(telegramUserID)
+	return member, nil
+}
+
+// recordLocked returns the stored record of an allowed user to change their role or binding.
+// Users allowed only by config get a FromConfig record, which does not allow them by itself.
+func (s *Service) recordLocked(telegramUserID int64) (*Member, error) {
+	if m, ok := s.members[telegramUserID]; ok && (!m.FromConfig || s.configured(telegramUserID)) {
+		return m, nil
+	}
+	if !s.configured(telegramUserID) {
+		return nil, ErrNotAllowed
+	}
+	m := &Member{TelegramUserID: telegramUserID, AddedAt: time.Now(), FromConfig: true}
+	s.members[telegramUserID] = m
+	return m, nil
+}
+
+func (s *Service) findAmoUser(ctx context.Context, email string) (*AmoUser, error) {
+	if s.directory == nil {
+		return nil, fmt.Errorf("access: amoCRM user directory is not configured")
+	}
+	u, err := s.directory.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("access: find amoCRM user: %w", err)
+	}
+	if u == nil {
+		return nil, ErrAmoUserNotFound
+	}
+	return u, nil
+}
+
+// saveLocked writes members to the file atomically (temp file + rename).
+func (s *Service) saveLocked() error {
+	members := make([]*Member, 0, len(s.members))
+	for _, m := range s.members {
+		members = append(members, m)
+	}
+	slices.SortFunc(members, func(a, b *Member) int {
+		return cmp.Compare(a.TelegramUserID, b.TelegramUserID)
+	})
+
+	data, err := json.MarshalIndent(members, "", "  ")
+	if err != nil {
+		return fmt.Errorf("access: marshal: %w", err)
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("access: create directory: %w", err)
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("access: write: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("access: rename: %w", err)
+	}
+	return nil
+}
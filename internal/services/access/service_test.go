@@ -0,0 +1,115 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewRequiresSomeone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.json")
+
+	if _, err := New(Config{Path: path}, nil); !errors.Is(err, ErrNoUsers) {
+		t.Fatalf("empty config: err = %v, want ErrNoUsers", err)
+	}
+
+	for name, cfg := range map[string]Config{
+		"admins":    {Path: path, Admins: []int64{1}},
+		"allowlist": {Path: path, Allowlist: []int64{2}},
+	} {
+		s, err := New(cfg, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(s.Members()) != 1 {
+			t.Errorf("%s: members = %v", name, s.Members())
+		}
+	}
+
+	// Members added earlier with /allow are enough to start without config
+	s, err := New(Config{Path: path, Admins: []int64{1}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Allow(context.Background(), 3, 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	s, err = New(Config{Path: path}, nil)
+	if err != nil {
+		t.Fatalf("stored members: %v", err)
+	}
+	if !s.IsAllowed(3) || s.IsAllowed(1) {
+		t.Error("stored member must be allowed, the removed admin must not")
+	}
+
+	if err := os.WriteFile(path, []byte("[]"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(Config{Path: path}, nil); !errors.Is(err, ErrNoUsers) {
+		t.Errorf("empty file: err = %v, want ErrNoUsers", err)
+	}
+}
+
+// fakeDirectory knows one amoCRM user.
+type fakeDirectory struct{}
+
+func (fakeDirectory) FindByEmail(_ context.Context, email string) (*AmoUser, error) {
+	if email != "anna@example.com" {
+		return nil, nil
+	}
+	return &AmoUser{ID: 7, Name: "Анна", Email: email}, nil
+}
+
+func TestRemovedFromAllowlist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.json")
+	s, err := New(Config{Path: path, Admins: []int64{1}, Allowlist: []int64{2, 3, 4}}, fakeDirectory{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SetRole(2, RoleReadOnly); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Bind(context.Background(), 3, "anna@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	// Explicitly allowed with /allow: keeps access without the allowlist
+	if _, err := s.Allow(context.Background(), 4, 1, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = New(Config{Path: path, Admins: []int64{1}}, fakeDirectory{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int64{2, 3} {
+		if s.IsAllowed(id) {
+			t.Errorf("user %d removed from ACCESS_ALLOWLIST is still allowed", id)
+		}
+		if _, ok := s.Member(id); ok {
+			t.Errorf("user %d removed from ACCESS_ALLOWLIST is still a member", id)
+		}
+		if _, err := s.SetRole(id, RoleSales); !errors.Is(err, ErrNotAllowed) {
+			t.Errorf("SetRole(%d): err = %v, want ErrNotAllowed", id, err)
+		}
+	}
+	if !s.IsAllowed(4) {
+		t.Error("member added with /allow lost access")
+	}
+	if n := len(s.Members()); n != 2 {
+		t.Errorf("members = %v, want the admin and user 4", s.Members())
+	}
+
+	// Back in the allowlist, the user gets the role and binding set earlier
+	s, err = New(Config{Path: path, Admins: []int64{1}, Allowlist: []int64{2, 3}}, fakeDirectory{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := s.Member(2); !ok || m.Role != RoleReadOnly {
+		t.Errorf("member 2 = %+v, %v; want readonly", m, ok)
+	}
+	if m, ok := s.Member(3); !ok || m.AmoUserID != 7 {
+		t.Errorf("member 3 = %+v, %v; want bound to 7", m, ok)
+	}
+}
@@ -0,0 +1,161 @@
+// Package account_context хранит справочники аккаунта amoCRM, общие для всех CRM-сервисов:
+// пользователей, воронки и статусы, кастомные поля, источники, причины отказа и статусы покупателей.
+// Справочники загружаются при старте (Load) и обновляются по TTL, после изменений
+// через admin-инструменты и по команде /reload; сервисы получают Resolver через конструктор.
+package account_context
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Named — элемент справочника с ID и названием: пользователь, источник, причина отказа, статус покупателя.
+type Named struct {
+	ID   int
+	Name string
+}
+
+// Pipeline — воронка сделок со статусами.
+type Pipeline struct {
+	ID       int
+	Name     string
+	Statuses []Named
+}
+
+// CustomField — кастомное поле сущности.
+type CustomField struct {
+	ID    int
+	Name  string
+	Code  string  // пусто у полей, созданных пользователем
+	Type  string  // text, numeric, select, multiselect, date, ...
+	Enums []Named // варианты select/multiselect: ID и значение
+}
+
+// Data — справочники аккаунта.
+type Data struct {
+	Users            []Named
+	Pipelines        []Pipeline
+	CustomFields     map[string][]CustomField // тип сущности (leads, contacts, ...) → поля
+	Sources          []Named
+	LossReasons      []Named
+	CustomerStatuses []Named
+	Failed           []string // необязательные справочники, которые не загрузились: "sources: <ошибка>"
+}
+
+// AccountContext — потокобезопасный кеш справочников. Реализует Resolver.
+type AccountContext struct {
+	mu       sync.RWMutex
+	idx      *index
+	loadedAt time.Time
+	lastErr  error // ошибка последней перезагрузки, nil после успешной
+
+	reloading sync.Mutex // одна перезагрузка за раз
+	fetch     func(ctx context.Context) (Data, error)
+}
+
+// New создаёт AccountContext из уже загруженных справочников.
+func New(data Data) *AccountContext {
+	return &AccountContext{idx: newIndex(data), loadedAt: time.Now()}
+}
+
+// Set заменяет справочники целиком: читатели видят либо старые, либо новые данные.
+func (ac *AccountContext) Set(data Data) {
+	idx := newIndex(data)
+	ac.mu.Lock()
+	ac.idx = idx
+	ac.loadedAt = time.Now()
+	ac.lastErr = nil
+	ac.mu.Unlock()
+}
+
+// LoadedAt возвращает время последней успешной загрузки справочников.
+func (ac *AccountContext) LoadedAt() time.Time {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	return ac.loadedAt
+}
+
+// Resolver возвращает резолвер имя↔ID поверх кеша.
+func (ac *AccountContext) Resolver() Resolver {
+	return ac
+}
+
+func (ac *AccountContext) index() *index {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	return ac.idx
+}
+
+// names — двунаправленный индекс справочника, нечёткий поиск по названию и отсортированный список названий.
+type names struct {
+	byName  map[string]int
+	byID    map[int]string
+	matcher matcher
+	sorted  []string
+}
+
+func newNames(items []Named) names {
+	n := names{byName: make(map[string]int, len(items)), byID: make(map[int]string, len(items))}
+	for _, item := range items {
+		if item.Name == "" {
+			continue
+		}
+		if _, dup := n.byName[item.Name]; !dup {
+			n.sorted = append(n.sorted, item.Name)
+		}
+		n.byName[item.Name] = item.ID
+		n.byID[item.ID] = item.Name
+	}
+	sort.Strings(n.sorted)
+	n.matcher = newMatcher(items)
+	return n
+}
+
+// index — неизменяемые индексы справочников; при обновлении строится новый.
+type index struct {
+	data             Data // исходные справочники: при частичной ошибке обновления берутся старые
+	users            names
+	pipelines        names
+	statuses         map[int]names // pipeline ID → статусы воронки
+	statusesByID     map[int]string
+	customFields     map[string][]CustomField
+	sources          names
+	lossReasons      names
+	customerStatuses names
+}
+
+func newIndex(data Data) *index {
+	idx := &index{
+		data:             data,
+		users:            newNames(data.Users),
+		statuses:         make(map[int]names, len(data.Pipelines)),
+		statusesByID:     make(map[int]string),
+		customFields:     make(map[string][]CustomField, len(data.CustomFields)),
+		sources:          newNames(data.Sources),
+		lossReasons:      newNames(data.LossReasons),
+		customerStatuses: newNames(data.CustomerStatuses),
+	}
+
+	pipelines := make([]Named, 0, len(data.Pipelines))
+	for _, p := range data.Pipelines {
+		if p.Name == "" {
+			continue
+		}
+		pipelines = append(pipelines, Named{ID: p.ID, Name: p.Name})
+		idx.statuses[p.ID] = newNames(p.Statuses)
+		for _, st := range p.Statuses {
+			if st.Name != "" {
+				// Системные статусы 142/143 общие для всех воронок
+				idx.statusesByID[st.ID] = st.Name
+			}
+		}
+	}
+	idx.pipelines = newNames(pipelines)
+
+	for entityType, fields := range data.CustomFields {
+		idx.customFields[entityType] = fields
+	}
+	return idx
+}
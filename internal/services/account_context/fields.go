@@ -0,0 +1,388 @@
+package account_context
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/utils"
+)
+
+// FieldValue — значение кастомного поля в формате API amoCRM.
+type FieldValue struct {
+	FieldID   int    // 0, если схема полей не загружена и поле передано кодом
+	FieldCode string // заполнен только вместе с FieldID 0
+	Values    []FieldValueItem
+}
+
+// FieldValueItem — элемент values: значение или вариант списка.
+type FieldValueItem struct {
+	Value    any
+	EnumID   int
+	EnumCode string
+}
+
+// multitextEnums — русские названия типов телефона и email (enum_code полей PHONE и EMAIL).
+var multitextEnums = map[string]string{
+	"рабочий":   "WORK",
+	"мобильный": "MOB",
+	"сотовый":   "MOB",
+	"домашний":  "HOME",
+	"личный":    "PRIV",
+	"прямой":    "WORKDD",
+	"факс":      "FAX",
+	"другой":    "OTHER",
+}
+
+// boolWords — как пользователи пишут значение флажка.
+var boolWords = map[string]bool{
+	"да": true, "yes": true, "true": true, "1": true, "+": true, "вкл": true, "on": true,
+	"нет": false, "no": false, "false": false, "0": false, "-": false, "выкл": false, "off": false,
+}
+
+// CustomField ищет кастомное поле сущности по ID, коду (без учёта регистра) или названию (нечётко, см. Match).
+func (ac *AccountContext) CustomField(entityType, key string) (CustomField, error) {
+	idx := ac.index()
+	fields, ok := idx.customFields[entityType]
+	if !ok {
+		return CustomField{}, fmt.Errorf("кастомные поля %s не загружены", entityType)
+	}
+	return findField(entityType, fields, key)
+}
+
+// FieldValues переводит значения кастомных полей в формат API. Ключ — название, код или ID поля;
+// значение приводится к типу поля: варианты списков — по названию, даты — из человеческой записи,
+// флажки — из да/нет, числа — из строк с пробелами и валютой, телефоны и email — с типом (enum_code).
+// Ошибки по всем полям возвращаются вместе, для списков — с допустимыми вариантами;
+// два ключа, указывающие на одно поле («бюджет» и «Бюджет проекта»), — ошибка, а не два значения.
+func (ac *AccountContext) FieldValues(entityType string, values map[string]any) ([]FieldValue, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	fields, loaded := ac.index().customFields[entityType]
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var result []FieldValue
+	var errs []error
+	seen := make(map[int]string, len(keys)) // ID поля → ключ, которым оно задано
+	for _, key := range keys {
+		raw := values[key]
+		if raw == nil {
+			continue
+		}
+		if !loaded {
+			// Схема полей не загрузилась: передаём как есть, ключ — код поля
+			result = append(result, FieldValue{FieldCode: key, Values: rawItems(raw)})
+			continue
+		}
+		field, err := findField(entityType, fields, key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if prev, dup := seen[field.ID]; dup {
+			errs = append(errs, fmt.Errorf("ключи %q и %q указывают на одно поле %q: оставь один", prev, key, field.Name))
+			// Какое из значений верное, неизвестно — не передаём ни одно
+			result = slices.DeleteFunc(result, func(v FieldValue) bool { return v.FieldID == field.ID })
+			continue
+		}
+		seen[field.ID] = key
+		items, err := convertValue(field, raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("поле %q: %w", field.Name, err))
+			continue
+		}
+		result = append(result, FieldValue{FieldID: field.ID, Values: items})
+	}
+	return result, errors.Join(errs...)
+}
+
+func findField(entityType string, fields []CustomField, key string) (CustomField, error) {
+	key = strings.TrimSpace(key)
+	id, _ := strconv.Atoi(key)
+	for _, f := range fields {
+		if (f.Code != "" && strings.EqualFold(f.Code, key)) || (id != 0 && f.ID == id) {
+			return f, nil
+		}
+	}
+
+	items := make([]Named, 0, len(fields))
+	fieldNames := make([]string, 0, len(fields))
+	for _, f := range fields {
+		items = append(items, Named{ID: f.ID, Name: f.Name})
+		fieldNames = append(fieldNames, f.Name)
+	}
+	id, err := Match(key, items)
+	if errors.Is(err, ErrNotFound) {
+		return CustomField{}, fmt.Errorf("поле %q не найдено у %s. Доступные: %s", key, entityType, strings.Join(fieldNames, ", "))
+	}
+	if err != nil {
+		return CustomField{}, fmt.Errorf("поле: %w", err)
+	}
+	i := slices.IndexFunc(fields, func(f CustomField) bool { return f.ID == id })
+	return fields[i], nil
+}
+
+// convertValue приводит значение (одно или массив) к элементам values по типу поля.
+func convertValue(field CustomField, raw any) ([]FieldValueItem, error) {
+	list, ok := raw.([]any)
+	if !ok {
+		list = []any{raw}
+	}
+	if len(list) == 0 {
+		return nil, errors.New("пустое значение")
+	}
+
+	switch field.Type {
+	case "select", "radiobutton", "category":
+		if len(list) > 1 {
+			return nil, fmt.Errorf("можно выбрать только один вариант. Допустимые: %s", enumNames(field))
+		}
+		return convertEach(list, func(v any) (FieldValueItem, error) { return enumItem(field, v) })
+	case "multiselect":
+		return convertEach(list, func(v any) (FieldValueItem, error) { return enumItem(field, v) })
+	case "multitext":
+		return convertEach(list, func(v any) (FieldValueItem, error) { return multitextItem(field, v) })
+	case "checkbox":
+		return convertEach(list, func(v any) (FieldValueItem, error) {
+			b, err := parseBool(valueOf(v))
+			return FieldValueItem{Value: b}, err
+		})
+	case "numeric":
+		return convertEach(list, func(v any) (FieldValueItem, error) {
+			n, err := parseNumber(valueOf(v))
+			return FieldValueItem{Value: strconv.FormatFloat(n, 'f', -1, 64)}, err
+		})
+	case "price", "monetary":
+		return convertEach(list, func(v any) (FieldValueItem, error) {
+			n, err := parseNumber(valueOf(v))
+			return FieldValueItem{Value: n}, err
+		})
+	case "date", "date_time", "birthday":
+		withTime := field.Type == "date_time"
+		return convertEach(list, func(v any) (FieldValueItem, error) {
+			ts, err := parseDate(valueOf(v), withTime)
+			return FieldValueItem{Value: ts}, err
+		})
+	}
+	return convertEach(list, func(v any) (FieldValueItem, error) {
+		return FieldValueItem{Value: text(valueOf(v))}, nil
+	})
+}
+
+func convertEach(list []any, convert func(v any) (FieldValueItem, error)) ([]FieldValueItem, error) {
+	items := make([]FieldValueItem, 0, len(list))
+	for _, v := range list {
+		item, err := convert(v)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// enumItem находит вариант списка по названию ({"value": ...}) или ID ({"enum_id": ...} или число).
+func enumItem(field CustomField, v any) (FieldValueItem, error) {
+	if m, ok := v.(map[string]any); ok {
+		if id, ok := m["enum_id"].(float64); ok {
+			return FieldValueItem{EnumID: int(id)}, nil
+		}
+	}
+	label := valueOf(v)
+	if n, ok := label.(float64); ok {
+		if slices.ContainsFunc(field.Enums, func(e Named) bool { return e.ID == int(n) }) {
+			return FieldValueItem{EnumID: int(n)}, nil
+		}
+	}
+	name := text(label)
+	id, err := Match(name, field.Enums)
+	if errors.Is(err, ErrNotFound) {
+		return FieldValueItem{}, fmt.Errorf("нет варианта %q. Допустимые: %s", name, enumNames(field))
+	}
+	if err != nil {
+		return FieldValueItem{}, err
+	}
+	return FieldValueItem{EnumID: id}, nil
+}
+
+// multitextItem — телефон или email с типом: строка (тип WORK) или {"value": ..., "enum_code": "MOB"}.
+func multitextItem(field CustomField, v any) (FieldValueItem, error) {
+	value := strings.TrimSpace(text(valueOf(v)))
+	if value == "" {
+		return FieldValueItem{}, errors.New("пустое значение")
+	}
+	code := "WORK"
+	if m, ok := v.(map[string]any); ok {
+		if c, ok := m["enum_code"].(string); ok && strings.TrimSpace(c) != "" {
+			code = strings.TrimSpace(c)
+		}
+	}
+	if c, ok := multitextEnums[strings.ToLower(code)]; ok {
+		code = c
+	}
+	code = strings.ToUpper(code)
+	if len(field.Enums) > 0 && !slices.ContainsFunc(field.Enums, func(e Named) bool { return e.Name == code }) {
+		return FieldValueItem{}, fmt.Errorf("нет типа %q. Допустимые: %s", code, enumNames(field))
+	}
+	return FieldValueItem{Value: value, EnumCode: code}, nil
+}
+
+// valueOf достаёт значение из {"value": ...}, остальные значения возвращает как есть.
+func valueOf(v any) any {
+	if m, ok := v.(map[string]any); ok {
+		return m["value"]
+	}
+	return v
+}
+
+func text(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func parseBool(v any) (bool, error) {
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case float64:
+		return v != 0, nil
+	}
+	s := strings.ToLower(strings.TrimSpace(text(v)))
+	if b, ok := boolWords[s]; ok {
+		return b, nil
+	}
+	return false, fmt.Errorf("%q — не да/нет", s)
+}
+
+// numberSuffixes — сокращения тысяч и миллионов: «150к», «150 тыс.», «1,2 млн».
+var numberSuffixes = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"тыс", 1e3}, {"к", 1e3}, {"k", 1e3}, {"млн", 1e6},
+}
+
+// parseNumber читает число из «1 500», «1500,50», «2.5к», «10 000 ₽».
+func parseNumber(v any) (float64, error) {
+	if n, ok := v.(float64); ok {
+		return finite(n, v)
+	}
+	s := strings.ToLower(text(v))
+	for _, unit := range []string{"руб.", "руб", "р.", "₽", "rub", "usd", "eur", "$", "€", " ", "\u00a0", "\u202f"} {
+		s = strings.ReplaceAll(s, unit, "")
+	}
+	s = strings.ReplaceAll(s, ",", ".")
+	s = strings.TrimSuffix(s, ".") // точка сокращения: «тыс.», «млн.»
+	multiplier := 1.0
+	for _, m := range numberSuffixes {
+		if strings.HasSuffix(s, m.suffix) {
+			s, multiplier = strings.TrimSuffix(s, m.suffix), m.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q — не число", text(v))
+	}
+	return finite(n*multiplier, v)
+}
+
+// finite отклоняет NaN и бесконечность: ParseFloat понимает «nan» и «inf», множитель
+// может переполнить большое число, а в JSON для amoCRM такие значения не кодируются.
+func finite(n float64, v any) (float64, error) {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("%q — не число", text(v))
+	}
+	return n, nil
+}
+
+// dateLayouts — даты, которые пишут пользователи и нейронка: русская запись и ISO 8601.
+var dateLayouts = []string{
+	"2.1.2006 15:04", "2.1.2006",
+	time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02",
+}
+
+// relativeDays — дни словами, отсчитываются от сегодня.
+var relativeDays = map[string]int{"сегодня": 0, "today": 0, "завтра": 1, "tomorrow": 1, "послезавтра": 2}
+
+// parseDate переводит дату в Unix timestamp: число — уже timestamp, строка — «15.01.2024»,
+// «2024-01-15», «сегодня», «завтра», «in 3 days». Все записи приводятся одинаково:
+// дата без времени — полдень этого дня, а у полей без времени (withTime false) — полдень всегда,
+// чтобы часовой пояс не сдвигал день.
+func parseDate(v any, withTime bool) (int64, error) {
+	if n, ok := v.(float64); ok {
+		return int64(n), nil
+	}
+	t, hasTime, err := parseDateTime(strings.TrimSpace(text(v)))
+	if err != nil {
+		return 0, fmt.Errorf("%q — не дата, пример: 15.01.2024 или 2024-01-15", text(v))
+	}
+	if !hasTime || !withTime {
+		t = time.Date(t.Year(), t.Month(), t.Day(), 12, 0, 0, 0, t.Location())
+	}
+	return t.Unix(), nil
+}
+
+// parseDateTime разбирает дату; hasTime сообщает, что время указано явно.
+func parseDateTime(s string) (t time.Time, hasTime bool, err error) {
+	if days, ok := relativeDays[strings.ToLower(s)]; ok {
+		return time.Now().AddDate(0, 0, days), false, nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, strings.Contains(layout, "15:04"), nil
+		}
+	}
+	// «in 3 days», «in 2 hours»
+	ts, err := utils.ParseHumanDeadline(s)
+	if err != nil || ts == 0 {
+		return time.Time{}, false, errors.New("unknown date format")
+	}
+	return time.Unix(ts, 0), true, nil
+}
+
+func enumNames(field CustomField) string {
+	names := make([]string, len(field.Enums))
+	for i, e := range field.Enums {
+		names[i] = e.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// rawItems передаёт значение без схемы поля: строка, массив или {value, enum_code}.
+func rawItems(raw any) []FieldValueItem {
+	list, ok := raw.([]any)
+	if !ok {
+		list = []any{raw}
+	}
+	items := make([]FieldValueItem, 0, len(list))
+	for _, v := range list {
+		item := FieldValueItem{Value: valueOf(v)}
+		if m, ok := v.(map[string]any); ok {
+			item.EnumCode, _ = m["enum_code"].(string)
+			if id, ok := m["enum_id"].(float64); ok {
+				item.EnumID = int(id)
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
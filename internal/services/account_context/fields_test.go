@@ -0,0 +1,141 @@
+package account_context
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFieldValues(t *testing.T) {
+	ac := New(Data{CustomFields: map[string][]CustomField{
+		"leads": {
+			{ID: 1, Name: "Источник заявки", Type: "select", Enums: []Named{{ID: 11, Name: "Сайт"}, {ID: 12, Name: "Реклама"}}},
+			{ID: 2, Name: "Дата встречи", Type: "date"},
+			{ID: 3, Name: "Оплачено", Type: "checkbox"},
+			{ID: 4, Name: "Бюджет проекта", Type: "numeric"},
+			{ID: 5, Name: "Комментарий", Code: "NOTE", Type: "text"},
+			{ID: 6, Name: "Созвон", Type: "date_time"},
+		},
+		"contacts": {
+			{ID: 20, Name: "Телефон", Code: "PHONE", Type: "multitext", Enums: []Named{{ID: 1, Name: "WORK"}, {ID: 2, Name: "MOB"}}},
+		},
+	}})
+
+	values, err := ac.FieldValues("leads", map[string]any{
+		"источник":       "реклама",
+		"Дата встречи":   "15.01.2024",
+		"оплачено":       "да",
+		"бюджет":         "150 000 ₽",
+		"note":           "перезвонить",
+		"Бюджет проекта": nil,
+	})
+	if err != nil {
+		t.Fatalf("FieldValues: %v", err)
+	}
+	got := map[int]FieldValueItem{}
+	for _, v := range values {
+		got[v.FieldID] = v.Values[0]
+	}
+	meeting := time.Date(2024, 1, 15, 12, 0, 0, 0, time.Local).Unix()
+	want := map[int]FieldValueItem{
+		1: {EnumID: 12},
+		2: {Value: meeting},
+		3: {Value: true},
+		4: {Value: "150000"},
+		5: {Value: "перезвонить"},
+	}
+	for id, w := range want {
+		if got[id] != w {
+			t.Errorf("field %d = %+v, want %+v", id, got[id], w)
+		}
+	}
+
+	phones, err := ac.FieldValues("contacts", map[string]any{
+		"телефон": []any{"+79001234567", map[string]any{"value": "+79007654321", "enum_code": "мобильный"}},
+	})
+	if err != nil {
+		t.Fatalf("FieldValues(contacts): %v", err)
+	}
+	if items := phones[0].Values; len(items) != 2 || items[0].EnumCode != "WORK" || items[1].EnumCode != "MOB" {
+		t.Errorf("phone items = %+v", items)
+	}
+
+	_, err = ac.FieldValues("leads", map[string]any{"источник": "Выставка", "Склад": "1"})
+	if err == nil {
+		t.Fatal("want errors for unknown option and field")
+	}
+	for _, part := range []string{"Допустимые: Сайт, Реклама", `"Склад" не найдено`} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("error %q does not mention %q", err, part)
+		}
+	}
+
+	// Два ключа одного поля — ошибка, а не два значения
+	values, err = ac.FieldValues("leads", map[string]any{"бюджет": "100", "Бюджет проекта": "200", "note": "x"})
+	if err == nil || !strings.Contains(err.Error(), "одно поле") {
+		t.Errorf("duplicate field: err = %v", err)
+	}
+	for _, v := range values {
+		if v.FieldID == 4 {
+			t.Errorf("duplicate field kept: %+v", v)
+		}
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := map[string]float64{
+		"1 500":     1500,
+		"1500,50":   1500.5,
+		"2.5к":      2500,
+		"150 тыс.":  150000,
+		"150тыс":    150000,
+		"1,2 млн.":  1200000,
+		"10 000 ₽":  10000,
+		"5000 руб.": 5000,
+		"1500.":     1500,
+	}
+	for input, want := range tests {
+		if got, err := parseNumber(input); err != nil || got != want {
+			t.Errorf("parseNumber(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	for _, input := range []any{"много", "nan", "NaN", "inf", "-Infinity", "1e999", "1e308 млн", math.Inf(1), math.NaN()} {
+		if got, err := parseNumber(input); err == nil || !strings.Contains(err.Error(), "не число") {
+			t.Errorf("parseNumber(%v) = %v, %v; want an error", input, got, err)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	noon := time.Date(2024, 1, 15, 12, 0, 0, 0, time.Local).Unix()
+	evening := time.Date(2024, 1, 15, 18, 30, 0, 0, time.Local).Unix()
+	now := time.Now()
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 12, 0, 0, 0, time.Local).Unix()
+
+	tests := []struct {
+		input    any
+		withTime bool
+		want     int64
+	}{
+		// Дата без времени — полдень в любой записи
+		{input: "15.01.2024", want: noon},
+		{input: "2024-01-15", want: noon},
+		{input: "2024-01-15", withTime: true, want: noon},
+		{input: "завтра", want: tomorrow},
+		{input: "Tomorrow", withTime: true, want: tomorrow},
+		// Время сохраняется только у полей с временем
+		{input: "15.01.2024 18:30", withTime: true, want: evening},
+		{input: "2024-01-15T18:30:00", withTime: true, want: evening},
+		{input: "2024-01-15 18:30", want: noon},
+		{input: float64(evening), want: evening},
+	}
+	for _, tt := range tests {
+		if got, err := parseDate(tt.input, tt.withTime); err != nil || got != tt.want {
+			t.Errorf("parseDate(%v, %v) = %v, %v; want %v", tt.input, tt.withTime, time.Unix(got, 0), err, time.Unix(tt.want, 0))
+		}
+	}
+	if _, err := parseDate("когда-нибудь", false); err == nil {
+		t.Error("parseDate(когда-нибудь) must fail")
+	}
+}
@@ -0,0 +1,211 @@
+package account_context
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+
+	"github.com/alextixru/amocrm-sdk-go"
+	"github.com/alextixru/amocrm-sdk-go/core/filters"
+	"github.com/alextixru/amocrm-sdk-go/core/models"
+	"github.com/alextixru/amocrm-sdk-go/core/services"
+)
+
+const (
+	pageLimit = 250 // максимум API amoCRM на страницу
+	maxPages  = 100 // предохранитель от бесконечной пагинации
+)
+
+// customFieldEntityTypes — сущности, кастомные поля которых загружаются в кеш.
+var customFieldEntityTypes = []string{"leads", "contacts", "companies", "customers"}
+
+// Load загружает справочники аккаунта.
+// Без пользователей и воронок сервисы не работают — их ошибка возвращается;
+// остальные справочники необязательны (например, покупатели бывают выключены) и только логируются.
+func Load(ctx context.Context, sdk *amocrm.SDK) (*AccountContext, error) {
+	data, err := Fetch(ctx, sdk)
+	if err != nil {
+		return nil, err
+	}
+	ac := New(data)
+	ac.fetch = func(ctx context.Context) (Data, error) {
+		return Fetch(ctx, sdk)
+	}
+	return ac, nil
+}
+
+// Fetch запрашивает справочники из API, все страницы каждого. Необязательный справочник, который
+// не удалось загрузить, остаётся nil (для кастомных полей — нет ключа сущности) и попадает в Data.Failed.
+func Fetch(ctx context.Context, sdk *amocrm.SDK) (Data, error) {
+	var data Data
+	var err error
+
+	if data.Users, err = fetchUsers(ctx, sdk); err != nil {
+		return Data{}, fmt.Errorf("account_context: load users: %w", err)
+	}
+	if data.Pipelines, err = fetchPipelines(ctx, sdk); err != nil {
+		return Data{}, fmt.Errorf("account_context: load pipelines: %w", err)
+	}
+
+	failed := func(what string, err error) {
+		log.Printf("⚠️ account_context: load %s: %v", what, err)
+		data.Failed = append(data.Failed, fmt.Sprintf("%s: %v", what, err))
+	}
+	data.CustomFields = make(map[string][]CustomField, len(customFieldEntityTypes))
+	for _, entityType := range customFieldEntityTypes {
+		fields, err := fetchCustomFields(ctx, sdk, entityType)
+		if err != nil {
+			failed(entityType+" custom fields", err)
+			continue
+		}
+		data.CustomFields[entityType] = fields
+	}
+	if data.Sources, err = fetchSources(ctx, sdk); err != nil {
+		failed("sources", err)
+	}
+	if data.LossReasons, err = fetchLossReasons(ctx, sdk); err != nil {
+		failed("loss reasons", err)
+	}
+	// 422 означает, что статусы покупателей недоступны в этом аккаунте (режим сегментов)
+	if data.CustomerStatuses, err = fetchCustomerStatuses(ctx, sdk); err != nil {
+		failed("customer statuses", err)
+	}
+	return data, nil
+}
+
+// fetchAll запрашивает страницы по pageLimit, пока PageMeta сообщает, что есть ещё.
+// Ошибка любой страницы — ошибка всего справочника: неполный список не подменяет прежний.
+func fetchAll[T any](fetch func(page int) ([]T, *services.PageMeta, error)) ([]T, error) {
+	var all []T
+	for page := 1; page <= maxPages; page++ {
+		items, meta, err := fetch(page)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", page, err)
+		}
+		all = append(all, items...)
+		if meta == nil || !meta.HasMore || len(items) == 0 {
+			return all, nil
+		}
+	}
+	return nil, fmt.Errorf("more than %d pages of %d", maxPages, pageLimit)
+}
+
+// pageParams — page и limit для методов SDK, принимающих url.Values.
+func pageParams(page int) url.Values {
+	params := url.Values{}
+	params.Set("page", strconv.Itoa(page))
+	params.Set("limit", strconv.Itoa(pageLimit))
+	return params
+}
+
+func fetchUsers(ctx context.Context, sdk *amocrm.SDK) ([]Named, error) {
+	users, err := fetchAll(func(page int) ([]*models.User, *services.PageMeta, error) {
+		f := filters.NewUsersFilter()
+		f.SetLimit(pageLimit)
+		f.SetPage(page)
+		return sdk.Users().Get(ctx, f)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Named, 0, len(users))
+	for _, u := range users {
+		if u != nil {
+			result = append(result, Named{ID: u.ID, Name: u.Name})
+		}
+	}
+	return result, nil
+}
+
+func fetchPipelines(ctx context.Context, sdk *amocrm.SDK) ([]Pipeline, error) {
+	params := url.Values{}
+	params.Set("with", "statuses")
+	pipelines, _, err := sdk.Pipelines().Get(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Pipeline, 0, len(pipelines))
+	for _, p := range pipelines {
+		if p == nil {
+			continue
+		}
+		pipeline := Pipeline{ID: p.ID, Name: p.Name}
+		if p.Embedded != nil {
+			for _, st := range p.Embedded.Statuses {
+				pipeline.Statuses = append(pipeline.Statuses, Named{ID: st.ID, Name: st.Name})
+			}
+		}
+		result = append(result, pipeline)
+	}
+	return result, nil
+}
+
+func fetchCustomFields(ctx context.Context, sdk *amocrm.SDK, entityType string) ([]CustomField, error) {
+	fields, err := fetchAll(func(page int) ([]*models.CustomField, *services.PageMeta, error) {
+		f := filters.NewCustomFieldsFilter()
+		f.SetLimit(pageLimit)
+		f.SetPage(page)
+		return sdk.CustomFields().Get(ctx, entityType, f)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]CustomField, 0, len(fields))
+	for _, cf := range fields {
+		if cf == nil {
+			continue
+		}
+		field := CustomField{ID: cf.ID, Name: cf.Name, Code: cf.Code, Type: string(cf.Type)}
+		for _, e := range cf.Enums {
+			field.Enums = append(field.Enums, Named{ID: e.ID, Name: e.Value})
+		}
+		result = append(result, field)
+	}
+	return result, nil
+}
+
+func fetchSources(ctx context.Context, sdk *amocrm.SDK) ([]Named, error) {
+	sources, _, err := sdk.Sources().Get(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Named, 0, len(sources))
+	for _, src := range sources {
+		if src != nil {
+			result = append(result, Named{ID: src.ID, Name: src.Name})
+		}
+	}
+	return result, nil
+}
+
+func fetchLossReasons(ctx context.Context, sdk *amocrm.SDK) ([]Named, error) {
+	reasons, err := fetchAll(func(page int) ([]*models.LossReason, *services.PageMeta, error) {
+		return sdk.LossReasons().Get(ctx, pageParams(page)) //nolint:staticcheck
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Named, 0, len(reasons))
+	for _, r := range reasons {
+		if r != nil {
+			result = append(result, Named{ID: r.ID, Name: r.Name})
+		}
+	}
+	return result, nil
+}
+
+func fetchCustomerStatuses(ctx context.Context, sdk *amocrm.SDK) ([]Named, error) {
+	statuses, err := fetchAll(func(page int) ([]models.Status, *services.PageMeta, error) {
+		return sdk.CustomerStatuses().Get(ctx, pageParams(page))
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Named, 0, len(statuses))
+	for _, st := range statuses {
+		result = append(result, Named{ID: st.ID, Name: st.Name})
+	}
+	return result, nil
+}
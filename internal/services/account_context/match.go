@@ -0,0 +1,224 @@
+package account_context
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// maxCandidates — сколько вариантов перечисляет ошибка неоднозначности.
+const maxCandidates = 10
+
+// ErrNotFound — название не подходит ни к одному значению справочника.
+var ErrNotFound = errors.New("не найдено")
+
+// AmbiguousError — название подходит к нескольким значениям справочника
+// или, при Typo, похоже на них только с опечаткой: тогда даже единственный вариант
+// не выбирается сам, а предлагается («возможно, имелось в виду»).
+// Candidates отсортированы от лучшего совпадения.
+type AmbiguousError struct {
+	Query      string
+	Candidates []Named
+	Typo       bool
+}
+
+func (e *AmbiguousError) Error() string {
+	count := make(map[string]int, len(e.Candidates))
+	for _, c := range e.Candidates {
+		count[c.Name]++
+	}
+	names := make([]string, len(e.Candidates))
+	for i, c := range e.Candidates {
+		names[i] = c.Name
+		if count[c.Name] > 1 {
+			// Одинаковые названия различаются только ID
+			names[i] = fmt.Sprintf("%s (ID %d)", c.Name, c.ID)
+		}
+	}
+	if e.Typo {
+		return fmt.Sprintf("%q не найдено, возможно, имелось в виду: %s. Укажи название точно", e.Query, strings.Join(names, ", "))
+	}
+	return fmt.Sprintf("%q подходит к нескольким значениям: %s. Укажи название точнее", e.Query, strings.Join(names, ", "))
+}
+
+// Match ищет название среди items по правилам Resolver:
+//  1. точное совпадение;
+//  2. совпадение без учёта регистра, ё/е, пробелов и знаков, в транслитерации («ivan» = «Иван»);
+//  3. по словам: каждое слово запроса — слово названия («петров» → «Иван Петров»);
+//  4. частичное: каждое слово запроса — начало слова названия («перегов» → «Переговоры»);
+//  5. опечатки: ближайшие по расстоянию Левенштейна.
+//
+// Единственный кандидат на первом сработавшем шаге 1–4 выбирается автоматически, несколько — *AmbiguousError,
+// ни одного — ErrNotFound. Совпадение только с опечаткой не выбирается никогда: «Иванова» может быть
+// и Иванов, и другой человек, поэтому варианты возвращаются в *AmbiguousError с Typo.
+func Match(name string, items []Named) (int, error) {
+	return newMatcher(items).match(name)
+}
+
+// candidate — значение справочника с нормализованным названием.
+type candidate struct {
+	Named
+	key    string
+	tokens []string
+}
+
+type matcher []candidate
+
+func newMatcher(items []Named) matcher {
+	m := make(matcher, 0, len(items))
+	for _, item := range items {
+		if item.Name == "" {
+			continue
+		}
+		key := normalize(item.Name)
+		m = append(m, candidate{Named: item, key: key, tokens: strings.Fields(key)})
+	}
+	return m
+}
+
+func (m matcher) match(name string) (int, error) {
+	key := normalize(name)
+	if key == "" {
+		return 0, ErrNotFound
+	}
+	tokens := strings.Fields(key)
+
+	steps := []func(c candidate) bool{
+		func(c candidate) bool { return c.Name == name },
+		func(c candidate) bool { return c.key == key },
+		func(c candidate) bool { return hasTokens(c.tokens, tokens, func(t, q string) bool { return t == q }) },
+		func(c candidate) bool { return hasTokens(c.tokens, tokens, strings.HasPrefix) },
+	}
+	for _, matches := range steps {
+		var found []candidate
+		for _, c := range m {
+			if matches(c) {
+				found = append(found, c)
+			}
+		}
+		if len(found) > 0 {
+			return pick(name, key, found)
+		}
+	}
+
+	// Опечатки: допускаем примерно одну ошибку на 4 символа
+	limit := min(max(len([]rune(key))/4, 1), 3)
+	var found []candidate
+	for _, c := range m {
+		if distance(key, tokens, c) <= limit {
+			found = append(found, c)
+		}
+	}
+	if len(found) == 0 {
+		return 0, ErrNotFound
+	}
+	err := ambiguous(name, key, found)
+	err.Typo = true
+	return 0, err
+}
+
+// pick выбирает единственного кандидата, иначе возвращает список для уточнения.
+func pick(name, key string, found []candidate) (int, error) {
+	if len(found) == 1 {
+		return found[0].ID, nil
+	}
+	return 0, ambiguous(name, key, found)
+}
+
+// ambiguous перечисляет кандидатов от ближайшего к запросу.
+func ambiguous(name, key string, found []candidate) *AmbiguousError {
+	tokens := strings.Fields(key)
+	slices.SortStableFunc(found, func(a, b candidate) int {
+		return cmp.Or(
+			cmp.Compare(distance(key, tokens, a), distance(key, tokens, b)),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+	err := &AmbiguousError{Query: name}
+	for _, c := range found[:min(len(found), maxCandidates)] {
+		err.Candidates = append(err.Candidates, c.Named)
+	}
+	return err
+}
+
+// distance — расстояние от запроса до названия; однословный запрос сравнивается и с отдельными словами («Петров»).
+func distance(key string, tokens []string, c candidate) int {
+	d := levenshtein(key, c.key)
+	if len(tokens) == 1 {
+		for _, t := range c.tokens {
+			d = min(d, levenshtein(key, t))
+		}
+	}
+	return d
+}
+
+// hasTokens сообщает, что каждому слову запроса соответствует своё слово названия.
+func hasTokens(nameTokens, queryTokens []string, matches func(nameToken, queryToken string) bool) bool {
+	used := make([]bool, len(nameTokens))
+	for _, q := range queryTokens {
+		ok := false
+		for i, t := range nameTokens {
+			if !used[i] && matches(t, q) {
+				used[i], ok = true, true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// translit — кириллица в латиницу: названия сравниваются в латинице,
+// поэтому «Ivan Petrov» находит «Иван Петров» и наоборот; латинская x пишется как ks («Alexey» = «Алексей»).
+var translit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "sch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'x': "ks",
+}
+
+// normalize приводит название к виду для сравнения: нижний регистр, латиница,
+// знаки препинания — пробелы, одиночные пробелы между словами.
+func normalize(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if t, ok := translit[r]; ok {
+			sb.WriteString(t)
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte(' ')
+		}
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// levenshtein — число вставок, удалений и замен символов, чтобы получить b из a.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,82 @@
+package account_context
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	users := []Named{
+		{ID: 1, Name: "Иван Петров"},
+		{ID: 2, Name: "Пётр Иванов"},
+		{ID: 3, Name: "Анна Петрова"},
+		{ID: 4, Name: "Alexey Smirnov"},
+		{ID: 5, Name: "Олег Иванцов"},
+	}
+	statuses := []Named{
+		{ID: 10, Name: "Первичный контакт"},
+		{ID: 11, Name: "Переговоры"},
+		{ID: 12, Name: "Принимают решение"},
+	}
+
+	tests := []struct {
+		name      string
+		items     []Named
+		query     string
+		want      int
+		ambiguous bool
+		typo      bool // only suggested: want is the first candidate
+		notFound  bool
+	}{
+		{name: "exact", items: users, query: "Иван Петров", want: 1},
+		{name: "case and spaces", items: users, query: "  иван   петров ", want: 1},
+		{name: "ё as е", items: users, query: "петр иванов", want: 2},
+		{name: "first name", items: users, query: "иван", want: 1},
+		{name: "surname", items: users, query: "Петров", want: 1},
+		{name: "transliteration", items: users, query: "Ivan Petrov", want: 1},
+		{name: "cyrillic to latin", items: users, query: "Алексей", want: 4},
+		{name: "prefix", items: statuses, query: "перегов", want: 11},
+		{name: "lowercase status", items: statuses, query: "переговоры", want: 11},
+		{name: "typo", items: statuses, query: "Переговры", typo: true, want: 11},
+		{name: "near-miss surname", items: users, query: "Иванова", typo: true, want: 2},
+		{name: "near-miss similar surname", items: users, query: "Иванцова", typo: true, want: 5},
+		{name: "near-miss full name", items: users, query: "Олег Иванцова", typo: true, want: 5},
+		{name: "exact similar surname", items: users, query: "Иванцов", want: 5},
+		{name: "first name as word", items: users, query: "петр", want: 2},
+		{name: "ambiguous prefix", items: users, query: "пет", ambiguous: true},
+		{name: "ambiguous status", items: statuses, query: "пер", ambiguous: true},
+		{name: "not found", items: users, query: "Сидоров", notFound: true},
+		{name: "same exact name", items: append(users, Named{ID: 6, Name: "Иван Петров"}), query: "Иван Петров", ambiguous: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Match(tt.query, tt.items)
+			var ambiguous *AmbiguousError
+			switch {
+			case tt.typo:
+				if !errors.As(err, &ambiguous) || !ambiguous.Typo || ambiguous.Candidates[0].ID != tt.want {
+					t.Fatalf("Match(%q) = %d, %v; want a suggestion of %d", tt.query, got, err, tt.want)
+				}
+			case tt.ambiguous:
+				if !errors.As(err, &ambiguous) || ambiguous.Typo || len(ambiguous.Candidates) < 2 {
+					t.Fatalf("Match(%q) = %d, %v; want ambiguity", tt.query, got, err)
+				}
+			case tt.notFound:
+				if !errors.Is(err, ErrNotFound) {
+					t.Fatalf("Match(%q) = %d, %v; want ErrNotFound", tt.query, got, err)
+				}
+			case err != nil || got != tt.want:
+				t.Fatalf("Match(%q) = %d, %v; want %d", tt.query, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmbiguousErrorSameNames(t *testing.T) {
+	_, err := Match("Иван Петров", []Named{{ID: 1, Name: "Иван Петров"}, {ID: 6, Name: "Иван Петров"}, {ID: 3, Name: "Анна Петрова"}})
+	want := `"Иван Петров" подходит к нескольким значениям: Иван Петров (ID 1), Иван Петров (ID 6). Укажи название точнее`
+	if err == nil || err.Error() != want {
+		t.Errorf("err = %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,80 @@
+package account_context
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Reload заново загружает справочники из API и атомарно подменяет кеш.
+// Если не загрузились пользователи или воронки, кеш не меняется и возвращается ошибка;
+// необязательные справочники, которые не удалось загрузить, остаются прежними.
+// Одновременные вызовы выполняются по очереди.
+func (ac *AccountContext) Reload(ctx context.Context) error {
+	if ac.fetch == nil {
+		return errors.New("account_context: reload: no loader, data was set directly")
+	}
+
+	ac.reloading.Lock()
+	defer ac.reloading.Unlock()
+
+	data, err := ac.fetch(ctx)
+	if err != nil {
+		err = fmt.Errorf("account_context: reload: %w", err)
+		ac.mu.Lock()
+		ac.lastErr = err
+		ac.mu.Unlock()
+		return err
+	}
+	ac.Set(keepMissing(data, ac.index().data))
+	return nil
+}
+
+// Refresh перезагружает справочники после изменения настроек аккаунта (admin-инструменты);
+// ошибка только логируется — прежние справочники продолжают работать.
+func (ac *AccountContext) Refresh(ctx context.Context) {
+	if err := ac.Reload(ctx); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+}
+
+// Run перезагружает справочники каждые ttl, пока не отменён ctx. ttl <= 0 — не обновлять.
+func (ac *AccountContext) Run(ctx context.Context, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	ticker := time.NewTicker(ttl)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ac.Refresh(ctx)
+		}
+	}
+}
+
+// keepMissing дополняет свежие справочники старыми там, где загрузка не удалась.
+func keepMissing(fresh, old Data) Data {
+	if fresh.CustomFields == nil {
+		fresh.CustomFields = make(map[string][]CustomField, len(old.CustomFields))
+	}
+	for entityType, fields := range old.CustomFields {
+		if _, ok := fresh.CustomFields[entityType]; !ok {
+			fresh.CustomFields[entityType] = fields
+		}
+	}
+	if fresh.Sources == nil {
+		fresh.Sources = old.Sources
+	}
+	if fresh.LossReasons == nil {
+		fresh.LossReasons = old.LossReasons
+	}
+	if fresh.CustomerStatuses == nil {
+		fresh.CustomerStatuses = old.CustomerStatuses
+	}
+	return fresh
+}
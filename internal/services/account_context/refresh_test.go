@@ -0,0 +1,180 @@
+package account_context
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fetchSequence отдаёт справочники по очереди и считает вызовы; после конца списка повторяет последний.
+type fetchSequence struct {
+	mu     sync.Mutex
+	calls  int
+	data   []Data
+	errs   []error
+	called chan struct{}
+}
+
+func (f *fetchSequence) fetch(context.Context) (Data, error) {
+	f.mu.Lock()
+	i := min(f.calls, len(f.data)-1)
+	f.calls++
+	f.mu.Unlock()
+	if f.called != nil {
+		select {
+		case f.called <- struct{}{}:
+		default:
+		}
+	}
+	var err error
+	if i < len(f.errs) {
+		err = f.errs[i]
+	}
+	return f.data[i], err
+}
+
+func usersData(names ...string) Data {
+	data := Data{Pipelines: []Pipeline{{ID: 1, Name: "Продажи"}}}
+	for i, name := range names {
+		data.Users = append(data.Users, Named{ID: i + 1, Name: name})
+	}
+	return data
+}
+
+func TestReloadSwapsIndex(t *testing.T) {
+	ac := New(usersData("Иван Петров"))
+	before := ac.LoadedAt()
+	seq := &fetchSequence{data: []Data{usersData("Анна Смирнова", "Олег Иванцов")}}
+	ac.fetch = seq.fetch
+
+	time.Sleep(time.Millisecond)
+	if err := ac.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := ac.UserID("Олег Иванцов"); err != nil || id != 2 {
+		t.Errorf("UserID(new user) = %d, %v", id, err)
+	}
+	if _, err := ac.UserID("Иван Петров"); err == nil {
+		t.Error("old user still resolves")
+	}
+	if name := ac.UserName(1); name != "Анна Смирнова" {
+		t.Errorf("UserName(1) = %q", name)
+	}
+	if !ac.LoadedAt().After(before) {
+		t.Error("LoadedAt was not updated")
+	}
+}
+
+func TestReloadFailureKeepsIndex(t *testing.T) {
+	ac := New(usersData("Иван Петров"))
+	seq := &fetchSequence{
+		data: []Data{{}, usersData("Анна Смирнова")},
+		errs: []error{errors.New("users: 503")},
+	}
+	ac.fetch = seq.fetch
+	loadedAt := ac.LoadedAt()
+
+	if err := ac.Reload(context.Background()); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("err = %v, want the fetch error", err)
+	}
+	if id, err := ac.UserID("Иван Петров"); err != nil || id != 1 {
+		t.Errorf("old data lost after a failed reload: %d, %v", id, err)
+	}
+	if !ac.LoadedAt().Equal(loadedAt) {
+		t.Error("LoadedAt changed after a failed reload")
+	}
+	if st := ac.Stats(); !strings.Contains(st.ReloadError, "503") {
+		t.Errorf("ReloadError = %q", st.ReloadError)
+	}
+
+	// Следующая удачная перезагрузка подменяет данные и сбрасывает ошибку
+	if err := ac.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ac.UserID("Анна Смирнова"); err != nil {
+		t.Errorf("new user: %v", err)
+	}
+	if st := ac.Stats(); st.ReloadError != "" {
+		t.Errorf("ReloadError = %q after a successful reload", st.ReloadError)
+	}
+}
+
+func TestReloadWithoutLoader(t *testing.T) {
+	if err := New(usersData("Иван Петров")).Reload(context.Background()); err == nil {
+		t.Error("reload of data set directly must fail")
+	}
+}
+
+func TestReloadWhileReading(t *testing.T) {
+	ac := New(usersData("Иван Петров"))
+	seq := &fetchSequence{data: []Data{usersData("Иван Петров", "Анна Смирнова")}}
+	ac.fetch = seq.fetch
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				// Читатели видят либо старый индекс, либо новый, но всегда целый
+				if id, err := ac.UserID("Иван Петров"); err != nil || id != 1 {
+					t.Errorf("UserID during reload = %d, %v", id, err)
+					return
+				}
+			}
+		}()
+	}
+	for range 20 {
+		if err := ac.Reload(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancel()
+	wg.Wait()
+}
+
+func TestRunReloadsEveryTTL(t *testing.T) {
+	ac := New(usersData("Иван Петров"))
+	seq := &fetchSequence{data: []Data{usersData("Анна Смирнова")}, called: make(chan struct{}, 1)}
+	ac.fetch = seq.fetch
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ac.Run(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	for range 2 {
+		select {
+		case <-seq.called:
+		case <-time.After(time.Second):
+			t.Fatal("no reload within a second")
+		}
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not stop after cancel")
+	}
+	if _, err := ac.UserID("Анна Смирнова"); err != nil {
+		t.Errorf("scheduled reload did not swap the data: %v", err)
+	}
+}
+
+func TestRunWithoutTTL(t *testing.T) {
+	ac := New(usersData("Иван Петров"))
+	seq := &fetchSequence{data: []Data{usersData("Анна Смирнова")}}
+	ac.fetch = seq.fetch
+
+	// ttl <= 0 — обновление выключено, Run сразу возвращается
+	ac.Run(context.Background(), 0)
+	if seq.calls != 0 {
+		t.Errorf("fetch called %d times with ttl 0", seq.calls)
+	}
+}
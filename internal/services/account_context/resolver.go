@@ -0,0 +1,213 @@
+package account_context
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Resolver переводит названия из справочников аккаунта в ID и обратно.
+// Названия ищутся нечётко (см. Match): «иван», «Петров» или «перегов» находят единственное
+// подходящее значение, при нескольких возвращается *AmbiguousError со списком кандидатов,
+// название с опечаткой («Иванова») — тоже *AmbiguousError: варианты предлагаются, но не выбираются.
+// Пустое название резолвится в 0 без ошибки (необязательное поле), ID 0 — в пустую строку,
+// неизвестный ID — в "[unknown:ID]". Ошибки перечисляют доступные значения для нейронки.
+type Resolver interface {
+	UserID(name string) (int, error)
+	UserIDs(names []string) ([]int, error)
+	UserName(id int) string
+	UserNames() []string
+
+	PipelineID(name string) (int, error)
+	PipelineName(id int) string
+	PipelineNames() []string
+
+	// StatusID ищет статус внутри воронки.
+	StatusID(pipelineID int, name string) (int, error)
+	// StatusName ищет статус внутри воронки, при pipelineID 0 — во всех воронках.
+	StatusName(pipelineID, statusID int) string
+	// StatusesByPipeline возвращает pipeline_name → []status_name.
+	StatusesByPipeline() map[string][]string
+
+	LossReasonID(name string) (int, error)
+	LossReasonName(id int) string
+	LossReasonNames() []string
+
+	SourceID(name string) (int, error)
+	SourceName(id int) string
+	SourceNames() []string
+
+	CustomerStatusID(name string) (int, error)
+	CustomerStatusName(id int) string
+	CustomerStatusNames() []string
+
+	// CustomField ищет кастомное поле сущности по ID, коду или названию.
+	CustomField(entityType, key string) (CustomField, error)
+	// CustomFields возвращает схему кастомных полей сущности: названия, типы, варианты списков.
+	CustomFields(entityType string) []CustomField
+	// FieldValues переводит значения кастомных полей (ключ — название, код или ID) в формат API по типам полей.
+	FieldValues(entityType string, values map[string]any) ([]FieldValue, error)
+}
+
+var _ Resolver = (*AccountContext)(nil)
+
+// --- Пользователи ---
+
+func (ac *AccountContext) UserID(name string) (int, error) {
+	return ac.index().users.id(name, "пользователь %q не найден")
+}
+
+func (ac *AccountContext) UserIDs(names []string) ([]int, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	ids := make([]int, 0, len(names))
+	for _, name := range names {
+		id, err := ac.UserID(name)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (ac *AccountContext) UserName(id int) string {
+	return ac.index().users.name(id)
+}
+
+func (ac *AccountContext) UserNames() []string {
+	return slices.Clone(ac.index().users.sorted)
+}
+
+// --- Воронки и статусы ---
+
+func (ac *AccountContext) PipelineID(name string) (int, error) {
+	return ac.index().pipelines.id(name, "воронка %q не найдена")
+}
+
+func (ac *AccountContext) PipelineName(id int) string {
+	return ac.index().pipelines.name(id)
+}
+
+func (ac *AccountContext) PipelineNames() []string {
+	return slices.Clone(ac.index().pipelines.sorted)
+}
+
+func (ac *AccountContext) StatusID(pipelineID int, name string) (int, error) {
+	if name == "" {
+		return 0, nil
+	}
+	idx := ac.index()
+	statuses, ok := idx.statuses[pipelineID]
+	if !ok || len(statuses.byName) == 0 {
+		return 0, fmt.Errorf("статус %q не найден: воронка %q не содержит статусов", name, idx.pipelines.name(pipelineID))
+	}
+	id, err := statuses.matcher.match(name)
+	if errors.Is(err, ErrNotFound) {
+		return 0, fmt.Errorf("статус %q не найден в воронке %q. Доступные: %s",
+			name, idx.pipelines.name(pipelineID), strings.Join(statuses.sorted, ", "))
+	}
+	return id, err
+}
+
+func (ac *AccountContext) StatusName(pipelineID, statusID int) string {
+	if statusID == 0 {
+		return ""
+	}
+	idx := ac.index()
+	if statuses, ok := idx.statuses[pipelineID]; ok {
+		if name, ok := statuses.byID[statusID]; ok {
+			return name
+		}
+	}
+	if name, ok := idx.statusesByID[statusID]; ok {
+		return name
+	}
+	return unknown(statusID)
+}
+
+func (ac *AccountContext) StatusesByPipeline() map[string][]string {
+	idx := ac.index()
+	result := make(map[string][]string, len(idx.pipelines.byID))
+	for pipelineID, pipelineName := range idx.pipelines.byID {
+		result[pipelineName] = slices.Clone(idx.statuses[pipelineID].sorted)
+		if result[pipelineName] == nil {
+			result[pipelineName] = []string{}
+		}
+	}
+	return result
+}
+
+// --- Причины отказа, источники, статусы покупателей ---
+
+func (ac *AccountContext) LossReasonID(name string) (int, error) {
+	return ac.index().lossReasons.id(name, "причина отказа %q не найдена")
+}
+
+func (ac *AccountContext) LossReasonName(id int) string {
+	return ac.index().lossReasons.name(id)
+}
+
+func (ac *AccountContext) LossReasonNames() []string {
+	return slices.Clone(ac.index().lossReasons.sorted)
+}
+
+func (ac *AccountContext) SourceID(name string) (int, error) {
+	return ac.index().sources.id(name, "источник %q не найден")
+}
+
+func (ac *AccountContext) SourceName(id int) string {
+	return ac.index().sources.name(id)
+}
+
+func (ac *AccountContext) SourceNames() []string {
+	return slices.Clone(ac.index().sources.sorted)
+}
+
+func (ac *AccountContext) CustomerStatusID(name string) (int, error) {
+	return ac.index().customerStatuses.id(name, "статус покупателя %q не найден")
+}
+
+func (ac *AccountContext) CustomerStatusName(id int) string {
+	return ac.index().customerStatuses.name(id)
+}
+
+func (ac *AccountContext) CustomerStatusNames() []string {
+	return slices.Clone(ac.index().customerStatuses.sorted)
+}
+
+// --- Кастомные поля ---
+
+func (ac *AccountContext) CustomFields(entityType string) []CustomField {
+	return slices.Clone(ac.index().customFields[entityType])
+}
+
+// --- helpers ---
+
+// id резолвит название; notFound — формат ошибки с %q для названия.
+func (n names) id(name, notFound string) (int, error) {
+	if name == "" {
+		return 0, nil
+	}
+	id, err := n.matcher.match(name)
+	if errors.Is(err, ErrNotFound) {
+		return 0, fmt.Errorf(notFound+". Доступные: %s", name, strings.Join(n.sorted, ", "))
+	}
+	return id, err
+}
+
+func (n names) name(id int) string {
+	if id == 0 {
+		return ""
+	}
+	if name, ok := n.byID[id]; ok {
+		return name
+	}
+	return unknown(id)
+}
+
+func unknown(id int) string {
+	return fmt.Sprintf("[unknown:%d]", id)
+}
@@ -0,0 +1,23 @@
+package account_context
+
+import "github.com/alextixru/amocrm-sdk-go/core/models"
+
+// SDKFieldValues переводит значения кастомных полей (см. FieldValues) в модель SDK.
+func SDKFieldValues(values []FieldValue) []models.CustomFieldValue {
+	if len(values) == 0 {
+		return nil
+	}
+	result := make([]models.CustomFieldValue, 0, len(values))
+	for _, v := range values {
+		cfv := models.CustomFieldValue{FieldID: v.FieldID, FieldCode: v.FieldCode}
+		for _, item := range v.Values {
+			cfv.Values = append(cfv.Values, models.FieldValueElement{
+				Value:    item.Value,
+				EnumID:   item.EnumID,
+				EnumCode: item.EnumCode,
+			})
+		}
+		result = append(result, cfv)
+	}
+	return result
+}
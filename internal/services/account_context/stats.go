@@ -0,0 +1,100 @@
+package account_context
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Stats — сколько значений справочников в кеше и что не загрузилось при последнем обновлении.
+type Stats struct {
+	LoadedAt         time.Time
+	Users            int
+	Pipelines        int
+	Statuses         int
+	CustomFields     map[string]int // тип сущности → число полей
+	Sources          int
+	LossReasons      int
+	CustomerStatuses int
+	Failed           []string // необязательные справочники: в кеше прежние данные или их нет
+	ReloadError      string   // последняя перезагрузка не удалась целиком, работают данные от LoadedAt
+}
+
+// Stats возвращает сводку по кешу для логов и /status.
+func (ac *AccountContext) Stats() Stats {
+	ac.mu.RLock()
+	data, loadedAt, lastErr := ac.idx.data, ac.loadedAt, ac.lastErr
+	ac.mu.RUnlock()
+
+	st := Stats{
+		LoadedAt:         loadedAt,
+		Users:            len(data.Users),
+		Pipelines:        len(data.Pipelines),
+		CustomFields:     make(map[string]int, len(data.CustomFields)),
+		Sources:          len(data.Sources),
+		LossReasons:      len(data.LossReasons),
+		CustomerStatuses: len(data.CustomerStatuses),
+		Failed:           data.Failed,
+	}
+	for _, p := range data.Pipelines {
+		st.Statuses += len(p.Statuses)
+	}
+	for entityType, fields := range data.CustomFields {
+		st.CustomFields[entityType] = len(fields)
+	}
+	if lastErr != nil {
+		st.ReloadError = lastErr.Error()
+	}
+	return st
+}
+
+// String — сводка одной строкой для логов.
+func (st Stats) String() string {
+	var fields []string
+	for _, entityType := range st.entityTypes() {
+		fields = append(fields, fmt.Sprintf("%s=%d", entityType, st.CustomFields[entityType]))
+	}
+	s := fmt.Sprintf("users=%d pipelines=%d statuses=%d custom_fields(%s) sources=%d loss_reasons=%d customer_statuses=%d",
+		st.Users, st.Pipelines, st.Statuses, strings.Join(fields, " "), st.Sources, st.LossReasons, st.CustomerStatuses)
+	if len(st.Failed) > 0 {
+		s += fmt.Sprintf(" failed=%d", len(st.Failed))
+	}
+	return s
+}
+
+// Summary — сводка для пользователя (/status, /reload) в HTML Telegram: тексты ошибок API экранируются.
+func (ac *AccountContext) Summary() string {
+	st := ac.Stats()
+	var fields []string
+	for _, entityType := range st.entityTypes() {
+		fields = append(fields, fmt.Sprintf("%s %d", entityType, st.CustomFields[entityType]))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📚 Справочники amoCRM (загружены %s):\n", st.LoadedAt.Format("02.01 15:04"))
+	fmt.Fprintf(&sb, "• пользователи: %d\n• воронки: %d, статусы: %d\n", st.Users, st.Pipelines, st.Statuses)
+	fmt.Fprintf(&sb, "• кастомные поля: %s\n", strings.Join(fields, ", "))
+	fmt.Fprintf(&sb, "• источники: %d, причины отказа: %d, статусы покупателей: %d",
+		st.Sources, st.LossReasons, st.CustomerStatuses)
+	if len(st.Failed) > 0 {
+		sb.WriteString("\n⚠️ Не загрузились (работают прежние данные, если были):")
+		for _, f := range st.Failed {
+			sb.WriteString("\n  – " + html.EscapeString(f))
+		}
+	}
+	if st.ReloadError != "" {
+		sb.WriteString("\n⚠️ Последнее обновление не удалось: " + html.EscapeString(st.ReloadError))
+	}
+	return sb.String()
+}
+
+func (st Stats) entityTypes() []string {
+	types := make([]string, 0, len(st.CustomFields))
+	for entityType := range st.CustomFields {
+		types = append(types, entityType)
+	}
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,105 @@
+package account_context
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestKeepMissing(t *testing.T) {
+	old := Data{
+		Users:     []Named{{ID: 1, Name: "Иван Петров"}},
+		Pipelines: []Pipeline{{ID: 1, Name: "Продажи"}},
+		CustomFields: map[string][]CustomField{
+			"leads":    {{ID: 1, Name: "Бюджет проекта"}},
+			"contacts": {{ID: 2, Name: "Телефон"}},
+		},
+		Sources:          []Named{{ID: 1, Name: "Сайт"}},
+		LossReasons:      []Named{{ID: 1, Name: "Дорого"}},
+		CustomerStatuses: []Named{{ID: 1, Name: "Новый"}},
+	}
+	// Поля контактов и источники не загрузились; причин отказа в аккаунте больше нет
+	fresh := Data{
+		Users:            []Named{{ID: 2, Name: "Анна Смирнова"}},
+		Pipelines:        []Pipeline{{ID: 2, Name: "Партнёры"}},
+		CustomFields:     map[string][]CustomField{"leads": {{ID: 3, Name: "Срок"}}},
+		LossReasons:      []Named{},
+		CustomerStatuses: []Named{{ID: 2, Name: "Постоянный"}},
+		Failed:           []string{"custom_fields contacts: 503", "sources: 503"},
+	}
+
+	got := keepMissing(fresh, old)
+	if got.Users[0].ID != 2 || got.Pipelines[0].ID != 2 || got.CustomerStatuses[0].ID != 2 {
+		t.Error("loaded references were replaced with old ones")
+	}
+	if f := got.CustomFields["leads"]; len(f) != 1 || f[0].ID != 3 {
+		t.Errorf("leads fields = %+v, want the fresh ones", f)
+	}
+	if f := got.CustomFields["contacts"]; len(f) != 1 || f[0].ID != 2 {
+		t.Errorf("contacts fields = %+v, want the old ones", f)
+	}
+	if len(got.Sources) != 1 || got.Sources[0].Name != "Сайт" {
+		t.Errorf("sources = %+v, want the old ones", got.Sources)
+	}
+	if got.LossReasons == nil || len(got.LossReasons) != 0 {
+		t.Errorf("loss reasons = %+v: an empty list is loaded data, not a failure", got.LossReasons)
+	}
+	if len(got.Failed) != 2 {
+		t.Errorf("failed = %v", got.Failed)
+	}
+
+	// Ничего не загрузилось из необязательного — всё прежнее
+	got = keepMissing(Data{Users: fresh.Users, Pipelines: fresh.Pipelines}, old)
+	if len(got.CustomFields) != 2 || got.Sources == nil || got.LossReasons == nil || got.CustomerStatuses == nil {
+		t.Errorf("old references lost: %+v", got)
+	}
+}
+
+func TestStats(t *testing.T) {
+	ac := New(Data{
+		Users: []Named{{ID: 1, Name: "Иван Петров"}, {ID: 2, Name: "Анна Смирнова"}},
+		Pipelines: []Pipeline{
+			{ID: 1, Name: "Продажи", Statuses: []Named{{ID: 10, Name: "Новая"}, {ID: 11, Name: "Переговоры"}}},
+			{ID: 2, Name: "Партнёры", Statuses: []Named{{ID: 20, Name: "Новая"}}},
+		},
+		CustomFields: map[string][]CustomField{
+			"leads":    {{ID: 1, Name: "Бюджет"}, {ID: 2, Name: "Срок"}},
+			"contacts": {{ID: 3, Name: "Телефон"}},
+		},
+		LossReasons: []Named{{ID: 1, Name: "Дорого"}},
+		Failed:      []string{"sources: <html>502 Bad Gateway</html>"},
+	})
+
+	st := ac.Stats()
+	if st.Users != 2 || st.Pipelines != 2 || st.Statuses != 3 || st.Sources != 0 || st.LossReasons != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+	if st.CustomFields["leads"] != 2 || st.CustomFields["contacts"] != 1 {
+		t.Errorf("custom fields = %v", st.CustomFields)
+	}
+	if st.ReloadError != "" || len(st.Failed) != 1 {
+		t.Errorf("failed = %v, reload error = %q", st.Failed, st.ReloadError)
+	}
+	if s := st.String(); !strings.Contains(s, "custom_fields(contacts=1 leads=2)") || !strings.Contains(s, "failed=1") {
+		t.Errorf("String() = %q", s)
+	}
+
+	ac.fetch = func(context.Context) (Data, error) { return Data{}, errors.New(`users: "a" < "b"`) }
+	if err := ac.Reload(context.Background()); err == nil {
+		t.Fatal("want a reload error")
+	}
+
+	// Тексты ошибок API попадают в HTML-сообщение только экранированными
+	summary := ac.Summary()
+	for _, raw := range []string{"<html>", `"a" < "b"`} {
+		if strings.Contains(summary, raw) {
+			t.Errorf("summary contains raw %q:\n%s", raw, summary)
+		}
+	}
+	for _, escaped := range []string{"&lt;html&gt;502 Bad Gateway", "&#34;a&#34; &lt; &#34;b&#34;"} {
+		if !strings.Contains(summary, escaped) {
+			t.Errorf("summary does not contain %q:\n%s", escaped, summary)
+		}
+	}
+}
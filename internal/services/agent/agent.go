@@ -1,10 +1,72 @@
 // Package agent defines the interface for AI agent implementations.
 package agent
 
-import "context"
+import (
+	"context"
+	"iter"
+	"time"
+)
 
 // Processor is the interface for processing user messages through an AI agent.
 // Telegram service depends on this interface, not a concrete implementation.
 type Processor interface {
 	Process(ctx context.Context, userID, sessionID, message string) (string, error)
 }
+
+// StreamEventKind describes what a StreamEvent carries.
+type StreamEventKind int
+
+const (
+	// StreamEventText carries the answer text accumulated so far.
+	StreamEventText StreamEventKind = iota
+	// StreamEventToolCall reports that the agent started a tool call.
+	StreamEventToolCall
+	// StreamEventToolResult carries the result of a tool call.
+	StreamEventToolResult
+	// StreamEventDone carries the complete final answer. It is always the last event.
+	StreamEventDone
+)
+
+// StreamEvent is a single progress update of a streaming agent run.
+type StreamEvent struct {
+	Kind StreamEventKind
+
+	// Text is the full answer accumulated so far (StreamEventText)
+	// or the final answer (StreamEventDone).
+	Text string
+
+	// ToolName and ToolArgs describe the tool call (StreamEventToolCall),
+	// ToolName and ToolResult — its result (StreamEventToolResult).
+	// ToolCallID matches a result to its call.
+	ToolCallID string
+	ToolName   string
+	ToolArgs   map[string]any
+	ToolResult map[string]any
+}
+
+// StreamProcessor is a Processor that can report progress while the agent is running.
+type StreamProcessor interface {
+	Processor
+	ProcessStream(ctx context.Context, userID, sessionID, message string) iter.Seq2[StreamEvent, error]
+}
+
+// SessionInfo describes a stored conversation.
+type SessionInfo struct {
+	ID        string
+	Title     string // first user message of the conversation
+	UpdatedAt time.Time
+}
+
+// SessionManager is implemented by agents that can list and manage stored conversations.
+type SessionManager interface {
+	// ListSessions returns sessions of the user whose IDs start with prefix, most recent first.
+	ListSessions(ctx context.Context, userID, prefix string) ([]SessionInfo, error)
+	CreateSession(ctx context.Context, userID, sessionID string) error
+	DeleteSession(ctx context.Context, userID, sessionID string) error
+}
+
+// CancellationRecorder is implemented by agents that can note in a session
+// that the user interrupted a run, so the next turn knows the last request was not completed.
+type CancellationRecorder interface {
+	RecordCancellation(ctx context.Context, userID, sessionID string) error
+}
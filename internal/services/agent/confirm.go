@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrConfirmationTimeout is returned by a Confirmer when the user did not answer in time.
+var ErrConfirmationTimeout = errors.New("confirmation timed out")
+
+// Confirmation describes a destructive or bulk action waiting for the user's approval.
+type Confirmation struct {
+	Tool    string // tool name, e.g. "products"
+	Action  string // tool action, e.g. "delete"
+	Summary string // human-readable description shown to the user
+	// Reversible actions are asked about without the "cannot be undone" warning.
+	Reversible bool
+}
+
+// Confirmer asks the user to approve an action and blocks until they answer.
+// approved is false when the user declined; err is ErrConfirmationTimeout
+// when nobody answered in time.
+type Confirmer interface {
+	Confirm(ctx context.Context, c Confirmation) (approved bool, err error)
+}
+
+type confirmerKey struct{}
+
+// ContextWithConfirmer binds a Confirmer to the context of an agent run.
+func ContextWithConfirmer(ctx context.Context, c Confirmer) context.Context {
+	return context.WithValue(ctx, confirmerKey{}, c)
+}
+
+// ConfirmerFromContext returns the Confirmer bound to the context, if any.
+func ConfirmerFromContext(ctx context.Context) (Confirmer, bool) {
+	c, ok := ctx.Value(confirmerKey{}).(Confirmer)
+	return c, ok
+}
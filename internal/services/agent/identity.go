@@ -0,0 +1,25 @@
+package agent
+
+import "context"
+
+// Identity is the user on whose behalf the agent runs.
+type Identity struct {
+	TelegramUserID int64
+	AmoUserID      int    // 0 if the user is not bound to an amoCRM user
+	AmoUserName    string // amoCRM user name, used as responsible_user_name
+	Email          string
+	Role           string // permission profile, see access.Role; empty — no restrictions
+}
+
+type identityKey struct{}
+
+// ContextWithIdentity binds the current user to the context of an agent run.
+func ContextWithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFromContext returns the user bound to the context, if any.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
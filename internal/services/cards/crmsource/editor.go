@@ -0,0 +1,145 @@
+package crmsource
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	gkitmodels "github.com/tihn/amo-ai-tgbot-go/internal/models/tools"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/cards"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/crm/activities"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/crm/entities"
+)
+
+// newTaskDeadline is the deadline of tasks added from a card.
+const newTaskDeadline = "tomorrow"
+
+// crmEditor changes records directly through the CRM services, without the LLM.
+type crmEditor struct {
+	entities   entities.Service
+	activities activities.Service
+	baseURL    string
+}
+
+// NewEditor creates a cards.Editor. baseURL is the amoCRM account URL used for links to records.
+func NewEditor(entitiesSvc entities.Service, activitiesSvc activities.Service, baseURL string) cards.Editor {
+	return &crmEditor{
+		entities:   entitiesSvc,
+		activities: activitiesSvc,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// Card implements cards.Editor.
+func (e *crmEditor) Card(ctx context.Context, entityType cards.EntityType, id int) (*cards.Card, error) {
+	var (
+		item *entities.EntityResult
+		err  error
+	)
+	switch entityType {
+	case cards.Leads:
+		item, err = e.entities.GetLead(ctx, id, nil)
+	case cards.Contacts:
+		item, err = e.entities.GetContact(ctx, id, nil)
+	case cards.Companies:
+		item, err = e.entities.GetCompany(ctx, id, nil)
+	default:
+		return nil, fmt.Errorf("unknown entity type %q", entityType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, cards.ErrNotFound
+	}
+	card := toCard(e.baseURL, entityType, item)
+	return &card, nil
+}
+
+// Task implements cards.Editor.
+func (e *crmEditor) Task(ctx context.Context, id int) (*cards.Task, error) {
+	t, err := e.activities.GetTask(ctx, id, nil)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, cards.ErrNotFound
+	}
+	task := &cards.Task{
+		ID:            t.ID,
+		Text:          t.Text,
+		EntityType:    cards.EntityType(t.EntityType),
+		EntityID:      t.EntityID,
+		Deadline:      t.Deadline,
+		Responsible:   t.ResponsibleUserName,
+		ResponsibleID: t.ResponsibleUserID,
+		Completed:     t.IsCompleted,
+	}
+	if t.Result != nil {
+		task.Result = t.Result.Text
+	}
+	if slices.Contains(cards.EntityTypes, task.EntityType) && task.EntityID != 0 {
+		task.URL = recordURL(e.baseURL, task.EntityType, task.EntityID)
+	}
+	return task, nil
+}
+
+// Statuses implements cards.Editor. The CRM service keeps statuses in a map,
+// so they are sorted to give buttons a stable order.
+func (e *crmEditor) Statuses(pipeline string) []string {
+	statuses := slices.Clone(e.entities.StatusesByPipeline()[pipeline])
+	slices.Sort(statuses)
+	return statuses
+}
+
+// Users implements cards.Editor.
+func (e *crmEditor) Users() []string {
+	users := slices.Clone(e.entities.UserNames())
+	slices.Sort(users)
+	return users
+}
+
+// SetStatus implements cards.Editor.
+func (e *crmEditor) SetStatus(ctx context.Context, leadID int, pipeline, status string) error {
+	_, err := e.entities.UpdateLead(ctx, leadID, &gkitmodels.EntityData{PipelineName: pipeline, StatusName: status})
+	return err
+}
+
+// SetResponsible implements cards.Editor.
+func (e *crmEditor) SetResponsible(ctx context.Context, entityType cards.EntityType, id int, user string) error {
+	data := &gkitmodels.EntityData{ResponsibleUserName: user}
+	var err error
+	switch entityType {
+	case cards.Leads:
+		_, err = e.entities.UpdateLead(ctx, id, data)
+	case cards.Contacts:
+		_, err = e.entities.UpdateContact(ctx, id, data)
+	case cards.Companies:
+		_, err = e.entities.UpdateCompany(ctx, id, data)
+	default:
+		err = fmt.Errorf("unknown entity type %q", entityType)
+	}
+	return err
+}
+
+// AddTask implements cards.Editor.
+func (e *crmEditor) AddTask(ctx context.Context, entityType cards.EntityType, id int, text, responsible string) error {
+	parent := gkitmodels.ParentEntity{Type: string(entityType), ID: id}
+	data := &gkitmodels.TaskData{Text: text, ResponsibleUserName: responsible, Deadline: newTaskDeadline}
+	_, err := e.activities.CreateTask(ctx, parent, data)
+	return err
+}
+
+// AddNote implements cards.Editor.
+func (e *crmEditor) AddNote(ctx context.Context, entityType cards.EntityType, id int, text string) error {
+	parent := gkitmodels.ParentEntity{Type: string(entityType), ID: id}
+	_, err := e.activities.CreateNote(ctx, parent, &gkitmodels.NoteData{Text: text, NoteType: "common"})
+	return err
+}
+
+// CompleteTask implements cards.Editor.
+func (e *crmEditor) CompleteTask(ctx context.Context, id int) error {
+	_, err := e.activities.CompleteTask(ctx, id, "")
+	return err
+}
@@ -0,0 +1,75 @@
+// Package crmsource provides cards.Source and cards.Editor backed by the amoCRM services.
+package crmsource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	gkitmodels "github.com/tihn/amo-ai-tgbot-go/internal/models/tools"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/cards"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/crm/entities"
+)
+
+// entitiesSource searches records directly through entities.Service, without the LLM.
+type entitiesSource struct {
+	entities entities.Service
+	baseURL  string
+}
+
+// New creates a cards.Source. baseURL is the amoCRM account URL used for links to records.
+func New(entitiesSvc entities.Service, baseURL string) cards.Source {
+	return &entitiesSource{entities: entitiesSvc, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Search implements cards.Source.
+func (s *entitiesSource) Search(ctx context.Context, entityType cards.EntityType, query string, limit int) ([]cards.Card, error) {
+	filter := &gkitmodels.EntitiesFilter{Query: query, Limit: limit}
+
+	var (
+		result *entities.SearchResult
+		err    error
+	)
+	switch entityType {
+	case cards.Leads:
+		result, err = s.entities.SearchLeads(ctx, filter, nil)
+	case cards.Contacts:
+		result, err = s.entities.SearchContacts(ctx, filter, nil)
+	case cards.Companies:
+		result, err = s.entities.SearchCompanies(ctx, filter, nil)
+	default:
+		return nil, fmt.Errorf("unknown entity type %q", entityType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]cards.Card, 0, len(result.Items))
+	for _, item := range result.Items {
+		found = append(found, toCard(s.baseURL, entityType, item))
+	}
+	return found, nil
+}
+
+func toCard(baseURL string, entityType cards.EntityType, item *entities.EntityResult) cards.Card {
+	name := item.Name
+	if name == "" {
+		name = strings.TrimSpace(item.FirstName + " " + item.LastName)
+	}
+	return cards.Card{
+		Type:          entityType,
+		ID:            item.ID,
+		Name:          name,
+		Price:         item.Price,
+		Pipeline:      item.PipelineName,
+		Status:        item.StatusName,
+		Responsible:   item.ResponsibleUserName,
+		ResponsibleID: item.ResponsibleUserID,
+		URL:           recordURL(baseURL, entityType, item.ID),
+	}
+}
+
+// recordURL links to a record in amoCRM web interface.
+func recordURL(baseURL string, entityType cards.EntityType, id int) string {
+	return fmt.Sprintf("%s/%s/detail/%d", baseURL, entityType, id)
+}
@@ -0,0 +1,117 @@
+package cards
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is returned by Editor when the record or task does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Task is a short description of an amoCRM task.
+type Task struct {
+	ID            int
+	Text          string
+	EntityType    EntityType // the record the task belongs to, empty for tasks without one
+	EntityID      int
+	Deadline      string
+	Responsible   string
+	ResponsibleID int // amoCRM user ID of Responsible, for permission checks
+	Completed     bool
+	Result        string
+	URL           string // the parent record in amoCRM web interface
+}
+
+// Editor reads single records and changes them from card buttons.
+type Editor interface {
+	Card(ctx context.Context, entityType EntityType, id int) (*Card, error)
+	Task(ctx context.Context, id int) (*Task, error)
+	// Statuses returns the statuses of a pipeline and Users the amoCRM users, both in a stable order.
+	Statuses(pipeline string) []string
+	Users() []string
+
+	SetStatus(ctx context.Context, leadID int, pipeline, status string) error
+	SetResponsible(ctx context.Context, entityType EntityType, id int, user string) error
+	// AddTask creates a task due tomorrow; empty responsible leaves the amoCRM default.
+	AddTask(ctx context.Context, entityType EntityType, id int, text, responsible string) error
+	AddNote(ctx context.Context, entityType EntityType, id int, text string) error
+	CompleteTask(ctx context.Context, id int) error
+}
+
+// Card returns a fresh card of the record.
+func (s *Service) Card(ctx context.Context, entityType EntityType, id int) (*Card, error) {
+	card, err := s.editor.Card(ctx, entityType, id)
+	if err != nil {
+		return nil, fmt.Errorf("cards: get %s %d: %w", entityType, id, err)
+	}
+	return card, nil
+}
+
+// Task returns a fresh card of the task.
+func (s *Service) Task(ctx context.Context, id int) (*Task, error) {
+	task, err := s.editor.Task(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("cards: get task %d: %w", id, err)
+	}
+	return task, nil
+}
+
+// Statuses returns the statuses of a lead pipeline.
+func (s *Service) Statuses(pipeline string) []string {
+	return s.editor.Statuses(pipeline)
+}
+
+// Users returns the names of amoCRM users.
+func (s *Service) Users() []string {
+	return s.editor.Users()
+}
+
+// SetStatus moves a lead to another status of its pipeline.
+func (s *Service) SetStatus(ctx context.Context, leadID int, pipeline, status string) error {
+	defer s.invalidate()
+	if err := s.editor.SetStatus(ctx, leadID, pipeline, status); err != nil {
+		return fmt.Errorf("cards: set status of lead %d: %w", leadID, err)
+	}
+	return nil
+}
+
+// SetResponsible reassigns a record to another user.
+func (s *Service) SetResponsible(ctx context.Context, entityType EntityType, id int, user string) error {
+	defer s.invalidate()
+	if err := s.editor.SetResponsible(ctx, entityType, id, user); err != nil {
+		return fmt.Errorf("cards: set responsible of %s %d: %w", entityType, id, err)
+	}
+	return nil
+}
+
+// AddTask creates a task for the record.
+func (s *Service) AddTask(ctx context.Context, entityType EntityType, id int, text, responsible string) error {
+	if err := s.editor.AddTask(ctx, entityType, id, text, responsible); err != nil {
+		return fmt.Errorf("cards: add task to %s %d: %w", entityType, id, err)
+	}
+	return nil
+}
+
+// AddNote adds a note to the record.
+func (s *Service) AddNote(ctx context.Context, entityType EntityType, id int, text string) error {
+	if err := s.editor.AddNote(ctx, entityType, id, text); err != nil {
+		return fmt.Errorf("cards: add note to %s %d: %w", entityType, id, err)
+	}
+	return nil
+}
+
+// CompleteTask marks the task as done.
+func (s *Service) CompleteTask(ctx context.Context, id int) error {
+	if err := s.editor.CompleteTask(ctx, id); err != nil {
+		return fmt.Errorf("cards: complete task %d: %w", id, err)
+	}
+	return nil
+}
+
+// invalidate drops cached search results, so that inline cards show the change right away.
+func (s *Service) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.cache)
+}
@@ -0,0 +1,143 @@
+// Package cards finds amoCRM leads, contacts and companies for quick lookups
+// outside the AI agent (inline mode), caches the results and performs
+// the actions offered by card buttons.
+package cards
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EntityType is an amoCRM entity a card describes.
+type EntityType string
+
+const (
+	Leads     EntityType = "leads"
+	Contacts  EntityType = "contacts"
+	Companies EntityType = "companies"
+)
+
+// EntityTypes lists searched entity types in the order results are shown.
+var EntityTypes = []EntityType{Leads, Contacts, Companies}
+
+// Card is a short description of an amoCRM record.
+type Card struct {
+	Type          EntityType
+	ID            int
+	Name          string
+	Price         int    // leads only
+	Pipeline      string // leads only
+	Status        string // leads only
+	Responsible   string
+	ResponsibleID int    // amoCRM user ID of Responsible, for permission checks
+	URL           string // the record in amoCRM web interface
+}
+
+// Source searches amoCRM records.
+type Source interface {
+	Search(ctx context.Context, entityType EntityType, query string, limit int) ([]Card, error)
+}
+
+const (
+	// perTypeLimit is how many records of each type a search returns.
+	perTypeLimit = 10
+	// cacheTTL — typing "@bot Альфа" sends a query per keystroke, repeated ones are served from cache.
+	cacheTTL = time.Minute
+	// maxCacheEntries bounds the cache; expired entries are dropped when it is full.
+	maxCacheEntries = 1000
+)
+
+type cacheEntry struct {
+	cards   []Card
+	expires time.Time
+}
+
+// Service searches all entity types at once and caches results by query.
+type Service struct {
+	source Source
+	editor Editor
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// New creates a Service over the source and editor.
+func New(source Source, editor Editor) *Service {
+	return &Service{source: source, editor: editor, cache: make(map[string]cacheEntry)}
+}
+
+// Search finds leads, contacts and companies matching the query, leads first.
+// If some entity types fail, the others are still returned; an error is returned
+// only when every search failed.
+func (s *Service) Search(ctx context.Context, query string) ([]Card, error) {
+	key := strings.ToLower(strings.Join(strings.Fields(query), " "))
+	if cards, ok := s.cached(key); ok {
+		return cards, nil
+	}
+
+	results := make([][]Card, len(EntityTypes))
+	errs := make([]error, len(EntityTypes))
+	var wg sync.WaitGroup
+	for i, entityType := range EntityTypes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = s.source.Search(ctx, entityType, query, perTypeLimit)
+		}()
+	}
+	wg.Wait()
+
+	var cards []Card
+	var failed []error
+	for i, entityType := range EntityTypes {
+		if errs[i] != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", entityType, errs[i]))
+			continue
+		}
+		cards = append(cards, results[i]...)
+	}
+	switch {
+	case len(failed) == len(EntityTypes):
+		return nil, fmt.Errorf("cards: search %q: %w", query, errors.Join(failed...))
+	case len(failed) > 0:
+		// Partial results are not cached, so the next keystroke retries the failed types
+		log.Printf("⚠️ cards: search %q partially failed: %v", query, errors.Join(failed...))
+		return cards, nil
+	}
+
+	s.store(key, cards)
+	return cards, nil
+}
+
+func (s *Service) cached(key string) ([]Card, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.cache[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.cards, true
+}
+
+func (s *Service) store(key string, cards []Card) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if len(s.cache) >= maxCacheEntries {
+		for k, entry := range s.cache {
+			if now.After(entry.expires) {
+				delete(s.cache, k)
+			}
+		}
+		if len(s.cache) >= maxCacheEntries {
+			clear(s.cache)
+		}
+	}
+	s.cache[key] = cacheEntry{cards: cards, expires: now.Add(cacheTTL)}
+}
@@ -0,0 +1,164 @@
+package cards
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// fakeSource returns cards per entity type and counts searches.
+type fakeSource struct {
+	mu       sync.Mutex
+	cards    map[EntityType][]Card
+	failing  map[EntityType]bool
+	searches int
+}
+
+func (f *fakeSource) Search(_ context.Context, entityType EntityType, _ string, _ int) ([]Card, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.searches++
+	if f.failing[entityType] {
+		return nil, errors.New("unavailable")
+	}
+	return f.cards[entityType], nil
+}
+
+// fakeEditor is an Editor over one lead.
+type fakeEditor struct {
+	Editor
+	lead   Card
+	status string
+}
+
+func (f *fakeEditor) Card(_ context.Context, entityType EntityType, id int) (*Card, error) {
+	if entityType != Leads || id != f.lead.ID {
+		return nil, ErrNotFound
+	}
+	card := f.lead
+	return &card, nil
+}
+
+func (f *fakeEditor) SetStatus(_ context.Context, leadID int, _, status string) error {
+	if leadID != f.lead.ID {
+		return ErrNotFound
+	}
+	f.status = status
+	return nil
+}
+
+func testSource() *fakeSource {
+	return &fakeSource{cards: map[EntityType][]Card{
+		Companies: {{Type: Companies, ID: 3, Name: "Альфа ООО"}},
+		Contacts:  {{Type: Contacts, ID: 2, Name: "Альфа Иван"}},
+		Leads:     {{Type: Leads, ID: 1, Name: "Альфа"}},
+	}}
+}
+
+func TestSearchOrderAndCache(t *testing.T) {
+	source := testSource()
+	s := New(source, &fakeEditor{})
+
+	got, err := s.Search(context.Background(), "Альфа")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []EntityType
+	for _, card := range got {
+		order = append(order, card.Type)
+	}
+	if len(order) != 3 || order[0] != Leads || order[1] != Contacts || order[2] != Companies {
+		t.Errorf("order = %v, want leads, contacts, companies", order)
+	}
+
+	// The same query with other case and spacing is served from cache
+	if _, err := s.Search(context.Background(), "  альфа "); err != nil {
+		t.Fatal(err)
+	}
+	if source.searches != len(EntityTypes) {
+		t.Errorf("searches = %d, want %d", source.searches, len(EntityTypes))
+	}
+}
+
+func TestSearchPartialFailure(t *testing.T) {
+	source := testSource()
+	source.failing = map[EntityType]bool{Contacts: true}
+	s := New(source, &fakeEditor{})
+
+	got, err := s.Search(context.Background(), "Альфа")
+	if err != nil {
+		t.Fatalf("partial failure must not fail the search: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("cards = %d, want 2", len(got))
+	}
+
+	// Partial results are not cached: the next search asks again and gets everything
+	source.failing = nil
+	got, err = s.Search(context.Background(), "Альфа")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || source.searches != 2*len(EntityTypes) {
+		t.Errorf("cards = %d, searches = %d", len(got), source.searches)
+	}
+}
+
+func TestSearchAllFailed(t *testing.T) {
+	source := testSource()
+	source.failing = map[EntityType]bool{Leads: true, Contacts: true, Companies: true}
+	s := New(source, &fakeEditor{})
+
+	if _, err := s.Search(context.Background(), "Альфа"); err == nil {
+		t.Fatal("search must fail when every type failed")
+	}
+	source.failing = nil
+	if got, err := s.Search(context.Background(), "Альфа"); err != nil || len(got) != 3 {
+		t.Errorf("after recovery: %d cards, %v", len(got), err)
+	}
+}
+
+func TestChangeDropsCache(t *testing.T) {
+	source := testSource()
+	editor := &fakeEditor{lead: Card{Type: Leads, ID: 1, Name: "Альфа", Pipeline: "Продажи"}}
+	s := New(source, editor)
+
+	if _, err := s.Search(context.Background(), "Альфа"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetStatus(context.Background(), 1, "Продажи", "Договор"); err != nil {
+		t.Fatal(err)
+	}
+	if editor.status != "Договор" {
+		t.Errorf("status = %q", editor.status)
+	}
+	if _, err := s.Search(context.Background(), "Альфа"); err != nil {
+		t.Fatal(err)
+	}
+	if source.searches != 2*len(EntityTypes) {
+		t.Errorf("searches = %d: a change must drop cached results", source.searches)
+	}
+
+	// A failed change drops the cache too: the record may have changed partly
+	if err := s.SetStatus(context.Background(), 9, "Продажи", "Договор"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Search(context.Background(), "Альфа"); err != nil {
+		t.Fatal(err)
+	}
+	if source.searches != 3*len(EntityTypes) {
+		t.Errorf("searches = %d after a failed change", source.searches)
+	}
+}
+
+func TestCardNotFound(t *testing.T) {
+	s := New(testSource(), &fakeEditor{lead: Card{Type: Leads, ID: 1}})
+
+	if card, err := s.Card(context.Background(), Leads, 1); err != nil || card.ID != 1 {
+		t.Errorf("card = %v, %v", card, err)
+	}
+	if _, err := s.Card(context.Background(), Contacts, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
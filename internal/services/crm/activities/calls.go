@@ -21,8 +21,8 @@ func (s *service) convertCall(c *models.Call) *CallOutput {
 		Source:              c.Source,
 		UniqueID:            c.Uniq,
 		RecordURL:           c.Link,
-		ResponsibleUserName: s.resolveUserID(c.ResponsibleUserID),
-		CreatedByName:       s.resolveUserID(c.CreatedBy),
+		ResponsibleUserName: s.resolver.UserName(c.ResponsibleUserID),
+		CreatedByName:       s.resolver.UserName(c.CreatedBy),
 		CreatedAt:           toISO(c.CreatedAt),
 	}
 }
@@ -22,7 +22,7 @@ func (s *service) convertEvent(e *models.Event) *EventOutput {
 		Type:          e.Type,
 		EntityID:      e.EntityID,
 		EntityType:    e.EntityType,
-		CreatedByName: s.resolveUserID(e.CreatedBy),
+		CreatedByName: s.resolver.UserName(e.CreatedBy),
 		CreatedAt:     toISO(e.CreatedAt),
 		ValueBefore:   e.ValueBefore,
 		ValueAfter:    e.ValueAfter,
@@ -45,7 +45,7 @@ func (s *service) ListEvents(ctx context.Context, parent *gkitmodels.ParentEntit
 		}
 		// Резолвинг created_by_names → IDs
 		if len(filter.CreatedByNames) > 0 {
-			ids, err := s.resolveUserNames(filter.CreatedByNames)
+			ids, err := s.resolver.UserIDs(filter.CreatedByNames)
 			if err != nil {
 				return nil, err
 			}
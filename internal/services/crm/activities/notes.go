@@ -16,8 +16,8 @@ func (s *service) convertNote(n *models.Note) *NoteOutput {
 		ID:            n.ID,
 		EntityID:      n.EntityID,
 		NoteType:      string(n.NoteType),
-		CreatedByName: s.resolveUserID(n.CreatedBy),
-		UpdatedByName: s.resolveUserID(n.UpdatedBy),
+		CreatedByName: s.resolver.UserName(n.CreatedBy),
+		UpdatedByName: s.resolver.UserName(n.UpdatedBy),
 		CreatedAt:     toISO(n.CreatedAt),
 		UpdatedAt:     toISO(n.UpdatedAt),
 	}
@@ -15,6 +15,7 @@ type TaskOutput struct {
 	TaskType            string      `json:"task_type,omitempty"`
 	IsCompleted         bool        `json:"is_completed"`
 	Deadline            string      `json:"deadline,omitempty"`
+	ResponsibleUserID   int         `json:"-"` // для проверки прав, модели не отдаётся
 	ResponsibleUserName string      `json:"responsible_user_name,omitempty"`
 	CreatedByName       string      `json:"created_by_name,omitempty"`
 	UpdatedByName       string      `json:"updated_by_name,omitempty"`
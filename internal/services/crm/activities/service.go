@@ -2,11 +2,10 @@ package activities
 
 import (
 	"context"
-	"fmt"
-	"strings"
 
 	"github.com/alextixru/amocrm-sdk-go"
 	gkitmodels "github.com/tihn/amo-ai-tgbot-go/internal/models/tools"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/account_context"
 )
 
 // Service определяет бизнес-логику для работы с активностями amoCRM.
@@ -65,90 +64,16 @@ type Service interface {
 }
 
 type service struct {
-	sdk         *amocrm.SDK
-	usersByName map[string]int
-	usersByID   map[int]string
+	sdk      *amocrm.SDK
+	resolver account_context.Resolver
 }
 
-// New создает новый экземпляр сервиса активностей и загружает справочники.
-func New(ctx context.Context, sdk *amocrm.SDK) (Service, error) {
-	s := &service{
-		sdk:         sdk,
-		usersByName: make(map[string]int),
-		usersByID:   make(map[int]string),
-	}
-	if err := s.loadUsers(ctx); err != nil {
-		return nil, fmt.Errorf("activities: load users: %w", err)
-	}
-	return s, nil
-}
-
-// loadUsers загружает всех пользователей из SDK и строит индексы.
-func (s *service) loadUsers(ctx context.Context) error {
-	users, _, err := s.sdk.Users().Get(ctx, nil)
-	if err != nil {
-		return err
-	}
-	for _, u := range users {
-		if u == nil || u.Name == "" {
-			continue
-		}
-		s.usersByID[u.ID] = u.Name
-		// Регистрируем оба варианта: точное имя -> ID.
-		// Дубли обрабатываются в resolveUserName.
-		s.usersByName[u.Name] = u.ID
-	}
-	return nil
-}
-
-// resolveUserName переводит имя пользователя в ID.
-// Возвращает ошибку с подсказкой если имя не найдено.
-func (s *service) resolveUserName(name string) (int, error) {
-	if name == "" {
-		return 0, nil
-	}
-	id, ok := s.usersByName[name]
-	if !ok {
-		// Ищем частичное совпадение для лучшей подсказки
-		var available []string
-		for n := range s.usersByName {
-			available = append(available, n)
-		}
-		return 0, fmt.Errorf("пользователь '%s' не найден. Доступные: %s", name, strings.Join(available, ", "))
-	}
-	return id, nil
-}
-
-// resolveUserNames переводит слайс имён в слайс ID.
-func (s *service) resolveUserNames(names []string) ([]int, error) {
-	ids := make([]int, 0, len(names))
-	for _, name := range names {
-		id, err := s.resolveUserName(name)
-		if err != nil {
-			return nil, err
-		}
-		ids = append(ids, id)
-	}
-	return ids, nil
-}
-
-// resolveUserID переводит ID пользователя в имя.
-// При неизвестном ID возвращает "[unknown:ID]".
-func (s *service) resolveUserID(id int) string {
-	if id == 0 {
-		return ""
-	}
-	if name, ok := s.usersByID[id]; ok {
-		return name
-	}
-	return fmt.Sprintf("[unknown:%d]", id)
+// New создает новый экземпляр сервиса активностей; пользователи резолвятся через общий AccountContext.
+func New(sdk *amocrm.SDK, resolver account_context.Resolver) Service {
+	return &service{sdk: sdk, resolver: resolver}
 }
 
 // UserNames возвращает список доступных имён пользователей (для описаний tools).
 func (s *service) UserNames() []string {
-	names := make([]string, 0, len(s.usersByName))
-	for name := range s.usersByName {
-		names = append(names, name)
-	}
-	return names
+	return s.resolver.UserNames()
 }
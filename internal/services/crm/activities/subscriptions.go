@@ -19,14 +19,14 @@ func (s *service) ListSubscriptions(ctx context.Context, parent gkitmodels.Paren
 	for _, sub := range subs {
 		out.Subscriptions = append(out.Subscriptions, SubscriptionOutput{
 			SubscriberID:   sub.SubscriberID,
-			SubscriberName: s.resolveUserID(sub.SubscriberID),
+			SubscriberName: s.resolver.UserName(sub.SubscriberID),
 		})
 	}
 	return out, nil
 }
 
 func (s *service) Subscribe(ctx context.Context, parent gkitmodels.ParentEntity, userNames []string) (*SubscriptionsListOutput, error) {
-	ids, err := s.resolveUserNames(userNames)
+	ids, err := s.resolver.UserIDs(userNames)
 	if err != nil {
 		return nil, err
 	}
@@ -41,14 +41,14 @@ func (s *service) Subscribe(ctx context.Context, parent gkitmodels.ParentEntity,
 	for _, sub := range subs {
 		out.Subscriptions = append(out.Subscriptions, SubscriptionOutput{
 			SubscriberID:   sub.SubscriberID,
-			SubscriberName: s.resolveUserID(sub.SubscriberID),
+			SubscriberName: s.resolver.UserName(sub.SubscriberID),
 		})
 	}
 	return out, nil
 }
 
 func (s *service) Unsubscribe(ctx context.Context, parent gkitmodels.ParentEntity, userName string) error {
-	uid, err := s.resolveUserName(userName)
+	uid, err := s.resolver.UserID(userName)
 	if err != nil {
 		return err
 	}
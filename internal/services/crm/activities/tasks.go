@@ -58,9 +58,10 @@ func (s *service) convertTask(t *models.Task) *TaskOutput {
 		EntityType:          t.EntityType,
 		TaskType:            taskTypeIDToName(t.TaskTypeID),
 		IsCompleted:         t.IsCompleted,
-		ResponsibleUserName: s.resolveUserID(t.ResponsibleUserID),
-		CreatedByName:       s.resolveUserID(t.CreatedBy),
-		UpdatedByName:       s.resolveUserID(t.UpdatedBy),
+		ResponsibleUserID:   t.ResponsibleUserID,
+		ResponsibleUserName: s.resolver.UserName(t.ResponsibleUserID),
+		CreatedByName:       s.resolver.UserName(t.CreatedBy),
+		UpdatedByName:       s.resolver.UserName(t.UpdatedBy),
 		CreatedAt:           toISO(t.CreatedAt),
 		UpdatedAt:           toISO(t.UpdatedAt),
 	}
@@ -108,7 +109,7 @@ func (s *service) ListTasks(ctx context.Context, parent *gkitmodels.ParentEntity
 		}
 		// Resolve responsible user names → IDs
 		if len(filter.ResponsibleUserNames) > 0 {
-			ids, err := s.resolveUserNames(filter.ResponsibleUserNames)
+			ids, err := s.resolver.UserIDs(filter.ResponsibleUserNames)
 			if err != nil {
 				return nil, err
 			}
@@ -116,7 +117,7 @@ func (s *service) ListTasks(ctx context.Context, parent *gkitmodels.ParentEntity
 		}
 		// Resolve created_by names → IDs
 		if len(filter.CreatedByNames) > 0 {
-			ids, err := s.resolveUserNames(filter.CreatedByNames)
+			ids, err := s.resolver.UserIDs(filter.CreatedByNames)
 			if err != nil {
 				return nil, err
 			}
@@ -213,7 +214,7 @@ func (s *service) CreateTasks(ctx context.Context, parent gkitmodels.ParentEntit
 			}
 		}
 		if d.ResponsibleUserName != "" {
-			uid, err := s.resolveUserName(d.ResponsibleUserName)
+			uid, err := s.resolver.UserID(d.ResponsibleUserName)
 			if err != nil {
 				return nil, err
 			}
@@ -252,7 +253,7 @@ func (s *service) UpdateTask(ctx context.Context, id int, data *gkitmodels.TaskD
 		}
 	}
 	if data.ResponsibleUserName != "" {
-		uid, err := s.resolveUserName(data.ResponsibleUserName)
+		uid, err := s.resolver.UserID(data.ResponsibleUserName)
 		if err != nil {
 			return nil, err
 		}
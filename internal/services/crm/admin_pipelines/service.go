@@ -2,10 +2,12 @@ package admin_pipelines
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/alextixru/amocrm-sdk-go"
 	toolmodels "github.com/tihn/amo-ai-tgbot-go/internal/models/tools"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/account_context"
 )
 
 // Service определяет бизнес-логику для работы с воронками и статусами.
@@ -73,7 +75,7 @@ func New(sdk *amocrm.SDK) Service {
 	}
 }
 
-// resolvePipelineID возвращает ID воронки по имени (если id == 0).
+// resolvePipelineID возвращает ID воронки по имени (если id == 0), имя ищется нечётко (account_context.Match).
 // Возвращает ошибку с подсказкой о доступных воронках если имя не найдено.
 func (s *service) resolvePipelineID(ctx context.Context, id int, name string) (int, error) {
 	if id != 0 {
@@ -89,17 +91,20 @@ func (s *service) resolvePipelineID(ctx context.Context, id int, name string) (i
 	}
 
 	var available []string
+	items := make([]account_context.Named, 0, len(result.Pipelines))
 	for _, p := range result.Pipelines {
-		if p.Name == name {
-			return p.ID, nil
-		}
+		items = append(items, account_context.Named{ID: p.ID, Name: p.Name})
 		available = append(available, p.Name)
 	}
 
-	return 0, fmt.Errorf("воронка %q не найдена. Доступные: %v", name, available)
+	id, err = account_context.Match(name, items)
+	if errors.Is(err, account_context.ErrNotFound) {
+		return 0, fmt.Errorf("воронка %q не найдена. Доступные: %v", name, available)
+	}
+	return id, err
 }
 
-// resolveStatusID возвращает ID статуса по имени (если id == 0).
+// resolveStatusID возвращает ID статуса по имени (если id == 0), имя ищется нечётко (account_context.Match).
 // Возвращает ошибку с подсказкой о доступных статусах если имя не найдено.
 func (s *service) resolveStatusID(ctx context.Context, pipelineID int, id int, name string) (int, error) {
 	if id != 0 {
@@ -115,12 +120,15 @@ func (s *service) resolveStatusID(ctx context.Context, pipelineID int, id int, n
 	}
 
 	var available []string
+	items := make([]account_context.Named, 0, len(statuses))
 	for _, st := range statuses {
-		if st.Name == name {
-			return st.ID, nil
-		}
+		items = append(items, account_context.Named{ID: st.ID, Name: st.Name})
 		available = append(available, st.Name)
 	}
 
-	return 0, fmt.Errorf("статус %q не найден. Доступные: %v", name, available)
+	id, err = account_context.Match(name, items)
+	if errors.Is(err, account_context.ErrNotFound) {
+		return 0, fmt.Errorf("статус %q не найден. Доступные: %v", name, available)
+	}
+	return id, err
 }
@@ -2,6 +2,7 @@ package catalogs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/alextixru/amocrm-sdk-go"
 	"github.com/alextixru/amocrm-sdk-go/core/models"
 	gkitmodels "github.com/tihn/amo-ai-tgbot-go/internal/models/tools"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/account_context"
 )
 
 // CatalogItem нормализованное представление каталога для LLM
@@ -138,17 +140,22 @@ func (s *service) CatalogNames() []string {
 	return names
 }
 
-// resolveCatalogName резолвит имя каталога в ID. Возвращает ошибку с подсказкой если не найдено.
+// resolveCatalogName резолвит имя каталога в ID (нечётко, account_context.Match).
+// Возвращает ошибку с подсказкой если не найдено.
 func (s *service) resolveCatalogName(name string) (int, error) {
-	id, ok := s.catalogsByName[name]
-	if !ok {
+	items := make([]account_context.Named, 0, len(s.catalogsByName))
+	for catalogName, id := range s.catalogsByName {
+		items = append(items, account_context.Named{ID: id, Name: catalogName})
+	}
+	id, err := account_context.Match(name, items)
+	if errors.Is(err, account_context.ErrNotFound) {
 		available := strings.Join(s.CatalogNames(), ", ")
 		if available == "" {
 			available = "(каталоги не загружены)"
 		}
 		return 0, fmt.Errorf("каталог %q не найден. Доступные: %s", name, available)
 	}
-	return id, nil
+	return id, err
 }
 
 // resolveCatalogID резолвит ID каталога в имя. Возвращает "[unknown:ID]" если не найдено.
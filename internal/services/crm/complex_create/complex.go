@@ -2,12 +2,12 @@ package complex_create
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 
 	amomodels "github.com/alextixru/amocrm-sdk-go/core/models"
 	gkitmodels "github.com/tihn/amo-ai-tgbot-go/internal/models/tools"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/account_context"
 )
 
 func (s *service) CreateComplex(ctx context.Context, input *gkitmodels.ComplexCreateInput) (*ComplexCreateResult, error) {
@@ -53,17 +53,17 @@ func (s *service) CreateComplexBatch(ctx context.Context, inputs []gkitmodels.Co
 // buildSDKLead конвертирует ComplexCreateInput в SDK Lead, резолвя имена → ID.
 func (s *service) buildSDKLead(input *gkitmodels.ComplexCreateInput) (*amomodels.Lead, error) {
 	// Резолвим поля сделки
-	pipelineID, err := s.resolvePipelineID(input.Lead.PipelineName)
+	pipelineID, err := s.resolver.PipelineID(input.Lead.PipelineName)
 	if err != nil {
 		return nil, err
 	}
 
-	statusID, err := s.resolveStatusID(input.Lead.PipelineName, pipelineID, input.Lead.StatusName)
+	statusID, err := s.resolver.StatusID(pipelineID, input.Lead.StatusName)
 	if err != nil {
 		return nil, err
 	}
 
-	responsibleID, err := s.resolveUserID(input.Lead.ResponsibleUserName)
+	responsibleID, err := s.resolver.UserID(input.Lead.ResponsibleUserName)
 	if err != nil {
 		return nil, err
 	}
@@ -78,9 +78,11 @@ func (s *service) buildSDKLead(input *gkitmodels.ComplexCreateInput) (*amomodels
 	lead.ResponsibleUserID = responsibleID
 
 	// Кастомные поля сделки
-	if len(input.Lead.CustomFieldsValues) > 0 {
-		lead.CustomFieldsValues = mapCustomFieldsValues(input.Lead.CustomFieldsValues)
+	cfv, err := s.mapCustomFieldsValues("leads", input.Lead.CustomFieldsValues)
+	if err != nil {
+		return nil, err
 	}
+	lead.CustomFieldsValues = cfv
 
 	// Теги
 	if len(input.Lead.Tags) > 0 {
@@ -116,7 +118,7 @@ func (s *service) buildSDKLead(input *gkitmodels.ComplexCreateInput) (*amomodels
 
 // buildSDKContact конвертирует ContactData в SDK Contact.
 func (s *service) buildSDKContact(c gkitmodels.ContactData) (*amomodels.Contact, error) {
-	responsibleID, err := s.resolveUserID(c.ResponsibleUserName)
+	responsibleID, err := s.resolver.UserID(c.ResponsibleUserName)
 	if err != nil {
 		return nil, err
 	}
@@ -129,17 +131,18 @@ func (s *service) buildSDKContact(c gkitmodels.ContactData) (*amomodels.Contact,
 	}
 	contact.ResponsibleUserID = responsibleID
 
-	cfv := buildContactCustomFields(c)
-	if len(cfv) > 0 {
-		contact.CustomFieldsValues = cfv
+	cfv, err := s.buildContactCustomFields(c)
+	if err != nil {
+		return nil, err
 	}
+	contact.CustomFieldsValues = cfv
 
 	return contact, nil
 }
 
 // buildSDKCompany конвертирует CompanyData в SDK Company.
 func (s *service) buildSDKCompany(c *gkitmodels.CompanyData) (*amomodels.Company, error) {
-	responsibleID, err := s.resolveUserID(c.ResponsibleUserName)
+	responsibleID, err := s.resolver.UserID(c.ResponsibleUserName)
 	if err != nil {
 		return nil, err
 	}
@@ -149,9 +152,11 @@ func (s *service) buildSDKCompany(c *gkitmodels.CompanyData) (*amomodels.Company
 	}
 	company.ResponsibleUserID = responsibleID
 
-	if len(c.CustomFieldsValues) > 0 {
-		company.CustomFieldsValues = mapCustomFieldsValues(c.CustomFieldsValues)
+	cfv, err := s.mapCustomFieldsValues("companies", c.CustomFieldsValues)
+	if err != nil {
+		return nil, err
 	}
+	company.CustomFieldsValues = cfv
 
 	return company, nil
 }
@@ -171,9 +176,9 @@ func (s *service) enrichResult(
 			ID:                  leadID,
 			Name:                lead.Name,
 			Price:               lead.Price,
-			PipelineName:        s.lookupPipelineName(lead.PipelineID),
-			StatusName:          s.lookupStatusName(lead.PipelineID, lead.StatusID),
-			ResponsibleUserName: s.lookupUserName(lead.ResponsibleUserID),
+			PipelineName:        s.resolver.PipelineName(lead.PipelineID),
+			StatusName:          s.resolver.StatusName(lead.PipelineID, lead.StatusID),
+			ResponsibleUserName: s.resolver.UserName(lead.ResponsibleUserID),
 			CreatedAt:           time.Now().UTC().Format(time.RFC3339),
 		},
 	}
@@ -224,7 +229,7 @@ func (s *service) enrichResult(
 }
 
 // buildContactCustomFields собирает кастомные поля контакта из Phone, Email и CustomFieldsValues.
-func buildContactCustomFields(c gkitmodels.ContactData) []amomodels.CustomFieldValue {
+func (s *service) buildContactCustomFields(c gkitmodels.ContactData) ([]amomodels.CustomFieldValue, error) {
 	var result []amomodels.CustomFieldValue
 
 	if c.Phone != "" {
@@ -245,46 +250,19 @@ func buildContactCustomFields(c gkitmodels.ContactData) []amomodels.CustomFieldV
 		})
 	}
 
-	if len(c.CustomFieldsValues) > 0 {
-		result = append(result, mapCustomFieldsValues(c.CustomFieldsValues)...)
+	cfv, err := s.mapCustomFieldsValues("contacts", c.CustomFieldsValues)
+	if err != nil {
+		return nil, err
 	}
-
-	return result
+	return append(result, cfv...), nil
 }
 
-// mapCustomFieldsValues конвертирует map[string]any в []CustomFieldValue.
-func mapCustomFieldsValues(cfv map[string]any) []amomodels.CustomFieldValue {
-	data, err := json.Marshal(cfv)
+// mapCustomFieldsValues переводит кастомные поля (ключ — название, код или ID) в модель SDK
+// по схеме полей из AccountContext.
+func (s *service) mapCustomFieldsValues(entityType string, cfv map[string]any) ([]amomodels.CustomFieldValue, error) {
+	values, err := s.resolver.FieldValues(entityType, cfv)
 	if err != nil {
-		return nil
-	}
-
-	var result []amomodels.CustomFieldValue
-	if err := json.Unmarshal(data, &result); err != nil {
-		// Fallback: пробуем как map field_code -> values
-		for fieldCode, values := range cfv {
-			cfValue := amomodels.CustomFieldValue{
-				FieldCode: fieldCode,
-			}
-			if valArr, ok := values.([]any); ok {
-				for _, v := range valArr {
-					if valMap, ok := v.(map[string]any); ok {
-						item := amomodels.FieldValueElement{}
-						if val, ok := valMap["value"]; ok {
-							item.Value = val
-						}
-						if enum, ok := valMap["enum_code"].(string); ok {
-							item.EnumCode = enum
-						}
-						cfValue.Values = append(cfValue.Values, item)
-					}
-				}
-			}
-			if len(cfValue.Values) > 0 {
-				result = append(result, cfValue)
-			}
-		}
+		return nil, err
 	}
-
-	return result
+	return account_context.SDKFieldValues(values), nil
 }
@@ -35,7 +35,7 @@ func (s *service) ListCustomers(ctx context.Context, filter *gkitmodels.Customer
 			sdkFilter.SetNames(filter.Names)
 		}
 		if len(filter.ResponsibleUserNames) > 0 {
-			ids, err := s.resolveUserNames(filter.ResponsibleUserNames)
+			ids, err := s.resolver.UserIDs(filter.ResponsibleUserNames)
 			if err != nil {
 				return nil, err
 			}
@@ -167,7 +167,7 @@ func (s *service) mapCustomerData(d *gkitmodels.CustomerData, id int) (models.Cu
 	}
 
 	if d.ResponsibleUserName != "" {
-		uid, err := s.resolveUserName(d.ResponsibleUserName)
+		uid, err := s.resolver.UserID(d.ResponsibleUserName)
 		if err != nil {
 			return models.Customer{}, err
 		}
@@ -175,7 +175,7 @@ func (s *service) mapCustomerData(d *gkitmodels.CustomerData, id int) (models.Cu
 	}
 
 	if d.StatusName != "" {
-		sid, err := s.resolveStatusName(d.StatusName)
+		sid, err := s.resolver.CustomerStatusID(d.StatusName)
 		if err != nil {
 			return models.Customer{}, err
 		}
@@ -222,11 +222,11 @@ func (s *service) enrichCustomer(c *models.Customer) *CustomerOutput {
 		Name:                c.Name,
 		NextPrice:           c.NextPrice,
 		NextDate:            toISO(c.NextDate),
-		StatusName:          s.resolveStatusID(c.StatusID),
+		StatusName:          s.resolver.CustomerStatusName(c.StatusID),
 		Periodicity:         c.Periodicity,
-		ResponsibleUserName: s.resolveUserID(c.ResponsibleUserID),
-		CreatedByName:       s.resolveUserID(c.CreatedBy),
-		UpdatedByName:       s.resolveUserID(c.UpdatedBy),
+		ResponsibleUserName: s.resolver.UserName(c.ResponsibleUserID),
+		CreatedByName:       s.resolver.UserName(c.CreatedBy),
+		UpdatedByName:       s.resolver.UserName(c.UpdatedBy),
 		CreatedAt:           toISO(c.CreatedAt),
 		UpdatedAt:           toISO(c.UpdatedAt),
 		IsDeleted:           c.IsDeleted,
@@ -3,11 +3,11 @@ package customers
 import (
 	"context"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/alextixru/amocrm-sdk-go"
 	gkitmodels "github.com/tihn/amo-ai-tgbot-go/internal/models/tools"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/account_context"
 )
 
 // Service определяет бизнес-логику для работы с покупателями, бонусами, статусами, транзакциями и сегментами.
@@ -63,127 +63,21 @@ type StatusOutput struct {
 }
 
 type service struct {
-	sdk            *amocrm.SDK
-	usersByName    map[string]int
-	usersByID      map[int]string
-	statusesByName map[string]int
-	statusesByID   map[int]string
+	sdk      *amocrm.SDK
+	resolver account_context.Resolver
 }
 
-// New создает новый экземпляр сервиса покупателей и загружает справочники.
-func New(ctx context.Context, sdk *amocrm.SDK) (Service, error) {
-	s := &service{
-		sdk:            sdk,
-		usersByName:    make(map[string]int),
-		usersByID:      make(map[int]string),
-		statusesByName: make(map[string]int),
-		statusesByID:   make(map[int]string),
-	}
-	if err := s.loadUsers(ctx); err != nil {
-		return nil, fmt.Errorf("customers: load users: %w", err)
-	}
-	if err := s.loadStatuses(ctx); err != nil {
-		// 422 означает что статусы покупателей недоступны в этом аккаунте (segments mode) — не блокируем старт
-		_ = err
-	}
-	return s, nil
+// New создает новый экземпляр сервиса покупателей; пользователи и статусы покупателей
+// резолвятся через общий AccountContext.
+func New(sdk *amocrm.SDK, resolver account_context.Resolver) Service {
+	return &service{sdk: sdk, resolver: resolver}
 }
 
-// loadUsers загружает всех пользователей из SDK и строит индексы.
-func (s *service) loadUsers(ctx context.Context) error {
-	users, _, err := s.sdk.Users().Get(ctx, nil)
-	if err != nil {
-		return err
-	}
-	for _, u := range users {
-		if u == nil || u.Name == "" {
-			continue
-		}
-		s.usersByID[u.ID] = u.Name
-		s.usersByName[u.Name] = u.ID
-	}
-	return nil
-}
-
-// loadStatuses загружает статусы покупателей из SDK и строит индексы.
-func (s *service) loadStatuses(ctx context.Context) error {
-	statuses, _, err := s.sdk.CustomerStatuses().Get(ctx, nil)
-	if err != nil {
-		return err
-	}
-	for _, st := range statuses {
-		if st.Name == "" {
-			continue
-		}
-		s.statusesByID[st.ID] = st.Name
-		s.statusesByName[st.Name] = st.ID
-	}
-	return nil
-}
-
-// resolveUserName переводит имя пользователя в ID.
-// Возвращает ошибку с подсказкой если имя не найдено.
-func (s *service) resolveUserName(name string) (int, error) {
-	if name == "" {
-		return 0, nil
-	}
-	id, ok := s.usersByName[name]
-	if !ok {
-		available := make([]string, 0, len(s.usersByName))
-		for n := range s.usersByName {
-			available = append(available, n)
-		}
-		return 0, fmt.Errorf("пользователь '%s' не найден. Доступные: %s", name, strings.Join(available, ", "))
-	}
-	return id, nil
-}
-
-// resolveUserNames переводит слайс имён в слайс ID.
-func (s *service) resolveUserNames(names []string) ([]int, error) {
-	ids := make([]int, 0, len(names))
-	for _, name := range names {
-		id, err := s.resolveUserName(name)
-		if err != nil {
-			return nil, err
-		}
-		ids = append(ids, id)
-	}
-	return ids, nil
-}
-
-// resolveUserID переводит ID пользователя в имя.
-// При неизвестном ID возвращает "[unknown:ID]".
-func (s *service) resolveUserID(id int) string {
-	if id == 0 {
-		return ""
-	}
-	if name, ok := s.usersByID[id]; ok {
-		return name
-	}
-	return fmt.Sprintf("[unknown:%d]", id)
-}
-
-// resolveStatusName переводит имя статуса покупателя в ID.
-func (s *service) resolveStatusName(name string) (int, error) {
-	if name == "" {
-		return 0, nil
-	}
-	id, ok := s.statusesByName[name]
-	if !ok {
-		available := make([]string, 0, len(s.statusesByName))
-		for n := range s.statusesByName {
-			available = append(available, n)
-		}
-		return 0, fmt.Errorf("статус покупателя '%s' не найден. Доступные: %s", name, strings.Join(available, ", "))
-	}
-	return id, nil
-}
-
-// resolveStatusNames переводит слайс имён статусов в слайс ID.
+// resolveStatusNames переводит слайс имён статусов покупателей в слайс ID.
 func (s *service) resolveStatusNames(names []string) ([]int, error) {
 	ids := make([]int, 0, len(names))
 	for _, name := range names {
-		id, err := s.resolveStatusName(name)
+		id, err := s.resolver.CustomerStatusID(name)
 		if err != nil {
 			return nil, err
 		}
@@ -192,17 +86,6 @@ func (s *service) resolveStatusNames(names []string) ([]int, error) {
 	return ids, nil
 }
 
-// resolveStatusID переводит ID статуса в имя.
-func (s *service) resolveStatusID(id int) string {
-	if id == 0 {
-		return ""
-	}
-	if name, ok := s.statusesByID[id]; ok {
-		return name
-	}
-	return fmt.Sprintf("[unknown:%d]", id)
-}
-
 // parseISO парсит ISO 8601 строку в Unix timestamp.
 // Возвращает 0 и ошибку если строка непустая, но не парсится.
 func parseISO(s string) (int64, error) {
@@ -230,18 +113,10 @@ func toISO(ts int64) string {
 
 // UserNames возвращает список доступных имён пользователей.
 func (s *service) UserNames() []string {
-	names := make([]string, 0, len(s.usersByName))
-	for name := range s.usersByName {
-		names = append(names, name)
-	}
-	return names
+	return s.resolver.UserNames()
 }
 
 // StatusNames возвращает список доступных имён статусов покупателей.
 func (s *service) StatusNames() []string {
-	names := make([]string, 0, len(s.statusesByName))
-	for name := range s.statusesByName {
-		names = append(names, name)
-	}
-	return names
+	return s.resolver.CustomerStatusNames()
 }
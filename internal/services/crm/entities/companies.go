@@ -33,7 +33,7 @@ func (s *service) SearchCompanies(ctx context.Context, filter *gkitmodels.Entiti
 
 		// Ответственные: имена → ID
 		if len(filter.ResponsibleUserNames) > 0 {
-			ids, err := s.resolveUserIDs(filter.ResponsibleUserNames)
+			ids, err := s.resolver.UserIDs(filter.ResponsibleUserNames)
 			if err != nil {
 				return nil, err
 			}
@@ -42,7 +42,7 @@ func (s *service) SearchCompanies(ctx context.Context, filter *gkitmodels.Entiti
 
 		// created_by: имена → ID
 		if len(filter.CreatedByNames) > 0 {
-			ids, err := s.resolveUserIDs(filter.CreatedByNames)
+			ids, err := s.resolver.UserIDs(filter.CreatedByNames)
 			if err != nil {
 				return nil, err
 			}
@@ -51,7 +51,7 @@ func (s *service) SearchCompanies(ctx context.Context, filter *gkitmodels.Entiti
 
 		// updated_by: имена → ID
 		if len(filter.UpdatedByNames) > 0 {
-			ids, err := s.resolveUserIDs(filter.UpdatedByNames)
+			ids, err := s.resolver.UserIDs(filter.UpdatedByNames)
 			if err != nil {
 				return nil, err
 			}
@@ -70,9 +70,12 @@ func (s *service) SearchCompanies(ctx context.Context, filter *gkitmodels.Entiti
 			f.SetUpdatedAt(intPtrOrNil(from), intPtrOrNil(to))
 		}
 
-		// Кастомные поля: field_code → field_id
+		// Кастомные поля: код или название → field_id
 		if len(filter.CustomFieldsValues) > 0 {
-			cfMap := buildCustomFieldsFilter(filter.CustomFieldsValues, s.customFieldsCompanies)
+			cfMap, err := s.buildCustomFieldsFilter("companies", filter.CustomFieldsValues)
+			if err != nil {
+				return nil, err
+			}
 			if len(cfMap) > 0 {
 				f.SetCustomFieldsValues(cfMap)
 			}
@@ -241,14 +244,18 @@ func (s *service) mapToCompany(data *gkitmodels.EntityData) (*models.Company, er
 
 	// Ответственный — имя → ID
 	if data.ResponsibleUserName != "" {
-		id, err := s.resolveUserID(data.ResponsibleUserName)
+		id, err := s.resolver.UserID(data.ResponsibleUserName)
 		if err != nil {
 			return nil, err
 		}
 		company.ResponsibleUserID = id
 	}
 
-	company.CustomFieldsValues = mapCustomFieldsValues(data.CustomFieldsValues)
+	cfv, err := s.mapCustomFieldsValues("companies", data.CustomFieldsValues)
+	if err != nil {
+		return nil, err
+	}
+	company.CustomFieldsValues = cfv
 
 	if len(data.Tags) > 0 {
 		company.Embedded = &models.CompanyEmbedded{}
@@ -33,7 +33,7 @@ func (s *service) SearchContacts(ctx context.Context, filter *gkitmodels.Entitie
 
 		// Ответственные: имена → ID
 		if len(filter.ResponsibleUserNames) > 0 {
-			ids, err := s.resolveUserIDs(filter.ResponsibleUserNames)
+			ids, err := s.resolver.UserIDs(filter.ResponsibleUserNames)
 			if err != nil {
 				return nil, err
 			}
@@ -42,7 +42,7 @@ func (s *service) SearchContacts(ctx context.Context, filter *gkitmodels.Entitie
 
 		// created_by: имена → ID
 		if len(filter.CreatedByNames) > 0 {
-			ids, err := s.resolveUserIDs(filter.CreatedByNames)
+			ids, err := s.resolver.UserIDs(filter.CreatedByNames)
 			if err != nil {
 				return nil, err
 			}
@@ -51,7 +51,7 @@ func (s *service) SearchContacts(ctx context.Context, filter *gkitmodels.Entitie
 
 		// updated_by: имена → ID
 		if len(filter.UpdatedByNames) > 0 {
-			ids, err := s.resolveUserIDs(filter.UpdatedByNames)
+			ids, err := s.resolver.UserIDs(filter.UpdatedByNames)
 			if err != nil {
 				return nil, err
 			}
@@ -70,9 +70,12 @@ func (s *service) SearchContacts(ctx context.Context, filter *gkitmodels.Entitie
 			f.SetUpdatedAt(intPtrOrNil(from), intPtrOrNil(to))
 		}
 
-		// Кастомные поля: field_code → field_id
+		// Кастомные поля: код или название → field_id
 		if len(filter.CustomFieldsValues) > 0 {
-			cfMap := buildCustomFieldsFilter(filter.CustomFieldsValues, s.customFieldsContacts)
+			cfMap, err := s.buildCustomFieldsFilter("contacts", filter.CustomFieldsValues)
+			if err != nil {
+				return nil, err
+			}
 			if len(cfMap) > 0 {
 				f.SetCustomFieldsValues(cfMap)
 			}
@@ -255,14 +258,18 @@ func (s *service) mapToContact(data *gkitmodels.EntityData) (*models.Contact, er
 
 	// Ответственный — имя → ID
 	if data.ResponsibleUserName != "" {
-		id, err := s.resolveUserID(data.ResponsibleUserName)
+		id, err := s.resolver.UserID(data.ResponsibleUserName)
 		if err != nil {
 			return nil, err
 		}
 		contact.ResponsibleUserID = id
 	}
 
-	contact.CustomFieldsValues = mapCustomFieldsValues(data.CustomFieldsValues)
+	cfv, err := s.mapCustomFieldsValues("contacts", data.CustomFieldsValues)
+	if err != nil {
+		return nil, err
+	}
+	contact.CustomFieldsValues = cfv
 
 	hasEmbedded := len(data.Tags) > 0 || len(data.EmbeddedCompanies) > 0
 	if hasEmbedded {
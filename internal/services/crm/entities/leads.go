@@ -30,7 +30,7 @@ func (s *service) SearchLeads(ctx context.Context, filter *gkitmodels.EntitiesFi
 
 		// Ответственные: имена → ID
 		if len(filter.ResponsibleUserNames) > 0 {
-			ids, err := s.resolveUserIDs(filter.ResponsibleUserNames)
+			ids, err := s.resolver.UserIDs(filter.ResponsibleUserNames)
 			if err != nil {
 				return nil, err
 			}
@@ -39,7 +39,7 @@ func (s *service) SearchLeads(ctx context.Context, filter *gkitmodels.EntitiesFi
 
 		// created_by: имена → ID
 		if len(filter.CreatedByNames) > 0 {
-			ids, err := s.resolveUserIDs(filter.CreatedByNames)
+			ids, err := s.resolver.UserIDs(filter.CreatedByNames)
 			if err != nil {
 				return nil, err
 			}
@@ -48,7 +48,7 @@ func (s *service) SearchLeads(ctx context.Context, filter *gkitmodels.EntitiesFi
 
 		// updated_by: имена → ID
 		if len(filter.UpdatedByNames) > 0 {
-			ids, err := s.resolveUserIDs(filter.UpdatedByNames)
+			ids, err := s.resolver.UserIDs(filter.UpdatedByNames)
 			if err != nil {
 				return nil, err
 			}
@@ -59,7 +59,7 @@ func (s *service) SearchLeads(ctx context.Context, filter *gkitmodels.EntitiesFi
 		if len(filter.PipelineNames) > 0 {
 			ids := make([]int, 0, len(filter.PipelineNames))
 			for _, name := range filter.PipelineNames {
-				id, err := s.resolvePipelineID(name)
+				id, err := s.resolver.PipelineID(name)
 				if err != nil {
 					return nil, err
 				}
@@ -115,9 +115,12 @@ func (s *service) SearchLeads(ctx context.Context, filter *gkitmodels.EntitiesFi
 			f.SetClosedAt(intPtrOrNil(from), intPtrOrNil(to))
 		}
 
-		// Кастомные поля: field_code → field_id
+		// Кастомные поля: код или название → field_id
 		if len(filter.CustomFieldsValues) > 0 {
-			cfMap := buildCustomFieldsFilter(filter.CustomFieldsValues, s.customFieldsLeads)
+			cfMap, err := s.buildCustomFieldsFilter("leads", filter.CustomFieldsValues)
+			if err != nil {
+				return nil, err
+			}
 			if len(cfMap) > 0 {
 				f.SetCustomFieldsValues(cfMap)
 			}
@@ -302,7 +305,7 @@ func (s *service) mapToLead(data *gkitmodels.EntityData) (*models.Lead, error) {
 
 	// Воронка и статус — имена → ID
 	if data.PipelineName != "" {
-		id, err := s.resolvePipelineID(data.PipelineName)
+		id, err := s.resolver.PipelineID(data.PipelineName)
 		if err != nil {
 			return nil, err
 		}
@@ -318,7 +321,7 @@ func (s *service) mapToLead(data *gkitmodels.EntityData) (*models.Lead, error) {
 
 	// Ответственный — имя → ID
 	if data.ResponsibleUserName != "" {
-		id, err := s.resolveUserID(data.ResponsibleUserName)
+		id, err := s.resolver.UserID(data.ResponsibleUserName)
 		if err != nil {
 			return nil, err
 		}
@@ -327,14 +330,18 @@ func (s *service) mapToLead(data *gkitmodels.EntityData) (*models.Lead, error) {
 
 	// Причина отказа — имя → ID
 	if data.LossReasonName != "" {
-		id, err := s.resolveLossReasonID(data.LossReasonName)
+		id, err := s.resolver.LossReasonID(data.LossReasonName)
 		if err != nil {
 			return nil, err
 		}
 		lead.LossReasonID = &id
 	}
 
-	lead.CustomFieldsValues = mapCustomFieldsValues(data.CustomFieldsValues)
+	cfv, err := s.mapCustomFieldsValues("leads", data.CustomFieldsValues)
+	if err != nil {
+		return nil, err
+	}
+	lead.CustomFieldsValues = cfv
 
 	if len(data.Tags) > 0 || len(data.EmbeddedContacts) > 0 || len(data.EmbeddedCompanies) > 0 {
 		lead.Embedded = &models.LeadEmbedded{}
@@ -1,12 +1,12 @@
 package entities
 
 import (
-	"encoding/json"
-	"fmt"
+	"strconv"
 	"time"
 
 	amomodels "github.com/alextixru/amocrm-sdk-go/core/models"
 	gkitmodels "github.com/tihn/amo-ai-tgbot-go/internal/models/tools"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/account_context"
 )
 
 // unixToISO конвертирует Unix timestamp в ISO-8601 строку. Возвращает "" если 0.
@@ -44,17 +44,18 @@ func (s *service) leadToResult(lead *amomodels.Lead) *EntityResult {
 		ID:                  lead.ID,
 		Name:                lead.Name,
 		Price:               lead.Price,
-		PipelineName:        s.lookupPipelineName(lead.PipelineID),
-		StatusName:          s.lookupStatusName(lead.StatusID),
-		ResponsibleUserName: s.lookupUserName(lead.ResponsibleUserID),
-		CreatedByName:       s.lookupUserName(lead.CreatedBy),
-		UpdatedByName:       s.lookupUserName(lead.UpdatedBy),
+		PipelineName:        s.resolver.PipelineName(lead.PipelineID),
+		StatusName:          s.resolver.StatusName(lead.PipelineID, lead.StatusID),
+		ResponsibleUserID:   lead.ResponsibleUserID,
+		ResponsibleUserName: s.resolver.UserName(lead.ResponsibleUserID),
+		CreatedByName:       s.resolver.UserName(lead.CreatedBy),
+		UpdatedByName:       s.resolver.UserName(lead.UpdatedBy),
 		CreatedAt:           unixToISO(lead.CreatedAt),
 		UpdatedAt:           unixToISO(lead.UpdatedAt),
 	}
 
 	if lead.LossReasonID != nil {
-		r.LossReason = s.lookupLossReasonName(*lead.LossReasonID)
+		r.LossReason = s.resolver.LossReasonName(*lead.LossReasonID)
 	}
 	if lead.ClosedAt != nil {
 		r.ClosedAt = unixToISO(*lead.ClosedAt)
@@ -104,9 +105,10 @@ func (s *service) contactToResult(contact *amomodels.Contact) *EntityResult {
 		Name:                contact.Name,
 		FirstName:           contact.FirstName,
 		LastName:            contact.LastName,
-		ResponsibleUserName: s.lookupUserName(contact.ResponsibleUserID),
-		CreatedByName:       s.lookupUserName(contact.CreatedBy),
-		UpdatedByName:       s.lookupUserName(contact.UpdatedBy),
+		ResponsibleUserID:   contact.ResponsibleUserID,
+		ResponsibleUserName: s.resolver.UserName(contact.ResponsibleUserID),
+		CreatedByName:       s.resolver.UserName(contact.CreatedBy),
+		UpdatedByName:       s.resolver.UserName(contact.UpdatedBy),
 		CreatedAt:           unixToISO(contact.CreatedAt),
 		UpdatedAt:           unixToISO(contact.UpdatedAt),
 	}
@@ -142,9 +144,10 @@ func (s *service) companyToResult(company *amomodels.Company) *EntityResult {
 	r := &EntityResult{
 		ID:                  company.ID,
 		Name:                company.Name,
-		ResponsibleUserName: s.lookupUserName(company.ResponsibleUserID),
-		CreatedByName:       s.lookupUserName(company.CreatedBy),
-		UpdatedByName:       s.lookupUserName(company.UpdatedBy),
+		ResponsibleUserID:   company.ResponsibleUserID,
+		ResponsibleUserName: s.resolver.UserName(company.ResponsibleUserID),
+		CreatedByName:       s.resolver.UserName(company.CreatedBy),
+		UpdatedByName:       s.resolver.UserName(company.UpdatedBy),
 		CreatedAt:           unixToISO(company.CreatedAt),
 		UpdatedAt:           unixToISO(company.UpdatedAt),
 	}
@@ -188,70 +191,14 @@ func mapCFVToEntries(cfv []amomodels.CustomFieldValue) []CustomFieldEntry {
 	return out
 }
 
-// mapCustomFieldsValues конвертирует map[string]any в []CustomFieldValue для SDK.
-// Ключ — field_code. Значение — строка/массив/{value, enum_code}.
-func mapCustomFieldsValues(cfv map[string]any) []amomodels.CustomFieldValue {
-	if cfv == nil {
-		return nil
+// mapCustomFieldsValues переводит кастомные поля в модель SDK. Ключ — название, код или ID поля,
+// значение приводится к типу поля по схеме из AccountContext (варианты списков, даты, флажки, числа).
+func (s *service) mapCustomFieldsValues(entityType string, cfv map[string]any) ([]amomodels.CustomFieldValue, error) {
+	values, err := s.resolver.FieldValues(entityType, cfv)
+	if err != nil {
+		return nil, err
 	}
-
-	var result []amomodels.CustomFieldValue
-
-	for fieldCode, rawVal := range cfv {
-		cfValue := amomodels.CustomFieldValue{
-			FieldCode: fieldCode,
-		}
-
-		switch v := rawVal.(type) {
-		case string:
-			// Простое строковое значение
-			cfValue.Values = []amomodels.FieldValueElement{{Value: v}}
-
-		case []any:
-			// Массив значений
-			for _, item := range v {
-				elem := amomodels.FieldValueElement{}
-				switch iv := item.(type) {
-				case string:
-					elem.Value = iv
-				case map[string]any:
-					if val, ok := iv["value"]; ok {
-						elem.Value = val
-					}
-					if enumCode, ok := iv["enum_code"].(string); ok {
-						elem.EnumCode = enumCode
-					}
-				default:
-					elem.Value = fmt.Sprintf("%v", iv)
-				}
-				cfValue.Values = append(cfValue.Values, elem)
-			}
-
-		case map[string]any:
-			// Одно сложное значение
-			elem := amomodels.FieldValueElement{}
-			if val, ok := v["value"]; ok {
-				elem.Value = val
-			}
-			if enumCode, ok := v["enum_code"].(string); ok {
-				elem.EnumCode = enumCode
-			}
-			cfValue.Values = []amomodels.FieldValueElement{elem}
-
-		default:
-			// Прочие скалярные типы — через JSON roundtrip как fallback
-			data, err := json.Marshal(rawVal)
-			if err == nil {
-				cfValue.Values = []amomodels.FieldValueElement{{Value: string(data)}}
-			}
-		}
-
-		if len(cfValue.Values) > 0 {
-			result = append(result, cfValue)
-		}
-	}
-
-	return result
+	return account_context.SDKFieldValues(values), nil
 }
 
 // mapTags конвертирует []EntityTag в []amomodels.Tag.
@@ -270,27 +217,41 @@ func mapTags(tags []gkitmodels.EntityTag) []amomodels.Tag {
 }
 
 // buildCustomFieldsFilter конвертирует []CustomFieldFilter в map[int]interface{} для SDK фильтра.
-// customFieldsByCode — маппинг code→id для соответствующего типа сущности.
-func buildCustomFieldsFilter(filters []gkitmodels.CustomFieldFilter, customFieldsByCode map[string]int) map[int]interface{} {
+// Поле задаётся кодом, названием или ID; значения списков (select, multiselect) переводятся в ID вариантов.
+func (s *service) buildCustomFieldsFilter(entityType string, filters []gkitmodels.CustomFieldFilter) (map[int]interface{}, error) {
 	if len(filters) == 0 {
-		return nil
+		return nil, nil
 	}
 	result := make(map[int]interface{})
 	for _, f := range filters {
-		fieldID, ok := customFieldsByCode[f.FieldCode]
-		if !ok || fieldID == 0 {
-			// код не найден — пропускаем
-			continue
+		field, err := s.resolver.CustomField(entityType, f.FieldCode)
+		if err != nil {
+			return nil, err
 		}
-		if len(f.Values) == 1 {
-			result[fieldID] = fmt.Sprintf("%v", f.Values[0])
-		} else if len(f.Values) > 1 {
-			strs := make([]string, 0, len(f.Values))
-			for _, v := range f.Values {
-				strs = append(strs, fmt.Sprintf("%v", v))
+		values := make([]string, 0, len(f.Values))
+		switch field.Type {
+		case "select", "multiselect", "radiobutton":
+			raw := make([]any, len(f.Values))
+			for i, v := range f.Values {
+				raw[i] = v
 			}
-			result[fieldID] = strs
+			converted, err := s.resolver.FieldValues(entityType, map[string]any{strconv.Itoa(field.ID): raw})
+			if err != nil {
+				return nil, err
+			}
+			for _, cfv := range converted {
+				for _, item := range cfv.Values {
+					values = append(values, strconv.Itoa(item.EnumID))
+				}
+			}
+		default:
+			values = append(values, f.Values...)
+		}
+		if len(values) == 1 {
+			result[field.ID] = values[0]
+		} else if len(values) > 1 {
+			result[field.ID] = values
 		}
 	}
-	return result
+	return result, nil
 }
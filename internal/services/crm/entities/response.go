@@ -20,6 +20,7 @@ type EntityResult struct {
 	LastName  string `json:"last_name,omitempty"`
 
 	// Общие
+	ResponsibleUserID   int    `json:"-"` // для проверки прав, модели не отдаётся
 	ResponsibleUserName string `json:"responsible_user_name,omitempty"`
 	CreatedByName       string `json:"created_by_name,omitempty"`
 	UpdatedByName       string `json:"updated_by_name,omitempty"`
@@ -2,14 +2,12 @@ package unsorted
 
 import (
 	"context"
-	"fmt"
-	"net/url"
-	"strings"
 
 	"github.com/alextixru/amocrm-sdk-go"
 	"github.com/alextixru/amocrm-sdk-go/core/models"
 	"github.com/alextixru/amocrm-sdk-go/core/services"
 	gkitmodels "github.com/tihn/amo-ai-tgbot-go/internal/models/tools"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/account_context"
 )
 
 // UnsortedMetadataOutput читаемые метаданные с RFC3339 вместо Unix timestamps.
@@ -84,189 +82,28 @@ type Service interface {
 }
 
 type service struct {
-	sdk *amocrm.SDK
-
-	usersByName map[string]int
-	usersByID   map[int]string
-
-	pipelinesByName map[string]int
-	pipelinesByID   map[int]string
-
-	// statusesByPipelineAndName[pipelineID][statusName] = statusID
-	statusesByPipelineAndName map[int]map[string]int
-}
-
-// New создает новый экземпляр сервиса неразобранного и загружает справочники.
-func New(ctx context.Context, sdk *amocrm.SDK) (Service, error) {
-	s := &service{
-		sdk:                       sdk,
-		usersByName:               make(map[string]int),
-		usersByID:                 make(map[int]string),
-		pipelinesByName:           make(map[string]int),
-		pipelinesByID:             make(map[int]string),
-		statusesByPipelineAndName: make(map[int]map[string]int),
-	}
-	if err := s.loadUsers(ctx); err != nil {
-		return nil, fmt.Errorf("unsorted: load users: %w", err)
-	}
-	if err := s.loadPipelines(ctx); err != nil {
-		return nil, fmt.Errorf("unsorted: load pipelines: %w", err)
-	}
-	return s, nil
-}
-
-// loadUsers загружает всех пользователей и строит индексы name↔id.
-func (s *service) loadUsers(ctx context.Context) error {
-	users, _, err := s.sdk.Users().Get(ctx, nil)
-	if err != nil {
-		return err
-	}
-	for _, u := range users {
-		if u == nil || u.Name == "" {
-			continue
-		}
-		s.usersByID[u.ID] = u.Name
-		s.usersByName[u.Name] = u.ID
-	}
-	return nil
-}
-
-// loadPipelines загружает воронки со статусами и строит индексы name↔id.
-func (s *service) loadPipelines(ctx context.Context) error {
-	params := url.Values{}
-	params.Set("with", "statuses")
-
-	pipelines, _, err := s.sdk.Pipelines().Get(ctx, params)
-	if err != nil {
-		return err
-	}
-	for _, p := range pipelines {
-		if p == nil || p.Name == "" {
-			continue
-		}
-		s.pipelinesByID[p.ID] = p.Name
-		s.pipelinesByName[p.Name] = p.ID
-
-		if p.Embedded != nil && len(p.Embedded.Statuses) > 0 {
-			statMap := make(map[string]int, len(p.Embedded.Statuses))
-			for _, st := range p.Embedded.Statuses {
-				if st.Name != "" {
-					statMap[st.Name] = st.ID
-				}
-			}
-			s.statusesByPipelineAndName[p.ID] = statMap
-		}
-	}
-	return nil
-}
-
-// resolveUserName переводит имя пользователя в ID.
-func (s *service) resolveUserName(name string) (int, error) {
-	if name == "" {
-		return 0, nil
-	}
-	id, ok := s.usersByName[name]
-	if !ok {
-		var available []string
-		for n := range s.usersByName {
-			available = append(available, n)
-		}
-		return 0, fmt.Errorf("пользователь '%s' не найден. Доступные: %s", name, strings.Join(available, ", "))
-	}
-	return id, nil
-}
-
-// resolveUserID переводит ID пользователя в имя. При неизвестном ID возвращает "[unknown:ID]".
-func (s *service) resolveUserID(id int) string {
-	if id == 0 {
-		return ""
-	}
-	if name, ok := s.usersByID[id]; ok {
-		return name
-	}
-	return fmt.Sprintf("[unknown:%d]", id)
-}
-
-// resolvePipelineName переводит имя воронки в ID.
-func (s *service) resolvePipelineName(name string) (int, error) {
-	if name == "" {
-		return 0, nil
-	}
-	id, ok := s.pipelinesByName[name]
-	if !ok {
-		var available []string
-		for n := range s.pipelinesByName {
-			available = append(available, n)
-		}
-		return 0, fmt.Errorf("воронка '%s' не найдена. Доступные: %s", name, strings.Join(available, ", "))
-	}
-	return id, nil
-}
-
-// resolvePipelineID переводит ID воронки в имя. При неизвестном ID возвращает "[unknown:ID]".
-func (s *service) resolvePipelineID(id int) string {
-	if id == 0 {
-		return ""
-	}
-	if name, ok := s.pipelinesByID[id]; ok {
-		return name
-	}
-	return fmt.Sprintf("[unknown:%d]", id)
+	sdk      *amocrm.SDK
+	resolver account_context.Resolver
 }
 
-// resolveStatusName переводит имя статуса в ID для заданной воронки.
-func (s *service) resolveStatusName(pipelineID int, statusName string) (int, error) {
-	if statusName == "" {
-		return 0, nil
-	}
-	statMap, ok := s.statusesByPipelineAndName[pipelineID]
-	if !ok {
-		return 0, fmt.Errorf("статусы для воронки ID=%d не найдены", pipelineID)
-	}
-	id, ok := statMap[statusName]
-	if !ok {
-		var available []string
-		for n := range statMap {
-			available = append(available, n)
-		}
-		return 0, fmt.Errorf("статус '%s' не найден. Доступные: %s", statusName, strings.Join(available, ", "))
-	}
-	return id, nil
+// New создает новый экземпляр сервиса неразобранного; справочники берутся из общего AccountContext.
+func New(sdk *amocrm.SDK, resolver account_context.Resolver) Service {
+	return &service{sdk: sdk, resolver: resolver}
 }
 
 // PipelineNames возвращает список доступных имён воронок (для описаний tools).
 func (s *service) PipelineNames() []string {
-	names := make([]string, 0, len(s.pipelinesByName))
-	for name := range s.pipelinesByName {
-		names = append(names, name)
-	}
-	return names
+	return s.resolver.PipelineNames()
 }
 
 // UserNames возвращает список доступных имён пользователей (для описаний tools).
 func (s *service) UserNames() []string {
-	names := make([]string, 0, len(s.usersByName))
-	for name := range s.usersByName {
-		names = append(names, name)
-	}
-	return names
+	return s.resolver.UserNames()
 }
 
 // StatusNames возвращает карту pipeline_name → []status_name для available_values.
 func (s *service) StatusNames() map[string][]string {
-	result := make(map[string][]string, len(s.statusesByPipelineAndName))
-	for pipelineID, statMap := range s.statusesByPipelineAndName {
-		pipelineName := s.resolvePipelineID(pipelineID)
-		if pipelineName == "" {
-			continue
-		}
-		statuses := make([]string, 0, len(statMap))
-		for name := range statMap {
-			statuses = append(statuses, name)
-		}
-		result[pipelineName] = statuses
-	}
-	return result
+	return s.resolver.StatusesByPipeline()
 }
 
 // metadataToOutput конвертирует SDK-метаданные в читаемый вывод с RFC3339 строками.
@@ -310,7 +147,7 @@ func (s *service) unsortedToOutput(u *models.Unsorted) *UnsortedOutput {
 	out := &UnsortedOutput{
 		UID:          u.UID,
 		Category:     string(u.Category),
-		PipelineName: s.resolvePipelineID(u.PipelineID),
+		PipelineName: s.resolver.PipelineName(u.PipelineID),
 		SourceName:   u.SourceName,
 		SourceUID:    u.SourceUID,
 		Metadata:     metadataToOutput(u.Metadata),
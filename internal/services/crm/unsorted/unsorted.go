@@ -25,7 +25,7 @@ func (s *service) ListUnsorted(ctx context.Context, filter *gkitmodels.UnsortedF
 			f.SetCategory(filter.Category)
 		}
 		if filter.PipelineName != "" {
-			id, err := s.resolvePipelineName(filter.PipelineName)
+			id, err := s.resolver.PipelineID(filter.PipelineName)
 			if err != nil {
 				return nil, err
 			}
@@ -76,7 +76,7 @@ func (s *service) CreateUnsorted(ctx context.Context, category string, items []g
 			CreatedAt:  rfc3339ToUnix(item.CreatedAt),
 		}
 		if item.PipelineName != "" {
-			id, err := s.resolvePipelineName(item.PipelineName)
+			id, err := s.resolver.PipelineID(item.PipelineName)
 			if err != nil {
 				return nil, err
 			}
@@ -102,7 +102,7 @@ func (s *service) AcceptUnsorted(ctx context.Context, uid string, params *gkitmo
 
 	if params != nil {
 		if params.UserName != "" {
-			userID, err := s.resolveUserName(params.UserName)
+			userID, err := s.resolver.UserID(params.UserName)
 			if err != nil {
 				return nil, err
 			}
@@ -111,11 +111,11 @@ func (s *service) AcceptUnsorted(ctx context.Context, uid string, params *gkitmo
 			}
 		}
 		if params.StatusName != "" {
-			pipelineID, err := s.resolvePipelineName(params.PipelineName)
+			pipelineID, err := s.resolver.PipelineID(params.PipelineName)
 			if err != nil {
 				return nil, err
 			}
-			statusID, err := s.resolveStatusName(pipelineID, params.StatusName)
+			statusID, err := s.resolver.StatusID(pipelineID, params.StatusName)
 			if err != nil {
 				return nil, err
 			}
@@ -136,7 +136,7 @@ func (s *service) DeclineUnsorted(ctx context.Context, uid string, params *gkitm
 	apiParams := map[string]interface{}{}
 
 	if params != nil && params.UserName != "" {
-		userID, err := s.resolveUserName(params.UserName)
+		userID, err := s.resolver.UserID(params.UserName)
 		if err != nil {
 			return nil, err
 		}
@@ -167,7 +167,7 @@ func (s *service) SummaryUnsorted(ctx context.Context, filter *gkitmodels.Unsort
 	f := filters.NewUnsortedSummaryFilter()
 	if filter != nil {
 		if filter.PipelineName != "" {
-			id, err := s.resolvePipelineName(filter.PipelineName)
+			id, err := s.resolver.PipelineID(filter.PipelineName)
 			if err != nil {
 				return nil, err
 			}
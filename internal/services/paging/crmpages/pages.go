@@ -0,0 +1,228 @@
+// Package crmpages provides a paging.Fetcher backed by the amoCRM services.
+package crmpages
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	gkitmodels "github.com/tihn/amo-ai-tgbot-go/internal/models/tools"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/crm/catalogs"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/crm/entities"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/crm/files"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/crm/products"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/paging"
+)
+
+// searches are the tool actions whose results come in pages.
+var searches = map[string]map[string]bool{
+	"entities": {"search": true},
+	"catalogs": {"list": true, "list_elements": true},
+	"files":    {"list": true},
+	"products": {"search": true},
+}
+
+var entityTitles = map[string]string{
+	"leads":     "Сделки",
+	"contacts":  "Контакты",
+	"companies": "Компании",
+}
+
+// fetcher decodes tool arguments the same way the tools do and calls the services directly.
+type fetcher struct {
+	entities entities.Service
+	catalogs catalogs.Service
+	files    files.Service
+	products products.Service
+}
+
+// New creates a paging.Fetcher.
+func New(entitiesSvc entities.Service, catalogsSvc catalogs.Service, filesSvc files.Service, productsSvc products.Service) paging.Fetcher {
+	return &fetcher{
+		entities: entitiesSvc,
+		catalogs: catalogsSvc,
+		files:    filesSvc,
+		products: productsSvc,
+	}
+}
+
+// Query implements paging.Fetcher.
+func (f *fetcher) Query(toolName string, args map[string]any) (paging.Query, int, bool) {
+	action, _ := args["action"].(string)
+	if !searches[toolName][action] {
+		return paging.Query{}, 0, false
+	}
+	page := 1
+	if filter, ok := args["filter"].(map[string]any); ok {
+		if p, ok := filter["page"].(float64); ok && p > 1 {
+			page = int(p)
+		}
+	}
+	return paging.Query{Tool: toolName, Args: args}, page, true
+}
+
+// Fetch implements paging.Fetcher.
+func (f *fetcher) Fetch(ctx context.Context, q paging.Query, page int) (*paging.Page, error) {
+	switch q.Tool {
+	case "entities":
+		return f.fetchEntities(ctx, q.Args, page)
+	case "catalogs":
+		return f.fetchCatalogs(ctx, q.Args, page)
+	case "files":
+		var input gkitmodels.FilesInput
+		if err := decode(q.Args, &input); err != nil {
+			return nil, err
+		}
+		if input.Filter == nil {
+			input.Filter = &gkitmodels.FileFilter{}
+		}
+		input.Filter.Page = page
+		result, err := f.files.ListFiles(ctx, input.Filter)
+		if err != nil {
+			return nil, err
+		}
+		return toPage("Файлы", result, page)
+	case "products":
+		var input gkitmodels.ProductsInput
+		if err := decode(q.Args, &input); err != nil {
+			return nil, err
+		}
+		if input.Filter == nil {
+			input.Filter = &gkitmodels.ProductFilter{}
+		}
+		input.Filter.Page = page
+		result, err := f.products.SearchProducts(ctx, input.Filter, input.With)
+		if err != nil {
+			return nil, err
+		}
+		return toPage("Товары", result, page)
+	}
+	return nil, fmt.Errorf("crmpages: tool %q has no pages", q.Tool)
+}
+
+func (f *fetcher) fetchEntities(ctx context.Context, args map[string]any, page int) (*paging.Page, error) {
+	var input gkitmodels.EntitiesInput
+	if err := decode(args, &input); err != nil {
+		return nil, err
+	}
+	if input.Filter == nil {
+		input.Filter = &gkitmodels.EntitiesFilter{}
+	}
+	input.Filter.Page = page
+
+	var (
+		result *entities.SearchResult
+		err    error
+	)
+	switch input.EntityType {
+	case "leads":
+		result, err = f.entities.SearchLeads(ctx, input.Filter, input.With)
+	case "contacts":
+		result, err = f.entities.SearchContacts(ctx, input.Filter, input.With)
+	case "companies":
+		result, err = f.entities.SearchCompanies(ctx, input.Filter, input.With)
+	default:
+		return nil, fmt.Errorf("crmpages: unknown entity_type %q", input.EntityType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toPage(entityTitles[input.EntityType], result, page)
+}
+
+func (f *fetcher) fetchCatalogs(ctx context.Context, args map[string]any, page int) (*paging.Page, error) {
+	var input gkitmodels.CatalogsInput
+	if err := decode(args, &input); err != nil {
+		return nil, err
+	}
+	if input.Filter == nil {
+		input.Filter = &gkitmodels.CatalogFilter{}
+	}
+	input.Filter.Page = page
+
+	if input.Action == "list" {
+		result, err := f.catalogs.ListCatalogs(ctx, input.Filter)
+		if err != nil {
+			return nil, err
+		}
+		return toPage("Каталоги", result, page)
+	}
+	result, err := f.catalogs.ListElements(ctx, input.CatalogName, input.Filter)
+	if err != nil {
+		return nil, err
+	}
+	return toPage(fmt.Sprintf("Каталог «%s»", input.CatalogName), result, page)
+}
+
+// decode converts tool arguments into the tool's input, as the tools do.
+func decode(args map[string]any, input any) error {
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("crmpages: marshal args: %w", err)
+	}
+	if err := json.Unmarshal(raw, input); err != nil {
+		return fmt.Errorf("crmpages: unmarshal args: %w", err)
+	}
+	return nil
+}
+
+// toPage reads a search result through its JSON form: all paginated results
+// have "items" and "has_more", and their items share field names.
+func toPage(title string, result any, page int) (*paging.Page, error) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("crmpages: marshal result: %w", err)
+	}
+	var parsed struct {
+		Items   []map[string]any `json:"items"`
+		HasMore bool             `json:"has_more"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return nil, fmt.Errorf("crmpages: unmarshal result: %w", err)
+	}
+
+	p := &paging.Page{Title: title, Number: page, HasMore: parsed.HasMore}
+	for _, item := range parsed.Items {
+		p.Items = append(p.Items, itemLine(item))
+	}
+	return p, nil
+}
+
+// itemLine describes a record in one line: name, budget, status, responsible and ID.
+func itemLine(item map[string]any) string {
+	var parts []string
+	name := firstString(item, "name", "file_name", "text")
+	if name == "" {
+		name = strings.TrimSpace(firstString(item, "first_name") + " " + firstString(item, "last_name"))
+	}
+	if name == "" {
+		name = "Без названия"
+	}
+	parts = append(parts, name)
+
+	if price, ok := item["price"].(float64); ok && price > 0 {
+		parts = append(parts, strconv.FormatFloat(price, 'f', -1, 64)+" ₽")
+	}
+	for _, key := range []string{"status_name", "responsible_user_name"} {
+		if v := firstString(item, key); v != "" {
+			parts = append(parts, v)
+		}
+	}
+
+	line := strings.Join(parts, " · ")
+	if id, ok := item["id"].(float64); ok && id > 0 {
+		line += fmt.Sprintf(" (#%d)", int(id))
+	}
+	return line
+}
+
+func firstString(item map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if v, ok := item[key].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
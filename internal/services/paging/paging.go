@@ -0,0 +1,27 @@
+// Package paging lets users browse long amoCRM lists page by page: the last search
+// of the AI agent is repeated with another page directly through the CRM services.
+package paging
+
+import "context"
+
+// Query is a search tool call of the agent that can be repeated with another page.
+type Query struct {
+	Tool string
+	Args map[string]any
+}
+
+// Page is one page of search results.
+type Page struct {
+	Title   string   // what is listed, e.g. "Сделки"
+	Items   []string // one line per record
+	Number  int      // starts with 1
+	HasMore bool
+}
+
+// Fetcher repeats searches of the agent without the LLM.
+type Fetcher interface {
+	// Query recognizes a search it can repeat and returns the requested page; ok is false for other tool calls.
+	Query(toolName string, args map[string]any) (q Query, page int, ok bool)
+	// Fetch runs the search for the page.
+	Fetch(ctx context.Context, q Query, page int) (*Page, error)
+}
@@ -0,0 +1,14 @@
+// Package speech defines speech-to-text for voice messages.
+// Telegram service depends on this interface, not a concrete engine.
+package speech
+
+import (
+	"context"
+	"io"
+)
+
+// Transcriber converts recorded speech to text.
+type Transcriber interface {
+	// Transcribe returns the text of the audio. filename hints the format, e.g. "voice.ogg".
+	Transcribe(ctx context.Context, audio io.Reader, filename string) (string, error)
+}
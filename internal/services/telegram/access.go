@@ -0,0 +1,208 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/access"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
+)
+
+// CheckAccess returns a refusal message if the user is not in the allowlist.
+// ok is true when the user may use the bot.
+func (s *Service) CheckAccess(telegramUserID int64) (message string, ok bool) {
+	if s.access == nil || s.access.IsAllowed(telegramUserID) {
+		return "", true
+	}
+	return fmt.Sprintf(`🔒 <b>Бот доступен только сотрудникам</b>
+
+Извини, у тебя пока нет доступа. Чтобы его получить, передай администратору свой Telegram ID: <code>%d</code>`, telegramUserID), false
+}
+
+// withIdentity binds the user's amoCRM identity to the context of an AI request.
+func (s *Service) withIdentity(ctx context.Context, telegramUserID int64) context.Context {
+	id := agent.Identity{TelegramUserID: telegramUserID}
+	if s.access != nil {
+		if m, ok := s.access.Member(telegramUserID); ok {
+			id.Role = string(m.Role)
+			if m.Bound() {
+				id.AmoUserID, id.AmoUserName, id.Email = m.AmoUserID, m.AmoUserName, m.AmoEmail
+			}
+		}
+	}
+	return agent.ContextWithIdentity(ctx, id)
+}
+
+// amoBindingLine describes the user's amoCRM binding for /me.
+func (s *Service) amoBindingLine(telegramUserID int64) string {
+	if s.access == nil {
+		return ""
+	}
+	m, ok := s.access.Member(telegramUserID)
+	if !ok {
+		return ""
+	}
+	role := fmt.Sprintf("\n🔑 Роль: <b>%s</b>", roleTitles[m.Role])
+	if !m.Bound() {
+		return "\n\n👤 Сотрудник amoCRM не привязан. Используй /bind после подключения Google." + role
+	}
+	return fmt.Sprintf("\n\n👤 Сотрудник amoCRM: <b>%s</b> (%s)", html.EscapeString(m.AmoUserName), html.EscapeString(m.AmoEmail)) + role
+}
+
+// roleTitles are human-readable role names.
+var roleTitles = map[access.Role]string{
+	access.RoleReadOnly: "только чтение",
+	access.RoleSales:    "продажи",
+	access.RoleAdmin:    "администратор",
+}
+
+// HandleBind binds the user to the amoCRM user with the email of their connected Google account.
+func (s *Service) HandleBind(ctx context.Context, telegramUserID int64) string {
+	if s.access == nil {
+		return "❌ Управление доступом не настроено."
+	}
+	email := s.auth.GetUserEmail(telegramUserID)
+	if email == "" {
+		return "❌ Не знаю твой email.\n\nПодключи Google аккаунт (/connect) с той же почтой, что и в amoCRM, и повтори /bind."
+	}
+
+	m, err := s.access.Bind(ctx, telegramUserID, email)
+	switch {
+	case errors.Is(err, access.ErrAmoUserNotFound):
+		return fmt.Sprintf("❌ В amoCRM нет сотрудника с почтой <code>%s</code>.\n\nПопроси администратора привязать тебя вручную.", html.EscapeString(email))
+	case err != nil:
+		return fmt.Sprintf("❌ Ошибка привязки:\n%v", err)
+	}
+	return fmt.Sprintf("✅ Ты привязан к сотруднику amoCRM <b>%s</b>.\n\nТеперь «мои сделки» — это сделки, где ты ответственный.", html.EscapeString(m.AmoUserName))
+}
+
+// HandleAllow adds a user to the allowlist: /allow <telegram_id> [email].
+// With email the user is also bound to the amoCRM user with that email.
+func (s *Service) HandleAllow(ctx context.Context, telegramUserID int64, args string) string {
+	if msg, ok := s.checkAccessAdmin(telegramUserID); !ok {
+		return msg
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		return "Использование: /allow &lt;telegram_id&gt; [email сотрудника amoCRM]"
+	}
+	targetID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return "❌ Telegram ID должен быть числом."
+	}
+	var email string
+	if len(fields) == 2 {
+		email = fields[1]
+	}
+
+	m, err := s.access.Allow(ctx, targetID, telegramUserID, email)
+	switch {
+	case errors.Is(err, access.ErrAmoUserNotFound):
+		return fmt.Sprintf("❌ В amoCRM нет сотрудника с почтой <code>%s</code>.", html.EscapeString(email))
+	case err != nil:
+		return fmt.Sprintf("❌ Ошибка:\n%v", err)
+	}
+
+	if m.Bound() {
+		return fmt.Sprintf("✅ Доступ выдан: <code>%d</code> → <b>%s</b>", targetID, html.EscapeString(m.AmoUserName))
+	}
+	return fmt.Sprintf("✅ Доступ выдан: <code>%d</code>\n\nПользователь может привязаться к amoCRM сам через /bind.", targetID)
+}
+
+// HandleDeny removes a user from the allowlist: /deny <telegram_id>.
+func (s *Service) HandleDeny(telegramUserID int64, args string) string {
+	if msg, ok := s.checkAccessAdmin(telegramUserID); !ok {
+		return msg
+	}
+
+	targetID, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
+	if err != nil {
+		return "Использование: /deny &lt;telegram_id&gt;"
+	}
+
+	switch err := s.access.Deny(targetID); {
+	case errors.Is(err, access.ErrNotAllowed):
+		return fmt.Sprintf("ℹ️ У <code>%d</code> и так нет доступа.", targetID)
+	case errors.Is(err, access.ErrConfigAdmin):
+		return "❌ Администратора из ACCESS_ADMINS можно убрать только в конфиге."
+	case err != nil:
+		return fmt.Sprintf("❌ Ошибка:\n%v", err)
+	}
+	return fmt.Sprintf("✅ Доступ отозван: <code>%d</code>", targetID)
+}
+
+// HandleSetRole changes a user's role: /role <telegram_id> <role>.
+func (s *Service) HandleSetRole(telegramUserID int64, args string) string {
+	if msg, ok := s.checkAccessAdmin(telegramUserID); !ok {
+		return msg
+	}
+
+	const usage = "Использование: /role &lt;telegram_id&gt; &lt;readonly|sales|admin&gt;\n\n" +
+		"• readonly — только поиск и просмотр\n" +
+		"• sales — работа со сделками и задачами, без удалений и настроек; изменять, дополнять задачами и примечаниями и связывать можно только свои сделки, контакты и компании; файлы на диске — только загружать\n" +
+		"• admin — полный доступ"
+	fields := strings.Fields(args)
+	if len(fields) != 2 {
+		return usage
+	}
+	targetID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return "❌ Telegram ID должен быть числом."
+	}
+	role, err := access.ParseRole(strings.ToLower(fields[1]))
+	if err != nil {
+		return usage
+	}
+
+	m, err := s.access.SetRole(targetID, role)
+	switch {
+	case errors.Is(err, access.ErrNotAllowed):
+		return fmt.Sprintf("❌ У <code>%d</code> нет доступа к боту. Сначала выдай его через /allow.", targetID)
+	case errors.Is(err, access.ErrConfigAdmin):
+		return "❌ Роль администратора из ACCESS_ADMINS меняется только в конфиге."
+	case err != nil:
+		return fmt.Sprintf("❌ Ошибка:\n%v", err)
+	}
+	return fmt.Sprintf("✅ Роль <code>%d</code>: <b>%s</b>", targetID, roleTitles[m.Role])
+}
+
+// HandleAccessList lists allowed users and their amoCRM bindings.
+func (s *Service) HandleAccessList(telegramUserID int64) string {
+	if msg, ok := s.checkAccessAdmin(telegramUserID); !ok {
+		return msg
+	}
+
+	members := s.access.Members()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "👥 <b>Доступ к боту</b> (%d)\n\n", len(members))
+	for _, m := range members {
+		fmt.Fprintf(&sb, "• <code>%d</code>", m.TelegramUserID)
+		if m.Admin {
+			sb.WriteString(" 👑")
+		}
+		fmt.Fprintf(&sb, " [%s]", m.Role)
+		if m.Bound() {
+			fmt.Fprintf(&sb, " → %s", html.EscapeString(m.AmoUserName))
+		} else {
+			sb.WriteString(" → <i>не привязан</i>")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n/allow &lt;id&gt; [email] — выдать доступ\n/deny &lt;id&gt; — отозвать\n/role &lt;id&gt; &lt;роль&gt; — сменить роль\n/reload — обновить справочники amoCRM (воронки, статусы, поля)")
+	return sb.String()
+}
+
+func (s *Service) checkAccessAdmin(telegramUserID int64) (string, bool) {
+	if s.access == nil {
+		return "❌ Управление доступом не настроено.", false
+	}
+	if !s.access.IsAdmin(telegramUserID) {
+		return "⛔ Команда доступна только администраторам бота.", false
+	}
+	return "", true
+}
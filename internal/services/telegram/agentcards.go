@@ -0,0 +1,93 @@
+package telegram
+
+import (
+	"context"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/cards"
+)
+
+// CardTracker finds the record or task an AI turn was about, to show its card under the answer.
+type CardTracker struct {
+	calls map[string]string // card kinds of single-record calls waiting for their result, by call ID
+	refs  []cardRef         // records and tasks the turn got, created or changed, in order
+}
+
+// NewCardTracker creates a tracker for one AI turn.
+func (s *Service) NewCardTracker() *CardTracker {
+	return &CardTracker{calls: make(map[string]string)}
+}
+
+// Observe records single-record results of the entities and activities tools.
+func (t *CardTracker) Observe(event agent.StreamEvent) {
+	key := event.ToolCallID
+	if key == "" {
+		key = event.ToolName
+	}
+
+	switch event.Kind {
+	case agent.StreamEventToolCall:
+		if kind := cardKind(event.ToolName, event.ToolArgs); kind != "" {
+			t.calls[key] = kind
+		}
+	case agent.StreamEventToolResult:
+		kind, ok := t.calls[key]
+		if !ok {
+			return
+		}
+		delete(t.calls, key)
+		if _, failed := event.ToolResult["error"]; failed {
+			return
+		}
+		// Batches come back as a list, only a single record has its ID on top
+		id, _ := event.ToolResult["id"].(float64)
+		if id <= 0 {
+			return
+		}
+		ref := cardRef{kind: kind, id: int(id)}
+		for _, seen := range t.refs {
+			if seen == ref {
+				return
+			}
+		}
+		t.refs = append(t.refs, ref)
+	}
+}
+
+// cardKind returns the card kind of a tool call that gets, creates or changes one record or task.
+func cardKind(toolName string, args map[string]any) string {
+	action, _ := args["action"].(string)
+	switch toolName {
+	case "entities":
+		entityType, _ := args["entity_type"].(string)
+		switch action {
+		case "get", "create", "update", "sync":
+			return kindOf(cards.EntityType(entityType))
+		}
+	case "activities":
+		if layer, _ := args["layer"].(string); layer != "tasks" {
+			return ""
+		}
+		switch action {
+		case "get", "create", "update", "complete":
+			return taskKind
+		}
+	}
+	return ""
+}
+
+// AgentCard returns the card of the record or task the turn was about, or an empty result
+// when the turn touched none or several of them: a card for one of many would be a guess.
+// The shown record becomes the focus of the chat topic, like after a card command.
+func (s *Service) AgentCard(ctx context.Context, telegramUserID int64, chat Chat, t *CardTracker) CardResult {
+	if s.cards == nil || t == nil || len(t.refs) != 1 {
+		return CardResult{}
+	}
+	ref := t.refs[0]
+	result := s.showCard(ctx, telegramUserID, ref, "")
+	if result.Alert != "" {
+		return CardResult{}
+	}
+	s.setFocus(telegramUserID, chat, ref)
+	return result
+}
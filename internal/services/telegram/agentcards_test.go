@@ -0,0 +1,88 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
+)
+
+func TestCardTracker(t *testing.T) {
+	call := func(id, tool string, args map[string]any) agent.StreamEvent {
+		return agent.StreamEvent{Kind: agent.StreamEventToolCall, ToolCallID: id, ToolName: tool, ToolArgs: args}
+	}
+	result := func(id string, res map[string]any) agent.StreamEvent {
+		return agent.StreamEvent{Kind: agent.StreamEventToolResult, ToolCallID: id, ToolResult: res}
+	}
+
+	tests := []struct {
+		name   string
+		events []agent.StreamEvent
+		want   []cardRef
+	}{
+		{
+			name: "lead get",
+			events: []agent.StreamEvent{
+				call("1", "entities", map[string]any{"entity_type": "leads", "action": "get", "id": 5.0}),
+				result("1", map[string]any{"id": 5.0, "name": "Альфа"}),
+			},
+			want: []cardRef{{kind: "l", id: 5}},
+		},
+		{
+			name: "task completed",
+			events: []agent.StreamEvent{
+				call("1", "activities", map[string]any{"layer": "tasks", "action": "complete", "id": 7.0}),
+				result("1", map[string]any{"id": 7.0, "is_completed": true}),
+			},
+			want: []cardRef{{kind: taskKind, id: 7}},
+		},
+		{
+			name: "same contact twice",
+			events: []agent.StreamEvent{
+				call("1", "entities", map[string]any{"entity_type": "contacts", "action": "get", "id": 2.0}),
+				result("1", map[string]any{"id": 2.0}),
+				call("2", "entities", map[string]any{"entity_type": "contacts", "action": "update", "id": 2.0}),
+				result("2", map[string]any{"id": 2.0}),
+			},
+			want: []cardRef{{kind: "c", id: 2}},
+		},
+		{
+			name: "batch create",
+			events: []agent.StreamEvent{
+				call("1", "entities", map[string]any{"entity_type": "leads", "action": "create"}),
+				result("1", map[string]any{"result": []any{map[string]any{"id": 1.0}, map[string]any{"id": 2.0}}}),
+			},
+		},
+		{
+			name: "failed",
+			events: []agent.StreamEvent{
+				call("1", "entities", map[string]any{"entity_type": "companies", "action": "get", "id": 3.0}),
+				result("1", map[string]any{"error": "not found"}),
+			},
+		},
+		{
+			name: "search and notes",
+			events: []agent.StreamEvent{
+				call("1", "entities", map[string]any{"entity_type": "leads", "action": "search"}),
+				result("1", map[string]any{"id": 1.0}),
+				call("2", "activities", map[string]any{"layer": "notes", "action": "create"}),
+				result("2", map[string]any{"id": 9.0}),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := (&Service{}).NewCardTracker()
+			for _, event := range tt.events {
+				tracker.Observe(event)
+			}
+			if len(tracker.refs) != len(tt.want) {
+				t.Fatalf("refs = %v, want %v", tracker.refs, tt.want)
+			}
+			for i := range tt.want {
+				if tracker.refs[i] != tt.want[i] {
+					t.Errorf("refs = %v, want %v", tracker.refs, tt.want)
+				}
+			}
+		})
+	}
+}
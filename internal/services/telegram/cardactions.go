@@ -0,0 +1,520 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/access"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/cards"
+)
+
+// CallbackCard is the callback data prefix of card buttons:
+// "ec:<action>:<kind>:<id>[:<arg>]", well within Telegram's 64 bytes.
+const CallbackCard = "ec:"
+
+// Card button actions.
+const (
+	cardRefresh    = "v"
+	cardStatusMenu = "sm"
+	cardStatus     = "s" // arg — nameKey of the status
+	cardUserMenu   = "um"
+	cardUser       = "u" // arg — nameKey of the user
+	cardAddTask    = "at"
+	cardAddNote    = "an"
+	cardDone       = "d"
+)
+
+const (
+	// taskKind is the kind of task cards, records use recordKinds.
+	taskKind = "t"
+	// cardInputTTL is how long the bot waits for the text of a task or note.
+	cardInputTTL = 10 * time.Minute
+	// maxMenuButtons bounds status and user menus; Telegram allows 100 buttons per message.
+	maxMenuButtons = 40
+
+	deniedAlert = "⛔ Недостаточно прав для этого действия."
+)
+
+// recordKinds are one-letter codes of record types in callback data.
+var recordKinds = map[string]cards.EntityType{
+	"l": cards.Leads,
+	"c": cards.Contacts,
+	"k": cards.Companies,
+}
+
+// cardCommands show a card by ID: "/lead 123".
+var cardCommands = map[string]string{
+	"/lead":    "l",
+	"/contact": "c",
+	"/company": "k",
+	"/task":    taskKind,
+}
+
+// CardResult is the outcome of a card command, button or text input.
+type CardResult struct {
+	MessageID int    // the card message
+	Text      string // new card text, empty — the card stays as is
+	Keyboard  *models.InlineKeyboardMarkup
+	Alert     string // shown as a callback alert
+	Reply     string // sent as a new message
+}
+
+// cardRef identifies the record or task a card shows.
+type cardRef struct {
+	kind string
+	id   int
+}
+
+func (r cardRef) button(text, action string, arg ...string) models.InlineKeyboardButton {
+	parts := append([]string{action, r.kind, strconv.Itoa(r.id)}, arg...)
+	return models.InlineKeyboardButton{Text: text, CallbackData: CallbackCard + strings.Join(parts, ":")}
+}
+
+// cardInput is the task or note text the bot waits for after a card button.
+type cardInput struct {
+	action    string // cardAddTask or cardAddNote
+	ref       cardRef
+	name      string
+	messageID int
+	expires   time.Time
+}
+
+// cardRights are the card actions a user may take. They follow the agent's role policies:
+// readonly only views, sales changes only their own records and tasks (the amoCRM user
+// they are bound to is responsible), admin may do everything.
+type cardRights struct {
+	edit bool // change status and responsible
+	add  bool // add tasks and notes, complete tasks
+}
+
+func (s *Service) cardRights(telegramUserID int64, responsibleID int) cardRights {
+	if s.access == nil {
+		return cardRights{edit: true, add: true}
+	}
+	m, ok := s.access.Member(telegramUserID)
+	if !ok {
+		return cardRights{}
+	}
+	switch m.Role {
+	case access.RoleAdmin:
+		return cardRights{edit: true, add: true}
+	case access.RoleSales:
+		own := m.Bound() && responsibleID == m.AmoUserID
+		return cardRights{edit: own, add: own}
+	}
+	return cardRights{}
+}
+
+// mayChange reports whether the user's role allows changes from cards at all;
+// the record itself is checked with cardRights.
+func (s *Service) mayChange(telegramUserID int64) bool {
+	if s.access == nil {
+		return true
+	}
+	m, _ := s.access.Member(telegramUserID)
+	return m.Role == access.RoleAdmin || m.Role == access.RoleSales
+}
+
+// IsCardCommand reports whether the text is a card command: /lead, /contact, /company or /task.
+func IsCardCommand(text string) bool {
+	command, _, _ := strings.Cut(text, " ")
+	_, ok := cardCommands[command]
+	return ok
+}
+
+// HandleCardCommand shows the card of a record or task by ID: "/lead 123".
+// A shown record becomes the focus of the chat topic, files sent without a caption go to it.
+func (s *Service) HandleCardCommand(ctx context.Context, telegramUserID int64, chat Chat, text string) (string, *models.InlineKeyboardMarkup) {
+	if s.cards == nil {
+		return "ℹ️ Карточки недоступны.", nil
+	}
+	command, arg, _ := strings.Cut(text, " ")
+	id, err := strconv.Atoi(strings.TrimSpace(arg))
+	if err != nil || id <= 0 {
+		return fmt.Sprintf("Укажи ID: <code>%s 12345</code>", command), nil
+	}
+
+	ref := cardRef{kind: cardCommands[command], id: id}
+	result := s.showCard(ctx, telegramUserID, ref, "")
+	if result.Alert != "" {
+		return result.Alert, nil
+	}
+	s.setFocus(telegramUserID, chat, ref)
+	return result.Text, result.Keyboard
+}
+
+// HandleCardCallback performs a card button action and returns the updated card.
+func (s *Service) HandleCardCallback(ctx context.Context, telegramUserID int64, chat Chat, messageID int, data string) CardResult {
+	result := s.cardCallback(ctx, telegramUserID, chat, messageID, data)
+	result.MessageID = messageID
+	return result
+}
+
+func (s *Service) cardCallback(ctx context.Context, telegramUserID int64, chat Chat, messageID int, data string) CardResult {
+	if s.cards == nil {
+		return CardResult{Alert: "ℹ️ Карточки недоступны."}
+	}
+	parts := strings.Split(strings.TrimPrefix(data, CallbackCard), ":")
+	if len(parts) < 3 {
+		return CardResult{Alert: "❓ Неизвестное действие."}
+	}
+	action, arg := parts[0], ""
+	if len(parts) > 3 {
+		arg = parts[3]
+	}
+	ref := cardRef{kind: parts[1]}
+	id, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return CardResult{Alert: "❓ Неизвестное действие."}
+	}
+	ref.id = id
+
+	if ref.kind == taskKind {
+		return s.taskAction(ctx, telegramUserID, ref, action)
+	}
+	entityType, ok := recordKinds[ref.kind]
+	if !ok {
+		return CardResult{Alert: "❓ Неизвестное действие."}
+	}
+	// Actions are checked against the current record, not the one shown on the card
+	card, err := s.cards.Card(ctx, entityType, ref.id)
+	if err != nil {
+		return CardResult{Alert: cardError(err)}
+	}
+	rights := s.cardRights(telegramUserID, card.ResponsibleID)
+	s.setFocus(telegramUserID, chat, ref)
+
+	switch action {
+	case cardRefresh:
+		return s.recordView(telegramUserID, ref, card, "")
+	case cardStatusMenu, cardStatus:
+		if !rights.edit || card.Type != cards.Leads {
+			return CardResult{Alert: deniedAlert}
+		}
+		statuses := s.cards.Statuses(card.Pipeline)
+		if action == cardStatusMenu {
+			return menuView(ref, card, cardStatus, "📊 Выбери новый статус:", statuses, card.Status)
+		}
+		status, found := findByKey(statuses, arg)
+		if !found {
+			return CardResult{Alert: "Статус не найден — открой список заново."}
+		}
+		if err := s.cards.SetStatus(ctx, ref.id, card.Pipeline, status); err != nil {
+			log.Printf("❌ Card action error: %v", err)
+			return CardResult{Alert: "❌ Не удалось изменить статус."}
+		}
+		return s.showCard(ctx, telegramUserID, ref, "✅ Статус: "+html.EscapeString(status))
+	case cardUserMenu, cardUser:
+		if !rights.edit {
+			return CardResult{Alert: deniedAlert}
+		}
+		users := s.cards.Users()
+		if action == cardUserMenu {
+			return menuView(ref, card, cardUser, "🧑‍💼 Выбери ответственного:", users, card.Responsible)
+		}
+		user, found := findByKey(users, arg)
+		if !found {
+			return CardResult{Alert: "Сотрудник не найден — открой список заново."}
+		}
+		if err := s.cards.SetResponsible(ctx, card.Type, ref.id, user); err != nil {
+			log.Printf("❌ Card action error: %v", err)
+			return CardResult{Alert: "❌ Не удалось сменить ответственного."}
+		}
+		return s.showCard(ctx, telegramUserID, ref, "✅ Ответственный: "+html.EscapeString(user))
+	case cardAddTask, cardAddNote:
+		if !rights.add {
+			return CardResult{Alert: deniedAlert}
+		}
+		s.mu.Lock()
+		s.cardInputs[chatUser{telegramUserID, chat}] = cardInput{
+			action:    action,
+			ref:       ref,
+			name:      card.Name,
+			messageID: messageID,
+			expires:   time.Now().Add(cardInputTTL),
+		}
+		s.mu.Unlock()
+		prompt := "📝 Ответь на это сообщение текстом задачи для «%s». Срок — завтра.\n\n/cancel — отменить"
+		if action == cardAddNote {
+			prompt = "🗒 Ответь на это сообщение текстом примечания для «%s».\n\n/cancel — отменить"
+		}
+		return CardResult{Reply: fmt.Sprintf(prompt, html.EscapeString(card.Name))}
+	}
+	return CardResult{Alert: "❓ Неизвестное действие."}
+}
+
+func (s *Service) taskAction(ctx context.Context, telegramUserID int64, ref cardRef, action string) CardResult {
+	task, err := s.cards.Task(ctx, ref.id)
+	if err != nil {
+		return CardResult{Alert: cardError(err)}
+	}
+	switch action {
+	case cardRefresh:
+		return s.taskView(telegramUserID, ref, task, "")
+	case cardDone:
+		if !s.cardRights(telegramUserID, task.ResponsibleID).add {
+			return CardResult{Alert: deniedAlert}
+		}
+		if task.Completed {
+			return s.taskView(telegramUserID, ref, task, "ℹ️ Задача уже выполнена")
+		}
+		if err := s.cards.CompleteTask(ctx, ref.id); err != nil {
+			log.Printf("❌ Card action error: %v", err)
+			return CardResult{Alert: "❌ Не удалось выполнить задачу."}
+		}
+		return s.showCard(ctx, telegramUserID, ref, "✅ Задача выполнена")
+	}
+	return CardResult{Alert: "❓ Неизвестное действие."}
+}
+
+// HasCardInput reports whether the bot waits for a task or note text from the user in the chat topic.
+func (s *Service) HasCardInput(telegramUserID int64, chat Chat) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := chatUser{telegramUserID, chat}
+	in, ok := s.cardInputs[key]
+	if ok && time.Now().After(in.expires) {
+		delete(s.cardInputs, key)
+		return false
+	}
+	return ok
+}
+
+// HandleCardInput adds the task or note the bot was waiting for and updates the card.
+func (s *Service) HandleCardInput(ctx context.Context, telegramUserID int64, chat Chat, text string) CardResult {
+	s.mu.Lock()
+	key := chatUser{telegramUserID, chat}
+	in, ok := s.cardInputs[key]
+	delete(s.cardInputs, key)
+	s.mu.Unlock()
+
+	text = strings.TrimSpace(text)
+	switch {
+	case !ok || s.cards == nil:
+		return CardResult{Reply: "ℹ️ Время ввода истекло, нажми кнопку на карточке ещё раз."}
+	case text == "":
+		return CardResult{Reply: "ℹ️ Пустой текст, нажми кнопку на карточке ещё раз."}
+	case !s.mayChange(telegramUserID):
+		return CardResult{Reply: deniedAlert}
+	}
+
+	// The record may have been reassigned while the user was typing
+	entityType := recordKinds[in.ref.kind]
+	card, err := s.cards.Card(ctx, entityType, in.ref.id)
+	if err != nil {
+		return CardResult{Reply: cardError(err)}
+	}
+	if !s.cardRights(telegramUserID, card.ResponsibleID).add {
+		return CardResult{Reply: deniedAlert}
+	}
+
+	var notice string
+	if in.action == cardAddTask {
+		err = s.cards.AddTask(ctx, entityType, in.ref.id, text, s.amoUserName(telegramUserID))
+		notice = "✅ Задача добавлена"
+	} else {
+		err = s.cards.AddNote(ctx, entityType, in.ref.id, text)
+		notice = "✅ Примечание добавлено"
+	}
+	if err != nil {
+		log.Printf("❌ Card action error: %v", err)
+		return CardResult{Reply: fmt.Sprintf("❌ Не удалось сохранить в «%s»\n\n%v", html.EscapeString(in.name), err)}
+	}
+
+	result := s.showCard(ctx, telegramUserID, in.ref, notice)
+	result.MessageID = in.messageID
+	result.Reply = fmt.Sprintf("%s в «%s».", notice, html.EscapeString(in.name))
+	return result
+}
+
+// cancelCardInput stops waiting for a task or note text (/cancel).
+func (s *Service) cancelCardInput(telegramUserID int64, chat Chat) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := chatUser{telegramUserID, chat}
+	_, ok := s.cardInputs[key]
+	delete(s.cardInputs, key)
+	return ok
+}
+
+// amoUserName returns the amoCRM user the Telegram user is bound to, if any.
+func (s *Service) amoUserName(telegramUserID int64) string {
+	if s.access == nil {
+		return ""
+	}
+	m, _ := s.access.Member(telegramUserID)
+	return m.AmoUserName
+}
+
+// showCard loads the record or task and renders its card; notice goes above the card.
+func (s *Service) showCard(ctx context.Context, telegramUserID int64, ref cardRef, notice string) CardResult {
+	if ref.kind == taskKind {
+		task, err := s.cards.Task(ctx, ref.id)
+		if err != nil {
+			return CardResult{Alert: cardError(err)}
+		}
+		return s.taskView(telegramUserID, ref, task, notice)
+	}
+	entityType, ok := recordKinds[ref.kind]
+	if !ok {
+		return CardResult{Alert: "❓ Неизвестное действие."}
+	}
+	card, err := s.cards.Card(ctx, entityType, ref.id)
+	if err != nil {
+		return CardResult{Alert: cardError(err)}
+	}
+	return s.recordView(telegramUserID, ref, card, notice)
+}
+
+func (s *Service) recordView(telegramUserID int64, ref cardRef, card *cards.Card, notice string) CardResult {
+	rights := s.cardRights(telegramUserID, card.ResponsibleID)
+	var rows [][]models.InlineKeyboardButton
+	if rights.edit {
+		var row []models.InlineKeyboardButton
+		if card.Type == cards.Leads && card.Pipeline != "" {
+			row = append(row, ref.button("📊 Статус", cardStatusMenu))
+		}
+		rows = append(rows, append(row, ref.button("🧑‍💼 Ответственный", cardUserMenu)))
+	}
+	if rights.add {
+		rows = append(rows, []models.InlineKeyboardButton{
+			ref.button("📝 Задача", cardAddTask),
+			ref.button("🗒 Примечание", cardAddNote),
+		})
+	}
+	rows = append(rows, []models.InlineKeyboardButton{ref.button("🔄 Обновить", cardRefresh)})
+
+	return CardResult{
+		Text:     withNotice(notice, RenderCard(*card)),
+		Keyboard: &models.InlineKeyboardMarkup{InlineKeyboard: rows},
+	}
+}
+
+func (s *Service) taskView(telegramUserID int64, ref cardRef, task *cards.Task, notice string) CardResult {
+	var rows [][]models.InlineKeyboardButton
+	if !task.Completed && s.cardRights(telegramUserID, task.ResponsibleID).add {
+		rows = append(rows, []models.InlineKeyboardButton{ref.button("✅ Выполнить", cardDone)})
+	}
+	if kind := kindOf(task.EntityType); kind != "" {
+		t := cardTitles[task.EntityType]
+		parent := cardRef{kind: kind, id: task.EntityID}
+		rows = append(rows, []models.InlineKeyboardButton{parent.button(t.icon+" "+t.title, cardRefresh)})
+	}
+	rows = append(rows, []models.InlineKeyboardButton{ref.button("🔄 Обновить", cardRefresh)})
+
+	return CardResult{
+		Text:     withNotice(notice, RenderTask(*task)),
+		Keyboard: &models.InlineKeyboardMarkup{InlineKeyboard: rows},
+	}
+}
+
+// menuView shows the card with a choice of statuses or users instead of its buttons.
+func menuView(ref cardRef, card *cards.Card, action, title string, names []string, current string) CardResult {
+	var rows [][]models.InlineKeyboardButton
+	var row []models.InlineKeyboardButton
+	shown := 0
+	for _, name := range names {
+		if name == current {
+			continue
+		}
+		if shown == maxMenuButtons {
+			title += fmt.Sprintf("\n<i>Показаны первые %d, остальные — в amoCRM.</i>", maxMenuButtons)
+			break
+		}
+		row = append(row, ref.button(name, action, nameKey(name)))
+		shown++
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, []models.InlineKeyboardButton{ref.button("⬅️ Назад", cardRefresh)})
+
+	return CardResult{
+		Text:     RenderCard(*card) + "\n\n" + title,
+		Keyboard: &models.InlineKeyboardMarkup{InlineKeyboard: rows},
+	}
+}
+
+// RenderTask renders a task as an HTML message.
+func RenderTask(task cards.Task) string {
+	var sb strings.Builder
+	icon := "📌"
+	if task.Completed {
+		icon = "✅"
+	}
+	fmt.Fprintf(&sb, "%s <b>Задача #%d</b>\n", icon, task.ID)
+	if task.Text != "" {
+		fmt.Fprintf(&sb, "%s\n", html.EscapeString(task.Text))
+	}
+	if task.Deadline != "" {
+		fmt.Fprintf(&sb, "⏰ %s\n", html.EscapeString(task.Deadline))
+	}
+	if task.Responsible != "" {
+		fmt.Fprintf(&sb, "🧑‍💼 %s\n", html.EscapeString(task.Responsible))
+	}
+	if task.Completed {
+		sb.WriteString("Выполнена")
+		if task.Result != "" {
+			fmt.Fprintf(&sb, ": %s", html.EscapeString(task.Result))
+		}
+		sb.WriteString("\n")
+	}
+	if task.URL != "" {
+		fmt.Fprintf(&sb, `🔗 <a href="%s">Открыть в amoCRM</a>`, html.EscapeString(task.URL))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func withNotice(notice, text string) string {
+	if notice == "" {
+		return text
+	}
+	return notice + "\n\n" + text
+}
+
+// nameKey is a short stable key of a status or user name for callback data:
+// names may be longer than Telegram allows, and indexes shift when the list changes.
+func nameKey(name string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	return strconv.FormatUint(uint64(h.Sum32()), 36)
+}
+
+func findByKey(names []string, key string) (string, bool) {
+	for _, name := range names {
+		if nameKey(name) == key {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func kindOf(entityType cards.EntityType) string {
+	for kind, t := range recordKinds {
+		if t == entityType {
+			return kind
+		}
+	}
+	return ""
+}
+
+func cardError(err error) string {
+	if errors.Is(err, cards.ErrNotFound) {
+		return "🔍 Запись не найдена."
+	}
+	log.Printf("❌ Card error: %v", err)
+	return "❌ amoCRM сейчас недоступен, попробуй позже."
+}
@@ -0,0 +1,137 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
+)
+
+// Callback data prefixes for confirmation buttons, followed by the confirmation ID.
+const (
+	CallbackConfirmYes = "confirm_yes:"
+	CallbackConfirmNo  = "confirm_no:"
+)
+
+// confirmationTimeout is how long a destructive action waits for the user's answer.
+const confirmationTimeout = 2 * time.Minute
+
+// ConfirmationExpiredText replaces the confirmation message when nobody answered in time.
+const ConfirmationExpiredText = "⌛ <b>Время на подтверждение истекло</b>\n\nДействие не выполнено."
+
+// pendingConfirmation is an action waiting for the user's answer.
+// It stays registered until WaitConfirmation returns, so an answer that arrives
+// before the wait starts is not lost.
+type pendingConfirmation struct {
+	telegramUserID int64
+	summary        string
+	answered       bool
+	result         chan bool // buffered, receives exactly one answer
+}
+
+// NewConfirmation registers an action that needs the user's approval
+// and returns its ID with the message and Confirm/Cancel buttons to send.
+func (s *Service) NewConfirmation(telegramUserID int64, c agent.Confirmation) (id, text string, keyboard *models.InlineKeyboardMarkup) {
+	s.mu.Lock()
+	s.confirmSeq++
+	id = strconv.FormatUint(s.confirmSeq, 36)
+	s.confirmations[id] = &pendingConfirmation{
+		telegramUserID: telegramUserID,
+		summary:        c.Summary,
+		result:         make(chan bool, 1),
+	}
+	s.mu.Unlock()
+
+	warning := "Действие необратимо. "
+	if c.Reversible {
+		warning = ""
+	}
+	text = fmt.Sprintf(`⚠️ <b>Нужно подтверждение</b>
+
+%s
+
+%sПодтверди в течение %d мин.`, html.EscapeString(c.Summary), warning, int(confirmationTimeout.Minutes()))
+	keyboard = &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{Text: "✅ Подтвердить", CallbackData: CallbackConfirmYes + id},
+				{Text: "❌ Отмена", CallbackData: CallbackConfirmNo + id},
+			},
+		},
+	}
+	return id, text, keyboard
+}
+
+// DiscardConfirmation removes a confirmation that will never be waited for,
+// e.g. when its message could not be sent.
+func (s *Service) DiscardConfirmation(id string) {
+	s.mu.Lock()
+	delete(s.confirmations, id)
+	s.mu.Unlock()
+}
+
+// WaitConfirmation blocks until the user answers the confirmation, it times out
+// or ctx is cancelled. On timeout returns agent.ErrConfirmationTimeout.
+func (s *Service) WaitConfirmation(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	pending, ok := s.confirmations[id]
+	s.mu.Unlock()
+	if !ok {
+		return false, fmt.Errorf("unknown confirmation %q", id)
+	}
+	defer s.DiscardConfirmation(id)
+
+	timer := time.NewTimer(confirmationTimeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case approved := <-pending.result:
+		return approved, nil
+	case <-timer.C:
+		err = agent.ErrConfirmationTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	s.mu.Lock()
+	answered := pending.answered
+	pending.answered = true // late button presses are rejected from now on
+	s.mu.Unlock()
+	if answered {
+		// The answer arrived at the same moment — it wins
+		return <-pending.result, nil
+	}
+	return false, err
+}
+
+// ResolveConfirmation delivers the user's answer from a Confirm/Cancel button.
+// Returns the new text of the confirmation message, or an alert to show
+// when the answer is not accepted (another user, expired confirmation).
+func (s *Service) ResolveConfirmation(telegramUserID int64, id string, approved bool) (text, alert string) {
+	s.mu.Lock()
+	pending, ok := s.confirmations[id]
+	switch {
+	case !ok || pending.answered:
+		s.mu.Unlock()
+		return "", "⌛ Подтверждение уже неактуально."
+	case pending.telegramUserID != telegramUserID:
+		s.mu.Unlock()
+		return "", "⛔ Подтвердить действие может только автор запроса."
+	}
+	pending.answered = true
+	s.mu.Unlock()
+
+	pending.result <- approved
+
+	summary := html.EscapeString(pending.summary)
+	if approved {
+		return "✅ <b>Подтверждено</b>\n\n" + summary, ""
+	}
+	return "❌ <b>Отменено</b>\n\n" + summary, ""
+}
@@ -0,0 +1,140 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/cards"
+)
+
+const (
+	// InlineCacheTime is how long Telegram may cache inline results, in seconds.
+	// Results are personal because access depends on the user.
+	InlineCacheTime = 30
+	// minInlineQuery is the shortest query that is searched, shorter ones match too much.
+	minInlineQuery = 2
+)
+
+// cardTitles are entity names shown on cards.
+var cardTitles = map[cards.EntityType]struct{ icon, title string }{
+	cards.Leads:     {"💼", "Сделка"},
+	cards.Contacts:  {"👤", "Контакт"},
+	cards.Companies: {"🏢", "Компания"},
+}
+
+// HandleInlineQuery searches amoCRM for an inline query ("@bot Альфа") without the AI agent
+// and returns cards that can be sent to any chat.
+func (s *Service) HandleInlineQuery(ctx context.Context, telegramUserID int64, query string) []models.InlineQueryResult {
+	if _, ok := s.CheckAccess(telegramUserID); !ok {
+		return []models.InlineQueryResult{inlineNotice("noaccess", "🔒 Нет доступа",
+			"Бот доступен только сотрудникам. Напиши боту в личные сообщения, чтобы узнать свой Telegram ID.")}
+	}
+	if s.cards == nil {
+		return nil
+	}
+
+	query = strings.TrimSpace(query)
+	if utf8.RuneCountInString(query) < minInlineQuery {
+		return []models.InlineQueryResult{inlineNotice("hint", "🔎 Поиск в amoCRM",
+			"Введи название сделки, имя контакта или компании, телефон или email.")}
+	}
+
+	found, err := s.cards.Search(ctx, query)
+	if err != nil {
+		log.Printf("❌ Inline search error: %v", err)
+		return []models.InlineQueryResult{inlineNotice("error", "❌ Ошибка поиска", "amoCRM сейчас недоступен, попробуй позже.")}
+	}
+	if len(found) == 0 {
+		return []models.InlineQueryResult{inlineNotice("empty", "Ничего не найдено", "Попробуй другой запрос.")}
+	}
+
+	results := make([]models.InlineQueryResult, 0, len(found))
+	for _, card := range found {
+		t := cardTitles[card.Type]
+		results = append(results, &models.InlineQueryResultArticle{
+			ID:          string(card.Type) + ":" + strconv.Itoa(card.ID),
+			Title:       t.icon + " " + card.Name,
+			Description: cardDescription(card),
+			URL:         card.URL,
+			InputMessageContent: &models.InputTextMessageContent{
+				MessageText:        RenderCard(card),
+				ParseMode:          models.ParseModeHTML,
+				LinkPreviewOptions: &models.LinkPreviewOptions{IsDisabled: bot.True()},
+			},
+		})
+	}
+	return results
+}
+
+// RenderCard renders a record as an HTML message.
+func RenderCard(card cards.Card) string {
+	t := cardTitles[card.Type]
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s <b>%s «%s»</b>\n", t.icon, t.title, html.EscapeString(card.Name))
+	if card.Type == cards.Leads {
+		fmt.Fprintf(&sb, "💰 %s\n", formatPrice(card.Price))
+		if card.Status != "" {
+			fmt.Fprintf(&sb, "📊 %s\n", html.EscapeString(joinNonEmpty(" → ", card.Pipeline, card.Status)))
+		}
+	}
+	if card.Responsible != "" {
+		fmt.Fprintf(&sb, "🧑‍💼 %s\n", html.EscapeString(card.Responsible))
+	}
+	fmt.Fprintf(&sb, `🔗 <a href="%s">Открыть в amoCRM</a>`, html.EscapeString(card.URL))
+	return sb.String()
+}
+
+// cardDescription is the second line of an inline result.
+func cardDescription(card cards.Card) string {
+	var parts []string
+	if card.Type == cards.Leads {
+		parts = append(parts, formatPrice(card.Price), card.Status)
+	} else {
+		parts = append(parts, cardTitles[card.Type].title)
+	}
+	parts = append(parts, card.Responsible)
+	return joinNonEmpty(" · ", parts...)
+}
+
+// formatPrice formats a lead budget with thousands separators: 1 250 000 ₽.
+func formatPrice(price int) string {
+	s := strconv.Itoa(price)
+	var sb strings.Builder
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			sb.WriteRune(' ')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String() + " ₽"
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var nonEmpty []string
+	for _, p := range parts {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, sep)
+}
+
+// inlineNotice is an inline result that explains why there are no cards.
+func inlineNotice(id, title, text string) models.InlineQueryResult {
+	return &models.InlineQueryResultArticle{
+		ID:          id,
+		Title:       title,
+		Description: text,
+		InputMessageContent: &models.InputTextMessageContent{
+			MessageText: title + "\n\n" + text,
+		},
+	}
+}
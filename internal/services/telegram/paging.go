@@ -0,0 +1,151 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot/models"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/paging"
+)
+
+// CallbackPage is the callback data prefix of page buttons: "pg:<query ID>:<page>".
+const CallbackPage = "pg:"
+
+// pagedQuery is the last search of the agent in a chat topic, browsed with page buttons.
+type pagedQuery struct {
+	id    string
+	query paging.Query
+}
+
+// trackedSearch is a search the agent ran during a turn.
+type trackedSearch struct {
+	query   paging.Query
+	page    int
+	hasMore bool
+}
+
+// PageTracker finds the last search with pages among the tool calls of an AI turn.
+type PageTracker struct {
+	fetcher paging.Fetcher
+	calls   map[string]trackedSearch // searches waiting for their result, by call ID
+	last    *trackedSearch
+}
+
+// NewPageTracker creates a tracker for one AI turn.
+func (s *Service) NewPageTracker() *PageTracker {
+	return &PageTracker{fetcher: s.pages, calls: make(map[string]trackedSearch)}
+}
+
+// Observe records searches and their results from the agent's stream.
+func (t *PageTracker) Observe(event agent.StreamEvent) {
+	if t.fetcher == nil {
+		return
+	}
+	key := event.ToolCallID
+	if key == "" {
+		key = event.ToolName
+	}
+
+	switch event.Kind {
+	case agent.StreamEventToolCall:
+		if q, page, ok := t.fetcher.Query(event.ToolName, event.ToolArgs); ok {
+			t.calls[key] = trackedSearch{query: q, page: page}
+		}
+	case agent.StreamEventToolResult:
+		search, ok := t.calls[key]
+		if !ok {
+			return
+		}
+		delete(t.calls, key)
+		if _, failed := event.ToolResult["error"]; failed {
+			return
+		}
+		search.hasMore, _ = event.ToolResult["has_more"].(bool)
+		t.last = &search
+	}
+}
+
+// PageKeyboard remembers the last search of the turn for the user in the chat topic
+// and returns page buttons for the answer, or nil if the results fit on one page.
+// Buttons of earlier answers stop working: only the last search is browsed.
+func (s *Service) PageKeyboard(telegramUserID int64, chat Chat, t *PageTracker) *models.InlineKeyboardMarkup {
+	if t == nil || t.last == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.pageSeq++
+	id := strconv.FormatUint(s.pageSeq, 36)
+	s.lastPages[chatUser{telegramUserID, chat}] = pagedQuery{id: id, query: t.last.query}
+	s.mu.Unlock()
+
+	if !t.last.hasMore && t.last.page <= 1 {
+		return nil
+	}
+	return pageKeyboard(id, t.last.page, t.last.hasMore)
+}
+
+// HandlePageCallback fetches the page of the remembered search straight from amoCRM, without the AI.
+// alert is shown instead when the page cannot be shown.
+func (s *Service) HandlePageCallback(ctx context.Context, telegramUserID int64, chat Chat, data string) (text string, keyboard *models.InlineKeyboardMarkup, alert string) {
+	id, pageText, _ := strings.Cut(data, ":")
+	page, err := strconv.Atoi(pageText)
+	if err != nil || page < 1 || s.pages == nil {
+		return "", nil, "❓ Неизвестное действие."
+	}
+
+	s.mu.Lock()
+	pq, ok := s.lastPages[chatUser{telegramUserID, chat}]
+	s.mu.Unlock()
+	if !ok || pq.id != id {
+		return "", nil, "Этот список устарел или принадлежит другому участнику — повтори запрос."
+	}
+
+	p, err := s.pages.Fetch(ctx, pq.query, page)
+	if err != nil {
+		log.Printf("❌ Page %d of %s search error: %v", page, pq.query.Tool, err)
+		return "", nil, "❌ Не удалось загрузить страницу, попробуй позже."
+	}
+	if len(p.Items) == 0 && page > 1 {
+		return "", nil, "Больше ничего нет."
+	}
+	return RenderPage(p), pageKeyboard(id, p.Number, p.HasMore), ""
+}
+
+// RenderPage renders a page of search results as an HTML message.
+func RenderPage(p *paging.Page) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📄 <b>%s</b> — страница %d\n", html.EscapeString(p.Title), p.Number)
+	if len(p.Items) == 0 {
+		sb.WriteString("\nНичего не найдено.")
+		return sb.String()
+	}
+	for _, item := range p.Items {
+		fmt.Fprintf(&sb, "\n• %s", html.EscapeString(item))
+	}
+	return sb.String()
+}
+
+// pageKeyboard shows ◀️/▶️ to the previous and next pages.
+func pageKeyboard(id string, page int, hasMore bool) *models.InlineKeyboardMarkup {
+	data := func(page int) string {
+		return fmt.Sprintf("%s%s:%d", CallbackPage, id, page)
+	}
+	var row []models.InlineKeyboardButton
+	if page > 1 {
+		row = append(row, models.InlineKeyboardButton{Text: fmt.Sprintf("◀️ %d", page-1), CallbackData: data(page - 1)})
+	}
+	if hasMore {
+		row = append(row, models.InlineKeyboardButton{Text: fmt.Sprintf("%d ▶️", page+1), CallbackData: data(page + 1)})
+	}
+	if len(row) == 0 {
+		return nil
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{row}}
+}
@@ -0,0 +1,105 @@
+package telegram
+
+import "fmt"
+
+// objectNames — названия объектов CRM: [0] — одна запись (винительный падеж), [1] — список.
+var objectNames = map[string][2]string{
+	"leads":           {"сделку", "сделки"},
+	"contacts":        {"контакт", "контакты"},
+	"companies":       {"компанию", "компании"},
+	"tasks":           {"задачу", "задачи"},
+	"notes":           {"примечание", "примечания"},
+	"calls":           {"звонок", "звонки"},
+	"events":          {"событие", "события"},
+	"files":           {"файл", "файлы"},
+	"links":           {"связь", "связи"},
+	"tags":            {"тег", "теги"},
+	"subscriptions":   {"подписку", "подписки"},
+	"talks":           {"беседу", "беседы"},
+	"customers":       {"покупателя", "покупателей"},
+	"bonus_points":    {"бонусные баллы", "бонусные баллы"},
+	"statuses":        {"статус", "статусы"},
+	"transactions":    {"транзакцию", "транзакции"},
+	"segments":        {"сегмент", "сегменты"},
+	"custom_fields":   {"поле", "поля"},
+	"field_groups":    {"группу полей", "группы полей"},
+	"loss_reasons":    {"причину отказа", "причины отказа"},
+	"sources":         {"источник", "источники"},
+	"users":           {"пользователя", "пользователей"},
+	"roles":           {"роль", "роли"},
+	"webhooks":        {"вебхук", "вебхуки"},
+	"widgets":         {"виджет", "виджеты"},
+	"website_buttons": {"кнопку сайта", "кнопки сайта"},
+	"chat_templates":  {"шаблон", "шаблоны"},
+	"short_links":     {"короткую ссылку", "короткие ссылки"},
+
+	// Объекты по имени tool (когда нет entity_type/layer)
+	"products":        {"товар", "товары"},
+	"catalogs":        {"элемент каталога", "каталоги"},
+	"unsorted":        {"заявку", "неразобранное"},
+	"admin_pipelines": {"воронку", "воронки"},
+	"complex_create":  {"сделку с контактами", "сделки"},
+}
+
+// actionVerbs — глагол для action. plural → objectNames[1],
+// full → фраза уже содержит объект (действия над статусами воронок).
+var actionVerbs = map[string]struct {
+	verb   string
+	plural bool
+	full   bool
+}{
+	"search":        {"🔍 Ищу", true, false},
+	"list":          {"🔍 Ищу", true, false},
+	"summary":       {"📊 Собираю", true, false},
+	"get":           {"📄 Загружаю", false, false},
+	"get_statuses":  {"📄 Загружаю статусы", false, true},
+	"get_status":    {"📄 Загружаю статус", false, true},
+	"create":        {"✏️ Создаю", false, false},
+	"create_status": {"✏️ Создаю статус", false, true},
+	"update":        {"✏️ Обновляю", false, false},
+	"update_status": {"✏️ Обновляю статус", false, true},
+	"sync":          {"🔄 Синхронизирую", false, false},
+	"complete":      {"✅ Завершаю", false, false},
+	"delete":        {"🗑 Удаляю", false, false},
+	"delete_status": {"🗑 Удаляю статус", false, true},
+	"link":          {"🔗 Связываю", false, false},
+	"unlink":        {"🔗 Отвязываю", false, false},
+	"accept":        {"✅ Принимаю", false, false},
+	"decline":       {"❌ Отклоняю", false, false},
+	"upload":        {"📤 Загружаю", false, false},
+}
+
+// ToolProgressLabel returns a human-readable progress line for a tool call,
+// e.g. "🔍 Ищу сделки…" or "✏️ Создаю задачу…".
+func ToolProgressLabel(toolName string, args map[string]any) string {
+	action, _ := args["action"].(string)
+
+	object := toolName
+	if v, ok := args["entity_type"].(string); ok && v != "" {
+		object = v
+	} else if v, ok := args["layer"].(string); ok && v != "" {
+		object = v
+	}
+
+	if toolName == "complex_create" && action == "" {
+		action = "create"
+	}
+
+	verb, ok := actionVerbs[action]
+	if !ok {
+		return fmt.Sprintf("⚙️ Работаю с %s…", toolName)
+	}
+
+	if verb.full {
+		return verb.verb + "…"
+	}
+
+	names, ok := objectNames[object]
+	if !ok {
+		return fmt.Sprintf("%s (%s)…", verb.verb, toolName)
+	}
+	if verb.plural {
+		return fmt.Sprintf("%s %s…", verb.verb, names[1])
+	}
+	return fmt.Sprintf("%s %s…", verb.verb, names[0])
+}
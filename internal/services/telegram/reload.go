@@ -0,0 +1,33 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ReferenceReloader reloads the account reference data: users, pipelines and statuses, custom fields.
+// Summary describes what is cached, as Telegram HTML: counts and the references that failed to load.
+type ReferenceReloader interface {
+	Reload(ctx context.Context) error
+	Summary() string
+}
+
+// HandleReload reloads the reference data right away, e.g. after statuses or fields
+// were changed in amoCRM itself. Only bot admins may do it when access control is on.
+func (s *Service) HandleReload(ctx context.Context, telegramUserID int64) string {
+	if s.access != nil && !s.access.IsAdmin(telegramUserID) {
+		return "⛔ Команда доступна только администраторам бота."
+	}
+	if s.references == nil {
+		return "ℹ️ Справочники amoCRM не кешируются — обновлять нечего."
+	}
+
+	start := time.Now()
+	if err := s.references.Reload(ctx); err != nil {
+		log.Printf("❌ Reference data reload error: %v", err)
+		return "❌ Не удалось обновить справочники amoCRM, работают прежние. Попробуй позже."
+	}
+	return fmt.Sprintf("🔄 Справочники amoCRM обновлены за %.1f с.\n\n%s", time.Since(start).Seconds(), s.references.Summary())
+}
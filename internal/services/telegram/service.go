@@ -3,26 +3,85 @@ package telegram
 import (
 	"context"
 	"fmt"
+	"html"
+	"iter"
+	"strings"
+	"sync"
 
 	"github.com/go-telegram/bot/models"
 	infraCRM "github.com/tihn/amo-ai-tgbot-go/internal/infrastructure/crm"
+	"github.com/tihn/amo-ai-tgbot-go/internal/infrastructure/llm"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/access"
 	"github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
 	"github.com/tihn/amo-ai-tgbot-go/internal/services/auth"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/cards"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/paging"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/speech"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/uploads"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/usermodel"
 )
 
 // Service handles Telegram business logic
 type Service struct {
-	agent     agent.Processor
-	crmClient *infraCRM.Client
-	auth      *auth.Service
+	agent      agent.Processor
+	crmClient  *infraCRM.Client
+	auth       *auth.Service
+	models     *usermodel.Resolver // optional, per-user LLM
+	access     *access.Service     // optional, allowlist and amoCRM bindings
+	cards      *cards.Service      // optional, inline search and card actions
+	pages      paging.Fetcher      // optional, page buttons for search results
+	speech     speech.Transcriber  // optional, voice messages
+	uploads    *uploads.Service    // optional, files sent to the bot
+	references ReferenceReloader   // optional, /reload
+	providers  ProviderStats       // optional, LLM fallback chain state for /status
+
+	mu             sync.Mutex
+	activeSessions map[chatUser]string // selected conversation per user in a chat
+	confirmations  map[string]*pendingConfirmation
+	confirmSeq     uint64
+	turnQueues     map[chatUser][]*Turn // running turn first, then queued ones
+	turns          map[string]*Turn
+	turnSeq        uint64
+	cardInputs     map[chatUser]cardInput // task or note text awaited after a card button
+	lastPages      map[chatUser]pagedQuery
+	pageSeq        uint64
+	focus          map[chatUser]cardRef // the record last opened on a card, for uploads
 }
 
-// NewService creates a new Telegram service
-func NewService(agent agent.Processor, crmClient *infraCRM.Client, authService *auth.Service) *Service {
+// Options are the optional parts of the service: a nil field turns its feature off.
+type Options struct {
+	Models     *usermodel.Resolver // per-user LLM; nil — all AI requests use the shared provider
+	Access     *access.Service     // allowlist and amoCRM bindings; nil — the bot is open to everyone
+	Cards      *cards.Service      // inline search and cards; nil — inline queries return nothing
+	Pages      paging.Fetcher      // page buttons for search results
+	Speech     speech.Transcriber  // voice message recognition
+	Uploads    *uploads.Service    // photos and documents uploaded to amoCRM
+	References ReferenceReloader   // reference data for /reload
+	Providers  ProviderStats       // LLM fallback chain state for /status; nil with a single provider
+}
+
+// NewService creates a new Telegram service.
+func NewService(agent agent.Processor, crmClient *infraCRM.Client, authService *auth.Service, opts Options) *Service {
 	return &Service{
-		agent:     agent,
-		crmClient: crmClient,
-		auth:      authService,
+		agent:      agent,
+		crmClient:  crmClient,
+		auth:       authService,
+		models:     opts.Models,
+		access:     opts.Access,
+		cards:      opts.Cards,
+		pages:      opts.Pages,
+		speech:     opts.Speech,
+		uploads:    opts.Uploads,
+		references: opts.References,
+		providers:  opts.Providers,
+
+		activeSessions: make(map[chatUser]string),
+		confirmations:  make(map[string]*pendingConfirmation),
+		turnQueues:     make(map[chatUser][]*Turn),
+		turns:          make(map[string]*Turn),
+		cardInputs:     make(map[chatUser]cardInput),
+		lastPages:      make(map[chatUser]pagedQuery),
+		focus:          make(map[chatUser]cardRef),
 	}
 }
 
@@ -48,11 +107,22 @@ func (s *Service) HandleStart(telegramUserID int64) (string, *models.InlineKeybo
 	message := `👋 Привет! Я amoCRM AI бот.
 
 📋 Доступные команды:
-• /status — проверить подключение к amoCRM
+• /status — проверить подключение к amoCRM и справочники
 • /account — информация об аккаунте
 • /pipelines — список воронок и статусов
-
-💬 Или просто напиши мне что-нибудь — я отвечу через AI!`
+• /new — начать новый диалог
+• /reset — очистить текущий диалог
+• /history — последние диалоги
+• /cancel — остановить текущий запрос
+• /lead, /contact, /company, /task с ID — карточка с кнопками действий
+• /me — мой Google аккаунт и сотрудник amoCRM
+• /bind — привязаться к сотруднику amoCRM по email Google
+
+💬 Или просто напиши мне что-нибудь — я отвечу через AI! Если ответ про одну сделку, контакт, компанию или задачу, пришлю и её карточку.
+🎙 Можно и голосом: распознаю голосовое, покажу текст и отвечу на него.
+📎 Пришли фото или документ — загружу в amoCRM и прикреплю к записи из подписи (<code>/lead 123</code> или название — спрошу, та ли запись) или к последней открытой карточке.
+🔎 В любом чате набери <code>@имя_бота Альфа</code>, чтобы найти и отправить карточку сделки, контакта или компании.
+👥 В группе отвечаю, когда меня упоминают или отвечают на моё сообщение. У каждого участника и каждой темы форума свой диалог.`
 
 	return message, keyboard
 }
@@ -169,6 +239,7 @@ func (s *Service) ShowAuthDisconnected() (string, *models.InlineKeyboardMarkup)
 
 // HandleAuthCode processes the authorization code (called when user sends text while waiting)
 func (s *Service) HandleAuthCode(ctx context.Context, telegramUserID int64, code string) (string, *models.InlineKeyboardMarkup) {
+	defer s.invalidateUserModel(telegramUserID)
 	if err := s.auth.CompleteAuth(ctx, telegramUserID, code); err != nil {
 		message := fmt.Sprintf("❌ <b>Ошибка авторизации</b>\n\n%v", err)
 		keyboard := &models.InlineKeyboardMarkup{
@@ -191,6 +262,7 @@ func (s *Service) CancelAuth(telegramUserID int64) (string, *models.InlineKeyboa
 // Disconnect removes the user's tokens
 func (s *Service) Disconnect(telegramUserID int64) (string, *models.InlineKeyboardMarkup) {
 	_ = s.auth.Logout(telegramUserID)
+	s.invalidateUserModel(telegramUserID)
 	return s.ShowAuthDisconnected()
 }
 
@@ -215,9 +287,9 @@ func (s *Service) HandleAuth(ctx context.Context, telegramUserID int64, code str
 // HandleMe returns information about the connected account (legacy)
 func (s *Service) HandleMe(telegramUserID int64) string {
 	if !s.auth.IsAuthenticated(telegramUserID) {
-		return "❌ Google аккаунт не подключён.\n\nИспользуй /connect для авторизации."
+		return "❌ Google аккаунт не подключён.\n\nИспользуй /connect для авторизации." + s.amoBindingLine(telegramUserID)
 	}
-	return "✅ Google аккаунт подключён.\n\nДля отключения используй /disconnect"
+	return "✅ Google аккаунт подключён.\n\nДля отключения используй /disconnect" + s.amoBindingLine(telegramUserID)
 }
 
 // HandleDisconnect removes the user's tokens (legacy)
@@ -225,17 +297,53 @@ func (s *Service) HandleDisconnect(telegramUserID int64) string {
 	if err := s.auth.Logout(telegramUserID); err != nil {
 		return fmt.Sprintf("❌ Ошибка отключения:\n%v", err)
 	}
+	s.invalidateUserModel(telegramUserID)
 	return "✅ Google аккаунт отключён."
 }
 
 // === CRM Handlers ===
 
-// HandleHealthcheck checks CRM connectivity
+// ProviderStats reports the state of the LLM fallback chain (llm.Fallback).
+type ProviderStats interface {
+	Stats() []llm.BackendStats
+}
+
+// HandleHealthcheck checks CRM connectivity and reports the cached reference data
+// and the LLM providers
 func (s *Service) HandleHealthcheck(ctx context.Context) string {
+	var details string
+	if s.references != nil {
+		details += "\n\n" + s.references.Summary()
+	}
+	if s.providers != nil {
+		details += "\n\n" + formatProviderStats(s.providers.Stats())
+	}
 	if err := s.crmClient.Healthcheck(ctx); err != nil {
-		return fmt.Sprintf("❌ amoCRM недоступен\n\nОшибка: %v", err)
+		return fmt.Sprintf("❌ amoCRM недоступен\n\nОшибка: %s", html.EscapeString(err.Error())) + details
 	}
-	return "✅ amoCRM доступен!"
+	return "✅ amoCRM доступен!" + details
+}
+
+// formatProviderStats renders the fallback chain for /status, primary provider first.
+func formatProviderStats(stats []llm.BackendStats) string {
+	var sb strings.Builder
+	sb.WriteString("🤖 Провайдеры AI:")
+	for _, st := range stats {
+		state := "✅"
+		if st.Open {
+			state = "⛔ временно пропускается"
+		}
+		fmt.Fprintf(&sb, "\n• %s (%s): %s, ответов %d, ошибок %d",
+			html.EscapeString(st.Name), html.EscapeString(st.Model), state, st.Answered, st.Failed)
+		if st.LastError != "" {
+			lastErr := []rune(st.LastError)
+			if len(lastErr) > 200 {
+				lastErr = append(lastErr[:200], '…')
+			}
+			fmt.Fprintf(&sb, "\n  последняя ошибка: %s", html.EscapeString(string(lastErr)))
+		}
+	}
+	return sb.String()
 }
 
 // HandleAccount returns account information
@@ -280,12 +388,80 @@ func (s *Service) HandlePipelines(ctx context.Context) string {
 }
 
 // ProcessAI processes a message through the AI agent
-func (s *Service) ProcessAI(ctx context.Context, telegramUserID int64, chatID int64, text string) (string, error) {
-	userID := fmt.Sprintf("tg_%d", telegramUserID)
-	sessionID := fmt.Sprintf("tg_%d", chatID)
+func (s *Service) ProcessAI(ctx context.Context, telegramUserID int64, chat Chat, text string) (string, error) {
+	ctx, err := s.withUserModel(ctx, telegramUserID)
+	if err != nil {
+		return "", err
+	}
+	ctx = s.withIdentity(ctx, telegramUserID)
+	userID, sessionID := s.sessionKeys(ctx, telegramUserID, chat)
 	return s.agent.Process(ctx, userID, sessionID, text)
 }
 
+// ProcessAIStream processes a message of a started turn through the AI agent, in the turn's
+// session, reporting progress as it happens.
+// If the agent does not support streaming, yields a single StreamEventDone with the whole answer.
+func (s *Service) ProcessAIStream(ctx context.Context, t *Turn, text string) iter.Seq2[agent.StreamEvent, error] {
+	ctx, err := s.withUserModel(ctx, t.telegramUserID)
+	if err != nil {
+		return func(yield func(agent.StreamEvent, error) bool) {
+			yield(agent.StreamEvent{}, err)
+		}
+	}
+	ctx = s.withIdentity(ctx, t.telegramUserID)
+	userID, sessionID := t.userID, t.sessionID
+	if sp, ok := s.agent.(agent.StreamProcessor); ok {
+		return sp.ProcessStream(ctx, userID, sessionID, text)
+	}
+	return func(yield func(agent.StreamEvent, error) bool) {
+		response, err := s.agent.Process(ctx, userID, sessionID, text)
+		if err != nil {
+			yield(agent.StreamEvent{}, err)
+			return
+		}
+		yield(agent.StreamEvent{Kind: agent.StreamEventDone, Text: response}, nil)
+	}
+}
+
+// withUserModel binds the user's own LLM to the context, if the user has one.
+func (s *Service) withUserModel(ctx context.Context, telegramUserID int64) (context.Context, error) {
+	if s.models == nil {
+		return ctx, nil
+	}
+	m, err := s.models.Resolve(ctx, telegramUserID)
+	if err != nil {
+		return ctx, err
+	}
+	if m != nil {
+		ctx = llm.ContextWithModel(ctx, m)
+	}
+	return ctx, nil
+}
+
+func (s *Service) invalidateUserModel(telegramUserID int64) {
+	if s.models != nil {
+		s.models.Invalidate(telegramUserID)
+	}
+}
+
+// CheckAIAccess returns a message with a connect button if the user may not use AI
+// until they connect a Google account. ok is true when AI is available.
+func (s *Service) CheckAIAccess(telegramUserID int64) (message string, keyboard *models.InlineKeyboardMarkup, ok bool) {
+	if s.models == nil || !s.models.RequireAuth() || s.auth.IsAuthenticated(telegramUserID) {
+		return "", nil, true
+	}
+
+	message = `🔐 <b>Нужен Google аккаунт</b>
+
+AI запросы выполняются от имени твоего Google аккаунта. Подключи его, чтобы продолжить.`
+	keyboard = &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{{Text: "🔗 Подключить Google", CallbackData: "auth_start"}},
+		},
+	}
+	return message, keyboard, false
+}
+
 // IsAuthenticated returns true if the user has a valid Google token
 func (s *Service) IsAuthenticated(telegramUserID int64) bool {
 	return s.auth.IsAuthenticated(telegramUserID)
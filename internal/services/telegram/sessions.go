@@ -0,0 +1,257 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-telegram/bot/models"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
+)
+
+// historyLimit is the number of sessions shown by /history.
+const historyLimit = 10
+
+// Callback data prefixes for session management buttons.
+const (
+	CallbackSessionOpen   = "sess_open:"
+	CallbackSessionDelete = "sess_del:"
+	CallbackSessionList   = "sess_list"
+	CallbackSessionNew    = "sess_new"
+)
+
+// Chat is where a conversation takes place: a chat and, in forum supergroups, a topic.
+type Chat struct {
+	ID       int64
+	ThreadID int // forum topic (message_thread_id), 0 — the chat itself
+}
+
+// chatUser identifies a user within a chat topic.
+type chatUser struct {
+	telegramUserID int64
+	chat           Chat
+}
+
+// sessionKeys returns ADK user and session IDs for a Telegram user in a chat topic.
+// Every user has their own sessions, so in groups conversations of different users do not mix.
+// The active session is the one selected with /new or /history; after a restart
+// it falls back to the most recently used session of the chat.
+func (s *Service) sessionKeys(ctx context.Context, telegramUserID int64, chat Chat) (userID, sessionID string) {
+	userID = fmt.Sprintf("tg_%d", telegramUserID)
+	key := chatUser{telegramUserID, chat}
+
+	s.mu.Lock()
+	sessionID, ok := s.activeSessions[key]
+	s.mu.Unlock()
+	if ok {
+		return userID, sessionID
+	}
+
+	sessionID = baseSessionID(chat)
+	if sessions, err := s.listChatSessions(ctx, telegramUserID, chat); err == nil && len(sessions) > 0 {
+		sessionID = sessions[0].ID
+	}
+	s.setActiveSession(telegramUserID, chat, sessionID)
+	return userID, sessionID
+}
+
+func (s *Service) setActiveSession(telegramUserID int64, chat Chat, sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.activeSessions[chatUser{telegramUserID, chat}] = sessionID
+}
+
+// baseSessionID is the session ID used for a chat topic before any /new.
+// Topics get a ":<thread>" suffix, which cannot be confused with the "_<id>" suffix of /new sessions.
+func baseSessionID(chat Chat) string {
+	if chat.ThreadID != 0 {
+		return fmt.Sprintf("tg_%d:%d", chat.ID, chat.ThreadID)
+	}
+	return fmt.Sprintf("tg_%d", chat.ID)
+}
+
+// isChatSession reports whether the session belongs to the chat topic.
+func isChatSession(sessionID string, chat Chat) bool {
+	base := baseSessionID(chat)
+	return sessionID == base || strings.HasPrefix(sessionID, base+"_")
+}
+
+func (s *Service) sessionManager() (agent.SessionManager, bool) {
+	sm, ok := s.agent.(agent.SessionManager)
+	return sm, ok
+}
+
+// listChatSessions returns sessions of the user in the chat, most recent first.
+func (s *Service) listChatSessions(ctx context.Context, telegramUserID int64, chat Chat) ([]agent.SessionInfo, error) {
+	sm, ok := s.sessionManager()
+	if !ok {
+		return nil, fmt.Errorf("agent does not support session management")
+	}
+
+	all, err := sm.ListSessions(ctx, fmt.Sprintf("tg_%d", telegramUserID), baseSessionID(chat))
+	if err != nil {
+		return nil, err
+	}
+	sessions := all[:0]
+	for _, info := range all {
+		if isChatSession(info.ID, chat) {
+			sessions = append(sessions, info)
+		}
+	}
+	return sessions, nil
+}
+
+// HandleNewSession starts a fresh conversation; the previous one stays in /history.
+func (s *Service) HandleNewSession(ctx context.Context, telegramUserID int64, chat Chat) (string, *models.InlineKeyboardMarkup) {
+	sessionID := baseSessionID(chat) + "_" + strconv.FormatInt(time.Now().UnixMilli(), 36)
+
+	if sm, ok := s.sessionManager(); ok {
+		if err := sm.CreateSession(ctx, fmt.Sprintf("tg_%d", telegramUserID), sessionID); err != nil {
+			return fmt.Sprintf("❌ Не удалось начать новый диалог:\n%v", err), nil
+		}
+	}
+	s.setActiveSession(telegramUserID, chat, sessionID)
+
+	message := "🆕 <b>Новый диалог начат.</b>\n\nПредыдущий сохранён — вернуться к нему можно через /history."
+	keyboard := &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{{Text: "🗂 Диалоги", CallbackData: CallbackSessionList}},
+		},
+	}
+	return message, keyboard
+}
+
+// HandleResetSession deletes the current conversation and starts a fresh one.
+func (s *Service) HandleResetSession(ctx context.Context, telegramUserID int64, chat Chat) (string, *models.InlineKeyboardMarkup) {
+	userID, sessionID := s.sessionKeys(ctx, telegramUserID, chat)
+
+	sm, ok := s.sessionManager()
+	if !ok {
+		return "❌ Управление диалогами недоступно.", nil
+	}
+	if err := sm.DeleteSession(ctx, userID, sessionID); err != nil {
+		return fmt.Sprintf("❌ Не удалось сбросить диалог:\n%v", err), nil
+	}
+
+	// The cleared session ID is reused, so /reset does not leave empty sessions behind
+	s.setActiveSession(telegramUserID, chat, sessionID)
+
+	return "🧹 <b>Диалог сброшен.</b>\n\nИстория текущего диалога удалена, начинаем с чистого листа.", nil
+}
+
+// HandleHistory lists recent conversations of the chat with switch and delete buttons.
+func (s *Service) HandleHistory(ctx context.Context, telegramUserID int64, chat Chat) (string, *models.InlineKeyboardMarkup) {
+	_, activeID := s.sessionKeys(ctx, telegramUserID, chat)
+
+	sessions, err := s.listChatSessions(ctx, telegramUserID, chat)
+	if err != nil {
+		return fmt.Sprintf("❌ Ошибка получения диалогов:\n%v", err), nil
+	}
+
+	newRow := []models.InlineKeyboardButton{{Text: "➕ Новый диалог", CallbackData: CallbackSessionNew}}
+	if len(sessions) == 0 {
+		return "🗂 Сохранённых диалогов пока нет.", &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{newRow},
+		}
+	}
+	if len(sessions) > historyLimit {
+		sessions = sessions[:historyLimit]
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🗂 <b>Последние диалоги</b>\n\n")
+
+	var rows [][]models.InlineKeyboardButton
+	for i, info := range sessions {
+		marker := ""
+		if info.ID == activeID {
+			marker = " ← текущий"
+		}
+		fmt.Fprintf(&sb, "%d. <b>%s</b>\n   <i>%s</i>%s\n",
+			i+1, html.EscapeString(sessionTitle(info)), info.UpdatedAt.Local().Format("02.01.2006 15:04"), marker)
+
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: fmt.Sprintf("💬 %d", i+1), CallbackData: CallbackSessionOpen + info.ID},
+			{Text: fmt.Sprintf("🗑 %d", i+1), CallbackData: CallbackSessionDelete + info.ID},
+		})
+	}
+	rows = append(rows, newRow)
+
+	return sb.String(), &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// SwitchSession makes a stored conversation of the chat active.
+func (s *Service) SwitchSession(ctx context.Context, telegramUserID int64, chat Chat, sessionID string) (string, *models.InlineKeyboardMarkup) {
+	info, err := s.findChatSession(ctx, telegramUserID, chat, sessionID)
+	if err != nil {
+		return fmt.Sprintf("❌ %v", err), nil
+	}
+	s.setActiveSession(telegramUserID, chat, sessionID)
+
+	message := fmt.Sprintf("✅ Продолжаем диалог <b>%s</b>.", html.EscapeString(sessionTitle(info)))
+	keyboard := &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{{Text: "⬅️ К списку диалогов", CallbackData: CallbackSessionList}},
+		},
+	}
+	return message, keyboard
+}
+
+// DeleteSession deletes a stored conversation of the chat and shows the updated list.
+func (s *Service) DeleteSession(ctx context.Context, telegramUserID int64, chat Chat, sessionID string) (string, *models.InlineKeyboardMarkup) {
+	if _, err := s.findChatSession(ctx, telegramUserID, chat, sessionID); err != nil {
+		return fmt.Sprintf("❌ %v", err), nil
+	}
+
+	sm, _ := s.sessionManager()
+	if err := sm.DeleteSession(ctx, fmt.Sprintf("tg_%d", telegramUserID), sessionID); err != nil {
+		return fmt.Sprintf("❌ Не удалось удалить диалог:\n%v", err), nil
+	}
+
+	// Deleted the active session — the next message goes to the most recent remaining one
+	s.mu.Lock()
+	key := chatUser{telegramUserID, chat}
+	if s.activeSessions[key] == sessionID {
+		delete(s.activeSessions, key)
+	}
+	s.mu.Unlock()
+
+	message, keyboard := s.HandleHistory(ctx, telegramUserID, chat)
+	return "🗑 Диалог удалён.\n\n" + message, keyboard
+}
+
+// findChatSession checks that the session exists and belongs to the chat.
+func (s *Service) findChatSession(ctx context.Context, telegramUserID int64, chat Chat, sessionID string) (agent.SessionInfo, error) {
+	if !isChatSession(sessionID, chat) {
+		return agent.SessionInfo{}, fmt.Errorf("диалог не найден")
+	}
+	sessions, err := s.listChatSessions(ctx, telegramUserID, chat)
+	if err != nil {
+		return agent.SessionInfo{}, fmt.Errorf("ошибка получения диалогов: %w", err)
+	}
+	for _, info := range sessions {
+		if info.ID == sessionID {
+			return info, nil
+		}
+	}
+	return agent.SessionInfo{}, fmt.Errorf("диалог не найден")
+}
+
+// sessionTitle returns a one-line preview of the first message.
+func sessionTitle(info agent.SessionInfo) string {
+	const maxRunes = 60
+
+	title := strings.Join(strings.Fields(info.Title), " ")
+	if title == "" {
+		return "Пустой диалог"
+	}
+	if utf8.RuneCountInString(title) > maxRunes {
+		title = string([]rune(title)[:maxRunes]) + "…"
+	}
+	return title
+}
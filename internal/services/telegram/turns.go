@@ -0,0 +1,194 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
+)
+
+// ErrTurnCancelled is the cause of a turn's context when the user stops it with /cancel or the Stop button.
+var ErrTurnCancelled = errors.New("cancelled by user")
+
+// CallbackTurnStop is the callback data prefix of the Stop button, followed by the turn ID.
+const CallbackTurnStop = "turn_stop:"
+
+// recordCancellationTimeout bounds writing the cancellation note to the session.
+const recordCancellationTimeout = 10 * time.Second
+
+// Turn is one AI request of a user in a chat topic.
+// Turns of the same conversation run one at a time, in arrival order, so that
+// overlapping runs do not interleave events in the session or repeat CRM writes.
+type Turn struct {
+	id             string
+	telegramUserID int64
+	key            chatUser
+
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	ready  chan struct{} // closed when the turn is first in the queue
+	// started is set once the turn begins running; only started turns leave traces in the session
+	started bool
+	// userID and sessionID are the agent session the turn runs in, fixed when it starts,
+	// so that /new or /history during the run do not move its traces to another session
+	userID, sessionID string
+}
+
+// Context is cancelled with ErrTurnCancelled when the user stops the turn.
+func (t *Turn) Context() context.Context {
+	return t.ctx
+}
+
+// StopKeyboard returns the inline keyboard with the Stop button of the turn.
+func (t *Turn) StopKeyboard() *models.InlineKeyboardMarkup {
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{{Text: "⏹ Стоп", CallbackData: CallbackTurnStop + t.id}},
+		},
+	}
+}
+
+// EnqueueTurn queues an AI request of the user in the chat topic.
+// position is the number of turns ahead of it, 0 means it may run right away.
+// The caller must call FinishTurn when done, whether the turn ran or not.
+func (s *Service) EnqueueTurn(ctx context.Context, telegramUserID int64, chat Chat) (t *Turn, position int) {
+	turnCtx, cancel := context.WithCancelCause(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.turnSeq++
+	t = &Turn{
+		id:             strconv.FormatUint(s.turnSeq, 36),
+		telegramUserID: telegramUserID,
+		key:            chatUser{telegramUserID, chat},
+		ctx:            turnCtx,
+		cancel:         cancel,
+		ready:          make(chan struct{}),
+	}
+	queue := s.turnQueues[t.key]
+	if len(queue) == 0 {
+		close(t.ready)
+	}
+	s.turnQueues[t.key] = append(queue, t)
+	s.turns[t.id] = t
+	return t, len(queue)
+}
+
+// WaitTurn blocks until the turn may run. It returns the cancellation cause
+// (ErrTurnCancelled or the parent context's error) if the turn was stopped while waiting.
+func (s *Service) WaitTurn(t *Turn) error {
+	select {
+	case <-t.ready:
+	case <-t.ctx.Done():
+	}
+
+	if t.ctx.Err() != nil {
+		return context.Cause(t.ctx)
+	}
+	// Resolved before the turn is marked started: FinishTurn relies on the keys of a started turn
+	userID, sessionID := s.sessionKeys(t.ctx, t.telegramUserID, t.key.chat)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Both may be ready at once — a cancelled turn never starts
+	if t.ctx.Err() != nil {
+		return context.Cause(t.ctx)
+	}
+	t.userID, t.sessionID = userID, sessionID
+	t.started = true
+	return nil
+}
+
+// FinishTurn removes the turn from the queue and lets the next one run.
+// If the user stopped the running turn, the cancellation is recorded in the session
+// before the next turn starts.
+func (s *Service) FinishTurn(t *Turn) {
+	s.mu.Lock()
+	started := t.started
+	s.mu.Unlock()
+
+	if started && errors.Is(context.Cause(t.ctx), ErrTurnCancelled) {
+		s.recordCancellation(t)
+	}
+	t.cancel(nil)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.turns, t.id)
+	queue := s.turnQueues[t.key]
+	i := slices.Index(queue, t)
+	if i < 0 {
+		return
+	}
+	queue = slices.Delete(queue, i, i+1)
+	if len(queue) == 0 {
+		delete(s.turnQueues, t.key)
+		return
+	}
+	s.turnQueues[t.key] = queue
+	if i == 0 {
+		close(queue[0].ready)
+	}
+}
+
+// CancelTurns stops the running and queued turns of the user in the chat topic (/cancel).
+// Returns the number of stopped turns.
+func (s *Service) CancelTurns(telegramUserID int64, chat Chat) int {
+	s.mu.Lock()
+	queue := slices.Clone(s.turnQueues[chatUser{telegramUserID, chat}])
+	s.mu.Unlock()
+
+	for _, t := range queue {
+		t.cancel(ErrTurnCancelled)
+	}
+	return len(queue)
+}
+
+// StopTurn stops one turn by its Stop button.
+// Returns an alert to show when the turn cannot be stopped by this user.
+func (s *Service) StopTurn(telegramUserID int64, id string) (alert string) {
+	s.mu.Lock()
+	t, ok := s.turns[id]
+	s.mu.Unlock()
+
+	switch {
+	case !ok:
+		return "Запрос уже завершён."
+	case t.telegramUserID != telegramUserID:
+		return "⛔ Остановить запрос может только его автор."
+	}
+	t.cancel(ErrTurnCancelled)
+	return ""
+}
+
+// HandleCancel stops the user's requests in the chat topic and text input for a card: /cancel.
+func (s *Service) HandleCancel(telegramUserID int64, chat Chat) string {
+	inputCancelled := s.cancelCardInput(telegramUserID, chat)
+	switch {
+	case s.CancelTurns(telegramUserID, chat) > 0:
+		return "⏹ Останавливаю запросы…"
+	case inputCancelled:
+		return "✖️ Ввод отменён."
+	}
+	return "ℹ️ Сейчас нечего останавливать."
+}
+
+func (s *Service) recordCancellation(t *Turn) {
+	recorder, ok := s.agent.(agent.CancellationRecorder)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(t.ctx), recordCancellationTimeout)
+	defer cancel()
+
+	if err := recorder.RecordCancellation(ctx, t.userID, t.sessionID); err != nil {
+		log.Printf("⚠️ Failed to record cancellation in session %s: %v", t.sessionID, err)
+	}
+}
@@ -0,0 +1,51 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+)
+
+// recordingAgent remembers the sessions cancellations were recorded in.
+type recordingAgent struct {
+	cancelled []string
+}
+
+func (a *recordingAgent) Process(context.Context, string, string, string) (string, error) {
+	return "", nil
+}
+
+func (a *recordingAgent) RecordCancellation(_ context.Context, _, sessionID string) error {
+	a.cancelled = append(a.cancelled, sessionID)
+	return nil
+}
+
+func TestCancellationStaysInTurnSession(t *testing.T) {
+	ag := &recordingAgent{}
+	s := NewService(ag, nil, nil, Options{})
+	chat := Chat{ID: 100}
+	s.setActiveSession(1, chat, "tg_100")
+
+	turn, _ := s.EnqueueTurn(context.Background(), 1, chat)
+	if err := s.WaitTurn(turn); err != nil {
+		t.Fatal(err)
+	}
+	// /new while the turn is running
+	s.setActiveSession(1, chat, "tg_100:new")
+	s.CancelTurns(1, chat)
+	s.FinishTurn(turn)
+
+	if len(ag.cancelled) != 1 || ag.cancelled[0] != "tg_100" {
+		t.Errorf("cancellation recorded in %v, want [tg_100]", ag.cancelled)
+	}
+
+	// A turn cancelled while queued never ran and leaves nothing
+	queued, _ := s.EnqueueTurn(context.Background(), 1, chat)
+	s.CancelTurns(1, chat)
+	if err := s.WaitTurn(queued); err == nil {
+		t.Fatal("cancelled turn started")
+	}
+	s.FinishTurn(queued)
+	if len(ag.cancelled) != 1 {
+		t.Errorf("queued turn recorded a cancellation: %v", ag.cancelled)
+	}
+}
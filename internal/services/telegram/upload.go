@@ -0,0 +1,243 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/cards"
+)
+
+// maxAmbiguousCards is how many candidates are listed when a caption matches several records.
+const maxAmbiguousCards = 5
+
+// Upload is a photo or document sent to the bot.
+type Upload struct {
+	FileName string // empty for photos
+	MIMEType string
+	Size     int64  // bytes, 0 if unknown
+	Caption  string // the caption addressed to the bot
+	// Open downloads the file.
+	Open func(ctx context.Context) (io.ReadCloser, error)
+}
+
+// UploadResult is the outcome of an upload.
+type UploadResult struct {
+	Reply  string // shown to the user
+	Prompt string // tells the agent about the file, empty when nothing was uploaded
+}
+
+// setFocus makes the record the focus of the user in the chat topic; tasks are ignored.
+func (s *Service) setFocus(telegramUserID int64, chat Chat, ref cardRef) {
+	if _, ok := recordKinds[ref.kind]; !ok {
+		return
+	}
+	s.mu.Lock()
+	s.focus[chatUser{telegramUserID, chat}] = ref
+	s.mu.Unlock()
+}
+
+// HandleUpload uploads the file to amoCRM Drive and attaches it to the record named in the caption
+// ("/lead 123" or a name) or, when the caption names none, to the record last opened on a card.
+// A record found by name is attached only after the user confirms it with the agent.Confirmer of ctx.
+// The record is resolved first: when it is unclear or not allowed, nothing is uploaded.
+func (s *Service) HandleUpload(ctx context.Context, telegramUserID int64, chat Chat, upload Upload) UploadResult {
+	switch {
+	case s.uploads == nil:
+		return UploadResult{Reply: "ℹ️ Загрузка файлов в amoCRM недоступна."}
+	case !s.mayChange(telegramUserID):
+		return UploadResult{Reply: deniedAlert}
+	case upload.Size > maxDownloadSize:
+		return UploadResult{Reply: fmt.Sprintf("📎 Файл больше %d МБ — Telegram не даёт ботам скачивать такие.", maxDownloadSize>>20)}
+	}
+
+	target, caption, found, note := s.uploadTarget(ctx, telegramUserID, chat, upload.Caption)
+	if note != "" {
+		return UploadResult{Reply: note}
+	}
+	if target != nil && !s.cardRights(telegramUserID, target.ResponsibleID).add {
+		return UploadResult{Reply: fmt.Sprintf("🚫 Прикреплять файлы к «%s» можно только ответственному.", html.EscapeString(target.Name))}
+	}
+	if found {
+		if reply := s.confirmUploadTarget(ctx, upload.FileName, target); reply != "" {
+			return UploadResult{Reply: reply}
+		}
+	}
+
+	file, err := upload.Open(ctx)
+	if err != nil {
+		log.Printf("❌ Upload download error: %v", err)
+		return UploadResult{Reply: "❌ Не удалось скачать файл, попробуй ещё раз."}
+	}
+	defer file.Close()
+
+	uuid, name, err := s.uploads.Upload(ctx, file, upload.FileName, upload.MIMEType)
+	if err != nil {
+		log.Printf("❌ Upload error: %v", err)
+		return UploadResult{Reply: "❌ Не удалось загрузить файл в amoCRM, попробуй позже."}
+	}
+
+	if target != nil {
+		if err := s.uploads.Attach(ctx, target.Type, target.ID, uuid); err != nil {
+			log.Printf("❌ Upload attach error: %v", err)
+			note = fmt.Sprintf("❌ Не удалось прикрепить его к «%s».", html.EscapeString(target.Name))
+			target = nil
+		} else {
+			s.setFocus(telegramUserID, chat, cardRef{kind: kindOf(target.Type), id: target.ID})
+		}
+	}
+
+	var reply, prompt strings.Builder
+	fmt.Fprintf(&prompt, "[Пользователь прислал файл «%s», он загружен в amoCRM Drive, UUID файла: %s.", name, uuid)
+	if target != nil {
+		t := cardTitles[target.Type]
+		fmt.Fprintf(&reply, "📎 <b>%s</b> загружен в amoCRM и прикреплён: %s %s «%s».",
+			html.EscapeString(name), t.icon, t.title, html.EscapeString(target.Name))
+		fmt.Fprintf(&prompt, " Файл прикреплён к %s #%d «%s».]", target.Type, target.ID, target.Name)
+	} else {
+		fmt.Fprintf(&reply, "📎 <b>%s</b> загружен в amoCRM, но ни к чему не прикреплён.", html.EscapeString(name))
+		if note == "" {
+			note = "Укажи запись в подписи к файлу: <code>/lead 123</code> или название — или попроси меня прикрепить его."
+		}
+		prompt.WriteString(" Файл ни к чему не прикреплён: прикрепить его можно инструментом activities (layer files, action link).]")
+	}
+	if note != "" {
+		reply.WriteString("\n\n" + note)
+	}
+	if caption != "" {
+		prompt.WriteString("\n\n" + caption)
+	}
+	return UploadResult{Reply: reply.String(), Prompt: prompt.String()}
+}
+
+// uploadTarget finds the record for an upload: by a card command in the caption,
+// by a name search, then the focus. caption is returned without the command;
+// found reports a record found by name, which the user has to confirm;
+// note explains why the file should not be uploaded when the caption was not clear.
+func (s *Service) uploadTarget(ctx context.Context, telegramUserID int64, chat Chat, text string) (target *cards.Card, caption string, found bool, note string) {
+	caption = strings.TrimSpace(text)
+	if s.cards == nil {
+		return nil, caption, false, ""
+	}
+
+	// "/lead 123 подписанный договор"
+	if command, rest, _ := strings.Cut(caption, " "); IsCardCommand(command) && cardCommands[command] != taskKind {
+		idText, rest, _ := strings.Cut(strings.TrimSpace(rest), " ")
+		id, err := strconv.Atoi(idText)
+		if err != nil || id <= 0 {
+			return nil, caption, false, fmt.Sprintf("Укажи ID: <code>%s 12345</code>", command)
+		}
+		card, err := s.cards.Card(ctx, recordKinds[cardCommands[command]], id)
+		if err != nil {
+			return nil, strings.TrimSpace(rest), false, cardError(err)
+		}
+		return card, strings.TrimSpace(rest), false, ""
+	}
+
+	if caption != "" {
+		// Without the search the caption may still name another record: don't fall back to the focus
+		candidates, err := s.cards.Search(ctx, caption)
+		if err != nil {
+			return nil, caption, false, cardError(err)
+		}
+		switch card, ambiguous := pickCard(candidates, caption); {
+		case card != nil:
+			return card, caption, true, ""
+		case ambiguous:
+			return nil, caption, false, ambiguousNote(candidates)
+		}
+	}
+
+	s.mu.Lock()
+	ref, ok := s.focus[chatUser{telegramUserID, chat}]
+	s.mu.Unlock()
+	if !ok {
+		return nil, caption, false, ""
+	}
+	card, err := s.cards.Card(ctx, recordKinds[ref.kind], ref.id)
+	if err != nil {
+		return nil, caption, false, cardError(err)
+	}
+	return card, caption, false, ""
+}
+
+// confirmUploadTarget asks the user whether the file goes to the record found by name.
+// Returns the reply when the file should not be uploaded: declined, not answered or nobody to ask.
+func (s *Service) confirmUploadTarget(ctx context.Context, fileName string, target *cards.Card) string {
+	t := cardTitles[target.Type]
+	retry := fmt.Sprintf("Файл не загружен. Пришли его ещё раз с командой в подписи: <code>%s %d</code>.", commandOf(target.Type), target.ID)
+
+	confirmer, ok := agent.ConfirmerFromContext(ctx)
+	if !ok {
+		return retry
+	}
+	if fileName == "" {
+		fileName = "фото"
+	}
+	approved, err := confirmer.Confirm(ctx, agent.Confirmation{
+		Tool:       "upload",
+		Action:     "attach",
+		Summary:    fmt.Sprintf("Загрузить «%s» в amoCRM и прикрепить: %s %s «%s»?", fileName, t.icon, t.title, target.Name),
+		Reversible: true,
+	})
+	if err != nil && !errors.Is(err, agent.ErrConfirmationTimeout) {
+		log.Printf("⚠️ Upload confirmation error: %v", err)
+	}
+	if !approved {
+		return retry
+	}
+	return ""
+}
+
+// pickCard returns the only record found or the only one named exactly as the caption;
+// ambiguous reports several candidates.
+func pickCard(found []cards.Card, caption string) (card *cards.Card, ambiguous bool) {
+	switch len(found) {
+	case 0:
+		return nil, false
+	case 1:
+		return &found[0], false
+	}
+	var exact []int
+	for i, c := range found {
+		if strings.EqualFold(strings.TrimSpace(c.Name), caption) {
+			exact = append(exact, i)
+		}
+	}
+	if len(exact) == 1 {
+		return &found[exact[0]], false
+	}
+	return nil, true
+}
+
+func ambiguousNote(found []cards.Card) string {
+	var sb strings.Builder
+	sb.WriteString("🤔 Подпись подходит к нескольким записям:")
+	for i, c := range found {
+		if i == maxAmbiguousCards {
+			fmt.Fprintf(&sb, "\n…и ещё %d", len(found)-i)
+			break
+		}
+		t := cardTitles[c.Type]
+		fmt.Fprintf(&sb, "\n%s %s — <code>%s %d</code>", t.icon, html.EscapeString(c.Name), commandOf(c.Type), c.ID)
+	}
+	sb.WriteString("\n\nФайл не загружен. Пришли его ещё раз с командой в подписи.")
+	return sb.String()
+}
+
+// commandOf returns the card command of a record type: "/lead".
+func commandOf(entityType cards.EntityType) string {
+	kind := kindOf(entityType)
+	for command, k := range cardCommands {
+		if k == kind {
+			return command
+		}
+	}
+	return ""
+}
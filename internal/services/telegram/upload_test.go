@@ -0,0 +1,128 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/agent"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/cards"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/uploads"
+)
+
+// fakeDrive counts uploads and remembers attachments.
+type fakeDrive struct {
+	uploads  int
+	attached []int
+}
+
+func (d *fakeDrive) Upload(context.Context, string, string) (string, error) {
+	d.uploads++
+	return "uuid", nil
+}
+
+func (d *fakeDrive) Attach(_ context.Context, _ cards.EntityType, id int, _ string) error {
+	d.attached = append(d.attached, id)
+	return nil
+}
+
+// leadCards finds leads by name and reads them by ID.
+type leadCards struct {
+	cards.Editor
+	leads   []cards.Card
+	failing bool
+}
+
+func (c *leadCards) Search(_ context.Context, entityType cards.EntityType, query string, _ int) ([]cards.Card, error) {
+	if c.failing {
+		return nil, errors.New("unavailable")
+	}
+	var found []cards.Card
+	for _, lead := range c.leads {
+		if entityType == cards.Leads && strings.Contains(lead.Name, query) {
+			found = append(found, lead)
+		}
+	}
+	return found, nil
+}
+
+func (c *leadCards) Card(_ context.Context, _ cards.EntityType, id int) (*cards.Card, error) {
+	for _, lead := range c.leads {
+		if lead.ID == id {
+			return &lead, nil
+		}
+	}
+	return nil, cards.ErrNotFound
+}
+
+// answeringConfirmer answers every confirmation the same way.
+type answeringConfirmer struct {
+	approve bool
+	asked   int
+}
+
+func (c *answeringConfirmer) Confirm(context.Context, agent.Confirmation) (bool, error) {
+	c.asked++
+	return c.approve, nil
+}
+
+func TestHandleUploadTarget(t *testing.T) {
+	tests := []struct {
+		name      string
+		caption   string
+		failing   bool
+		confirmer *answeringConfirmer
+		asked     int
+		attached  int // 0 — nothing uploaded
+		reply     string
+	}{
+		{name: "command", caption: "/lead 1 договор", attached: 1, reply: "прикреплён"},
+		{name: "name confirmed", caption: "Альфа", confirmer: &answeringConfirmer{approve: true}, asked: 1, attached: 1, reply: "прикреплён"},
+		{name: "name declined", caption: "Альфа", confirmer: &answeringConfirmer{}, asked: 1, reply: "/lead 1"},
+		{name: "name without confirmer", caption: "Альфа", reply: "/lead 1"},
+		{name: "ambiguous name", caption: "Бета", confirmer: &answeringConfirmer{approve: true}, reply: "нескольким записям"},
+		{name: "search failed", caption: "Альфа", failing: true, confirmer: &answeringConfirmer{approve: true}, reply: "недоступен"},
+		{name: "missing record", caption: "/lead 9", reply: "не найдена"},
+		{name: "bad ID", caption: "/lead x", reply: "Укажи ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drive := &fakeDrive{}
+			source := &leadCards{leads: []cards.Card{
+				{Type: cards.Leads, ID: 1, Name: "Альфа"},
+				{Type: cards.Leads, ID: 2, Name: "Бета 1"},
+				{Type: cards.Leads, ID: 3, Name: "Бета 2"},
+			}, failing: tt.failing}
+			s := &Service{
+				cards:   cards.New(source, source),
+				uploads: uploads.New(drive),
+				focus:   make(map[chatUser]cardRef),
+			}
+			// The chat has a record in focus: a failed search must not fall back to it
+			s.setFocus(1, Chat{ID: 1}, cardRef{kind: "l", id: 1})
+			ctx := context.Background()
+			if tt.confirmer != nil {
+				ctx = agent.ContextWithConfirmer(ctx, tt.confirmer)
+			}
+
+			result := s.HandleUpload(ctx, 1, Chat{ID: 1}, Upload{FileName: "a.pdf", Caption: tt.caption, Open: openString("pdf")})
+
+			if !strings.Contains(result.Reply, tt.reply) {
+				t.Errorf("reply = %q, want it to contain %q", result.Reply, tt.reply)
+			}
+			if tt.confirmer != nil && tt.confirmer.asked != tt.asked {
+				t.Errorf("asked = %d, want %d", tt.confirmer.asked, tt.asked)
+			}
+			if tt.attached == 0 {
+				if drive.uploads != 0 || result.Prompt != "" {
+					t.Errorf("uploaded %d files without a clear record", drive.uploads)
+				}
+				return
+			}
+			if len(drive.attached) != 1 || drive.attached[0] != tt.attached {
+				t.Errorf("attached to %v, want %d", drive.attached, tt.attached)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+const (
+	// maxVoiceDuration bounds voice messages: long recordings take a while to transcribe
+	// and rarely make a good request.
+	maxVoiceDuration = 10 * time.Minute
+	// maxDownloadSize is the largest file bots can download from Telegram.
+	maxDownloadSize = 20 << 20
+)
+
+// Voice is a voice note or audio file to transcribe.
+type Voice struct {
+	FileName string // hints the audio format to the transcriber, e.g. "voice.ogg"
+	Duration time.Duration
+	Size     int64 // bytes, 0 if unknown
+	// Open downloads the audio; it is called only when the message will be transcribed.
+	Open func(ctx context.Context) (io.ReadCloser, error)
+}
+
+// TranscribeVoice converts a voice message to text for the agent.
+// When there is no text, failure explains why.
+func (s *Service) TranscribeVoice(ctx context.Context, voice Voice) (transcript, failure string) {
+	if s.speech == nil {
+		return "", "🎙 Распознавание голосовых не настроено — напиши, пожалуйста, текстом."
+	}
+	if voice.Duration > maxVoiceDuration {
+		return "", fmt.Sprintf("🎙 Голосовое длиннее %d минут — раздели его на части.", int(maxVoiceDuration.Minutes()))
+	}
+	if voice.Size > maxDownloadSize {
+		return "", fmt.Sprintf("🎙 Файл больше %d МБ — Telegram не даёт ботам скачивать такие.", maxDownloadSize>>20)
+	}
+
+	audio, err := voice.Open(ctx)
+	if err != nil {
+		log.Printf("❌ Voice download error: %v", err)
+		return "", "❌ Не удалось скачать голосовое, попробуй ещё раз."
+	}
+	defer audio.Close()
+
+	transcript, err = s.speech.Transcribe(ctx, audio, voice.FileName)
+	if err != nil {
+		log.Printf("❌ Voice transcription error: %v", err)
+		return "", "❌ Не удалось распознать голосовое — попробуй ещё раз или напиши текстом."
+	}
+	if transcript == "" {
+		return "", "🤷 Не расслышал ни слова — попробуй ещё раз."
+	}
+	return transcript, ""
+}
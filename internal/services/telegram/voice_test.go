@@ -0,0 +1,125 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTranscriber returns a fixed transcript and remembers what it was given.
+type fakeTranscriber struct {
+	text     string
+	err      error
+	audio    string
+	filename string
+	calls    int
+}
+
+func (f *fakeTranscriber) Transcribe(_ context.Context, audio io.Reader, filename string) (string, error) {
+	f.calls++
+	data, err := io.ReadAll(audio)
+	if err != nil {
+		return "", err
+	}
+	f.audio, f.filename = string(data), filename
+	return f.text, f.err
+}
+
+func openString(s string) func(context.Context) (io.ReadCloser, error) {
+	return func(context.Context) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(s)), nil
+	}
+}
+
+func TestTranscribeVoice(t *testing.T) {
+	tests := []struct {
+		name        string
+		transcriber *fakeTranscriber
+		voice       Voice
+		transcript  string
+		failure     string // substring of the failure message
+		transcribed bool
+	}{
+		{
+			name:        "transcript",
+			transcriber: &fakeTranscriber{text: "найди сделку Альфа"},
+			voice:       Voice{FileName: "voice.ogg", Duration: 3 * time.Second, Open: openString("opus")},
+			transcript:  "найди сделку Альфа",
+			transcribed: true,
+		},
+		{
+			name:        "too long",
+			transcriber: &fakeTranscriber{text: "x"},
+			voice:       Voice{FileName: "voice.ogg", Duration: 11 * time.Minute, Open: openString("opus")},
+			failure:     "длиннее",
+		},
+		{
+			name:        "too large",
+			transcriber: &fakeTranscriber{text: "x"},
+			voice:       Voice{FileName: "talk.mp3", Size: 21 << 20, Open: openString("mp3")},
+			failure:     "больше 20 МБ",
+		},
+		{
+			name:        "download error",
+			transcriber: &fakeTranscriber{text: "x"},
+			voice: Voice{FileName: "voice.ogg", Open: func(context.Context) (io.ReadCloser, error) {
+				return nil, errors.New("network")
+			}},
+			failure: "скачать",
+		},
+		{
+			name:        "transcription error",
+			transcriber: &fakeTranscriber{err: errors.New("503")},
+			voice:       Voice{FileName: "voice.ogg", Open: openString("opus")},
+			failure:     "распознать",
+			transcribed: true,
+		},
+		{
+			name:        "silence",
+			transcriber: &fakeTranscriber{},
+			voice:       Voice{FileName: "voice.ogg", Open: openString("opus")},
+			failure:     "Не расслышал",
+			transcribed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewService(nil, nil, nil, Options{Speech: tt.transcriber})
+			transcript, failure := s.TranscribeVoice(context.Background(), tt.voice)
+
+			if transcript != tt.transcript {
+				t.Errorf("transcript = %q, want %q", transcript, tt.transcript)
+			}
+			if tt.failure == "" && failure != "" || !strings.Contains(failure, tt.failure) {
+				t.Errorf("failure = %q, want containing %q", failure, tt.failure)
+			}
+			if got := tt.transcriber.calls > 0; got != tt.transcribed {
+				t.Errorf("transcribed = %v, want %v", got, tt.transcribed)
+			}
+			if tt.transcribed && (tt.transcriber.audio != "opus" || tt.transcriber.filename != tt.voice.FileName) {
+				t.Errorf("transcriber got %q as %q", tt.transcriber.audio, tt.transcriber.filename)
+			}
+		})
+	}
+}
+
+func TestTranscribeVoiceDisabled(t *testing.T) {
+	s := NewService(nil, nil, nil, Options{})
+	opened := false
+	voice := Voice{FileName: "voice.ogg", Open: func(context.Context) (io.ReadCloser, error) {
+		opened = true
+		return io.NopCloser(strings.NewReader("")), nil
+	}}
+
+	transcript, failure := s.TranscribeVoice(context.Background(), voice)
+	if transcript != "" || !strings.Contains(failure, "не настроено") {
+		t.Errorf("TranscribeVoice() = %q, %q; want the disabled message", transcript, failure)
+	}
+	if opened {
+		t.Error("audio downloaded while voice input is disabled")
+	}
+}
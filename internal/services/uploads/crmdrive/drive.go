@@ -0,0 +1,47 @@
+// Package crmdrive provides uploads.Drive backed by the amoCRM services.
+package crmdrive
+
+import (
+	"context"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"github.com/alextixru/amocrm-sdk-go/core/services"
+
+	gkitmodels "github.com/tihn/amo-ai-tgbot-go/internal/models/tools"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/cards"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/crm/activities"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/crm/files"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/uploads"
+)
+
+type drive struct {
+	files      files.Service
+	activities activities.Service
+}
+
+// New creates an uploads.Drive.
+func New(filesSvc files.Service, activitiesSvc activities.Service) uploads.Drive {
+	return &drive{files: filesSvc, activities: activitiesSvc}
+}
+
+// Upload implements uploads.Drive.
+func (d *drive) Upload(ctx context.Context, path, name string) (string, error) {
+	file, err := d.files.UploadFile(ctx, services.FileUploadParams{
+		LocalPath:   path,
+		FileName:    name,
+		WithPreview: strings.HasPrefix(mime.TypeByExtension(filepath.Ext(name)), "image/"),
+	})
+	if err != nil {
+		return "", err
+	}
+	return file.UUID, nil
+}
+
+// Attach implements uploads.Drive.
+func (d *drive) Attach(ctx context.Context, entityType cards.EntityType, id int, uuid string) error {
+	parent := gkitmodels.ParentEntity{Type: string(entityType), ID: id}
+	_, err := d.activities.LinkFiles(ctx, parent, []string{uuid})
+	return err
+}
@@ -0,0 +1,114 @@
+// Package uploads stores files sent to the bot in amoCRM Drive
+// and attaches them to leads, contacts and companies.
+package uploads
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/cards"
+)
+
+// Drive is amoCRM Drive.
+type Drive interface {
+	// Upload stores the local file under name and returns its UUID.
+	Upload(ctx context.Context, path, name string) (uuid string, err error)
+	// Attach links a Drive file to a record.
+	Attach(ctx context.Context, entityType cards.EntityType, id int, uuid string) error
+}
+
+// Service uploads files and attaches them to records.
+type Service struct {
+	drive Drive
+}
+
+// New creates a Service over amoCRM Drive.
+func New(drive Drive) *Service {
+	return &Service{drive: drive}
+}
+
+// Upload stores the file in amoCRM Drive and returns its UUID and the name it was stored under.
+// Drive takes the MIME type from the file extension, so the name gets one matching mimeType.
+func (s *Service) Upload(ctx context.Context, r io.Reader, name, mimeType string) (uuid, storedName string, err error) {
+	storedName = FileName(name, mimeType)
+
+	// The SDK uploads from disk; the temp file keeps the extension for MIME detection
+	tmp, err := os.CreateTemp("", "tg-upload-*"+filepath.Ext(storedName))
+	if err != nil {
+		return "", "", fmt.Errorf("uploads: create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("uploads: save %q: %w", storedName, err)
+	}
+
+	uuid, err = s.drive.Upload(ctx, tmp.Name(), storedName)
+	if err != nil {
+		return "", "", fmt.Errorf("uploads: upload %q: %w", storedName, err)
+	}
+	return uuid, storedName, nil
+}
+
+// Attach links an uploaded file to a record.
+func (s *Service) Attach(ctx context.Context, entityType cards.EntityType, id int, uuid string) error {
+	if err := s.drive.Attach(ctx, entityType, id, uuid); err != nil {
+		return fmt.Errorf("uploads: attach %s to %s %d: %w", uuid, entityType, id, err)
+	}
+	return nil
+}
+
+// FileName returns a safe file name with an extension matching the MIME type:
+// Telegram documents may come without an extension, photos come without a name at all.
+func FileName(name, mimeType string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, `\`, "/")))
+	if name == "." || name == "/" {
+		name = ""
+	}
+	if name == "" {
+		name = "file"
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(mimeType)
+	if mediaType == "" || filepath.Ext(name) != "" && mime.TypeByExtension(filepath.Ext(name)) != "" {
+		return name
+	}
+	if ext := extensionOf(mediaType); ext != "" {
+		// "Договор v1.2" keeps its dots, the extension is appended
+		return name + ext
+	}
+	return name
+}
+
+// commonExtensions are preferred over the first of mime.ExtensionsByType,
+// which is alphabetical (".jpe" for JPEG).
+var commonExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+	"audio/ogg":       ".ogg",
+	"audio/mpeg":      ".mp3",
+	"video/mp4":       ".mp4",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": ".docx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":       ".xlsx",
+}
+
+func extensionOf(mediaType string) string {
+	if ext, ok := commonExtensions[mediaType]; ok {
+		return ext
+	}
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+package uploads
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/cards"
+)
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		name     string
+		mimeType string
+		expected string
+	}{
+		{name: "Договор.pdf", mimeType: "application/pdf", expected: "Договор.pdf"},
+		{name: "Договор", mimeType: "application/pdf", expected: "Договор.pdf"},
+		{name: "Договор v1.2", mimeType: "application/pdf", expected: "Договор v1.2.pdf"},
+		{name: "", mimeType: "image/jpeg", expected: "file.jpg"},
+		{name: "../../etc/passwd", mimeType: "", expected: "passwd"},
+		{name: `C:\Users\a\scan.png`, mimeType: "image/png", expected: "scan.png"},
+		{name: "notes", mimeType: "application/x-unknown-type", expected: "notes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FileName(tt.name, tt.mimeType); got != tt.expected {
+				t.Errorf("FileName(%q, %q) = %q, want %q", tt.name, tt.mimeType, got, tt.expected)
+			}
+		})
+	}
+}
+
+// fakeDrive remembers the uploaded file.
+type fakeDrive struct {
+	path, name, content string
+}
+
+func (d *fakeDrive) Upload(_ context.Context, path, name string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	d.path, d.name, d.content = path, name, string(data)
+	return "uuid-1", nil
+}
+
+func (d *fakeDrive) Attach(context.Context, cards.EntityType, int, string) error {
+	return nil
+}
+
+func TestUpload(t *testing.T) {
+	drive := &fakeDrive{}
+	uuid, name, err := New(drive).Upload(context.Background(), strings.NewReader("%PDF"), "Договор", "application/pdf")
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if uuid != "uuid-1" || name != "Договор.pdf" || drive.name != name || drive.content != "%PDF" {
+		t.Errorf("Upload() = %q, %q; drive got %q with %q", uuid, name, drive.name, drive.content)
+	}
+	if filepath.Ext(drive.path) != ".pdf" {
+		t.Errorf("temp file %q has no .pdf extension", drive.path)
+	}
+	if _, err := os.Stat(drive.path); !os.IsNotExist(err) {
+		t.Errorf("temp file %q was not removed", drive.path)
+	}
+}
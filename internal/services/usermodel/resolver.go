@@ -0,0 +1,109 @@
+// Package usermodel resolves the LLM used for a Telegram user's AI requests.
+package usermodel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"google.golang.org/adk/model"
+
+	"github.com/tihn/amo-ai-tgbot-go/internal/infrastructure/llm"
+	"github.com/tihn/amo-ai-tgbot-go/internal/services/auth"
+)
+
+// ErrAuthRequired is returned when per-user auth is required and the user has no Google token.
+var ErrAuthRequired = errors.New("google account is not connected")
+
+// Config configures per-user models.
+type Config struct {
+	ModelName   string       // Gemini model for Code Assist
+	Project     string       // Code Assist project, empty for free tier accounts
+	Settings    llm.Settings // generation settings of the gemini-cli provider
+	RequireAuth bool         // refuse AI for users without a connected Google account
+}
+
+// Resolver builds and caches a Code Assist model per Telegram user
+// from the Google token saved by auth.Service.
+type Resolver struct {
+	auth *auth.Service
+	cfg  Config
+
+	mu     sync.Mutex
+	models map[int64]model.LLM
+}
+
+// New creates a new Resolver.
+func New(authService *auth.Service, cfg Config) *Resolver {
+	return &Resolver{
+		auth:   authService,
+		cfg:    cfg,
+		models: make(map[int64]model.LLM),
+	}
+}
+
+// Resolve returns the user's own model, or nil if the shared provider should be used.
+// Returns ErrAuthRequired if RequireAuth is set and the user cannot get a personal model.
+func (r *Resolver) Resolve(ctx context.Context, telegramUserID int64) (model.LLM, error) {
+	if !r.auth.IsAuthenticated(telegramUserID) {
+		r.Invalidate(telegramUserID)
+		if r.cfg.RequireAuth {
+			return nil, ErrAuthRequired
+		}
+		return nil, nil
+	}
+
+	r.mu.Lock()
+	m, ok := r.models[telegramUserID]
+	r.mu.Unlock()
+	if ok {
+		return m, nil
+	}
+
+	m, err := r.build(ctx, telegramUserID)
+	if err != nil {
+		if r.cfg.RequireAuth {
+			return nil, err
+		}
+		log.Printf("[usermodel] user %d: falling back to shared model: %v", telegramUserID, err)
+		return nil, nil
+	}
+
+	r.mu.Lock()
+	r.models[telegramUserID] = m
+	r.mu.Unlock()
+	return m, nil
+}
+
+// Invalidate drops the cached model, e.g. after the user reconnects or disconnects Google.
+func (r *Resolver) Invalidate(telegramUserID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.models, telegramUserID)
+}
+
+// RequireAuth reports whether AI is available only with a connected Google account.
+func (r *Resolver) RequireAuth() bool {
+	return r.cfg.RequireAuth
+}
+
+func (r *Resolver) build(ctx context.Context, telegramUserID int64) (model.LLM, error) {
+	// The model outlives this request: token refreshes must not use a cancelable context
+	bgCtx := context.WithoutCancel(ctx)
+
+	ts, err := r.auth.GetTokenSource(bgCtx, telegramUserID)
+	if err != nil {
+		return nil, fmt.Errorf("get token source: %w", err)
+	}
+	if ts == nil {
+		return nil, ErrAuthRequired
+	}
+
+	m, err := llm.NewCodeAssist(ctx, r.cfg.ModelName, ts, r.cfg.Project)
+	if err != nil {
+		return nil, fmt.Errorf("create code assist model: %w", err)
+	}
+	return llm.WithSettings(m, r.cfg.Settings), nil
+}